@@ -0,0 +1,135 @@
+package main
+
+import (
+	"Termile/internal/task"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+const usage = `usage: termile [command]
+
+Without a command, termile starts the terminal UI.
+
+Commands:
+  timer start [-note text] <project> <task> [subtask]   start a timer
+  timer stop                                            stop the running timer
+  timer status                                          show the running timer
+  timer totals [-by task|project|assignee]              show logged time
+  report time [-from date] [-to date] [-by project|assignee|day]
+              [-format table|csv|json] [-round 15m] [-round-mode nearest|up|down]
+                                                        report logged time
+  report burndown -project id [-from date] [-to date] [-format table|csv|json]
+                                                        report open tasks per day
+  report velocity [-project id] [-weeks n] [-format table|csv|json]
+                                                        report tasks completed per week
+  stats [-metric lead|cycle] [-by project|assignee] [-project id]
+        [-format table|csv|json] [-histogram]          show lead or cycle time percentiles
+  sprint list <project>                                 list the sprints of a project
+  sprint add [-goal text] [-start date] [-end date | -days n] <project> <name>
+                                                        add a sprint
+  sprint assign <project> <sprint|0> <task>...          move tasks to a sprint or the backlog
+  sprint show <project> [sprint]                        show the scope and progress of a sprint
+  sprint close <project> [sprint]                       close a sprint, carrying unfinished tasks
+  people list                                           list the people tasks can be assigned to
+  people add [-name name] [-email email] [-color colour] <handle>
+                                                        add or update a person
+  people remove <handle>                                remove a person
+  people rename <old> <new>                             rename or merge an assignee everywhere
+  people me [handle]                                    show or set the current user
+  workload [-person handle] [-format table|csv|json]    show the open work of each person
+  comment add [-author handle] [-subtask id] <project> <task> <text>
+                                                        comment on a task or subtask
+  comment list [-subtask id] <project> <task>           show the comments of a task or subtask
+  export [-format json|csv|markdown] [-project id]      export tasks with their comments
+  log [-project id] [-task id] [-subtask id] [-actor handle] [-field name]
+      [-action add|modify|delete] [-since date] [-until date] [-limit n]
+      [-format table|csv|json]                          show the history of changes
+  log retention [-days n] [-max n]                      show or set how long history is kept
+  merge [-o file] [-format table|csv|json] [-i] <base> <ours> <theirs>
+                                                        merge two diverged projects files
+  sync init [-remote url] [-name remote]                commit every save to a git repository
+  sync [-i] [-format table|csv|json]                    pull, rebase and push the commits
+  replicate init -dir path                              replicate changes through a shared directory
+  replicate                                             exchange changes with the other replicas
+  serve [-addr host:port] [-allow-origin origins]       serve the REST API, see /openapi.json
+`
+
+// runCommand runs the subcommand named by args[0]. It fails with the changes
+// hooks vetoed, except for serve, which answers each request with its own
+func runCommand(args []string) error {
+	if args[0] == "serve" {
+		return runServe(args[1:])
+	}
+	var vetoes []error
+	onVeto = func(err error) {
+		vetoes = append(vetoes, err)
+	}
+	defer func() { onVeto = nil }()
+	err := runSubcommand(args)
+	errs := []error{err}
+	for _, veto := range vetoes {
+		// Mutators returning an error return their veto as well
+		if !errors.Is(err, veto) {
+			errs = append(errs, veto)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// runSubcommand runs a subcommand other than serve
+func runSubcommand(args []string) error {
+	switch args[0] {
+	case "timer":
+		return runTimer(args[1:])
+	case "report":
+		return runReport(args[1:])
+	case "stats":
+		return runStats(args[1:])
+	case "sprint":
+		return runSprint(args[1:])
+	case "people":
+		return runPeople(args[1:])
+	case "workload":
+		return runWorkload(args[1:])
+	case "comment":
+		return runComment(args[1:])
+	case "export":
+		return runExport(args[1:])
+	case "log":
+		return runLog(args[1:])
+	case "merge":
+		return runMerge(args[1:])
+	case "sync":
+		return runSync(args[1:])
+	case "replicate":
+		return runReplicate(args[1:])
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return nil
+	}
+	return fmt.Errorf("unknown command %q\n\n%s", args[0], usage)
+}
+
+// findProject returns the project with the given ID
+func findProject(taskManager *task.TaskManager, projectID int) (task.Project, error) {
+	for _, project := range taskManager.ListProjects() {
+		if project.ID == projectID {
+			return project, nil
+		}
+	}
+	return task.Project{}, fmt.Errorf("project %d not found", projectID)
+}
+
+// parseIDs parses the numeric IDs given on the command line
+func parseIDs(args []string) ([]int, error) {
+	ids := make([]int, len(args))
+	for i, arg := range args {
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ID %q", arg)
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
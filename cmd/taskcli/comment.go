@@ -0,0 +1,93 @@
+package main
+
+import (
+	"Termile/internal/task"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// runComment implements the comment add and list commands
+func runComment(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("comment needs a subcommand: add or list")
+	}
+	taskManager := loadTaskManager()
+
+	switch args[0] {
+	case "add":
+		flags := flag.NewFlagSet("comment add", flag.ContinueOnError)
+		author := flags.String("author", "", "author of the comment (default: the current user)")
+		subtaskID := flags.Int("subtask", 0, "comment on this subtask of the task")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() < 3 {
+			return fmt.Errorf("usage: termile comment add [-author handle] [-subtask id] <project> <task> <text>")
+		}
+		ids, err := parseIDs(flags.Args()[:2])
+		if err != nil {
+			return err
+		}
+		body := strings.Join(flags.Args()[2:], " ")
+		if *subtaskID != 0 {
+			_, err = taskManager.AddSubtaskComment(ids[0], ids[1], *subtaskID, *author, body, time.Now())
+		} else {
+			_, err = taskManager.AddComment(ids[0], ids[1], *author, body, time.Now())
+		}
+		if err != nil {
+			return err
+		}
+
+	case "list":
+		flags := flag.NewFlagSet("comment list", flag.ContinueOnError)
+		subtaskID := flags.Int("subtask", 0, "list the comments of this subtask of the task")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		ids, err := parseIDs(flags.Args())
+		if err != nil {
+			return err
+		}
+		if len(ids) != 2 {
+			return fmt.Errorf("usage: termile comment list [-subtask id] <project> <task>")
+		}
+		comments, err := findComments(taskManager, ids[0], ids[1], *subtaskID)
+		if err != nil {
+			return err
+		}
+		for _, comment := range comments {
+			fmt.Println(comment)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown comment subcommand %q", args[0])
+	}
+
+	return saveTaskManager()
+}
+
+// findComments returns the comments of a task, or of one of its subtasks
+func findComments(taskManager *task.TaskManager, projectID, taskID, subtaskID int) ([]task.Comment, error) {
+	project, err := findProject(taskManager, projectID)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range project.Tasks {
+		if t.ID != taskID {
+			continue
+		}
+		if subtaskID == 0 {
+			return t.Comments, nil
+		}
+		for _, subtask := range t.Subtasks {
+			if subtask.ID == subtaskID {
+				return subtask.Comments, nil
+			}
+		}
+		return nil, fmt.Errorf("subtask %d not found", subtaskID)
+	}
+	return nil, fmt.Errorf("task %d not found", taskID)
+}
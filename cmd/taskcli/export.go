@@ -0,0 +1,29 @@
+package main
+
+import (
+	"Termile/internal/report"
+	"Termile/internal/task"
+	"flag"
+	"os"
+)
+
+// runExport writes the projects with their tasks and comments to standard output
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := flags.String("format", report.FormatJSON, "output format: json, csv or markdown")
+	projectID := flags.Int("project", 0, "project ID (default: all projects)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	taskManager := loadTaskManager()
+	projects := taskManager.ListProjects()
+	if *projectID != 0 {
+		project, err := findProject(taskManager, *projectID)
+		if err != nil {
+			return err
+		}
+		projects = []task.Project{project}
+	}
+	return report.Export(os.Stdout, *format, projects)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"Termile/internal/config"
+	"Termile/internal/report"
+	"Termile/internal/task"
+	"Termile/pkg/storage"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// runLog prints the recorded history, or sets how long it is kept
+func runLog(args []string) error {
+	if len(args) > 0 && args[0] == "retention" {
+		return runLogRetention(args[1:])
+	}
+
+	flags := flag.NewFlagSet("log", flag.ContinueOnError)
+	var filter task.HistoryFilter
+	flags.IntVar(&filter.ProjectID, "project", 0, "only changes to this project")
+	flags.IntVar(&filter.TaskID, "task", 0, "only changes to this task and its subtasks")
+	flags.IntVar(&filter.SubtaskID, "subtask", 0, "only changes to this subtask")
+	flags.StringVar(&filter.Actor, "actor", "", "only changes made by this person")
+	flags.StringVar(&filter.Field, "field", "", "only changes of this field, e.g. Title or Complete")
+	flags.StringVar(&filter.Action, "action", "", "only add, modify or delete changes")
+	since := flags.String("since", "", "only changes from this day on")
+	until := flags.String("until", "", "only changes up to and including this day")
+	limit := flags.Int("limit", 0, "show only the last n changes")
+	format := flags.String("format", report.FormatTable, "output format: table, csv or json")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	sinceDate, err := task.ParseDate(*since, now)
+	if err != nil {
+		return err
+	}
+	if sinceDate != nil {
+		filter.Since = *sinceDate
+	}
+	untilDate, err := task.ParseDate(*until, now)
+	if err != nil {
+		return err
+	}
+	if untilDate != nil {
+		filter.Until = untilDate.AddDate(0, 0, 1)
+	}
+
+	changes, err := storage.LoadHistory(historyFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	var matched []task.Change
+	for _, change := range changes {
+		if filter.Match(change) {
+			matched = append(matched, change)
+		}
+	}
+	if *limit > 0 && len(matched) > *limit {
+		matched = matched[len(matched)-*limit:]
+	}
+	return report.WriteHistory(os.Stdout, *format, matched)
+}
+
+// runLogRetention shows or sets how long the history is kept
+func runLogRetention(args []string) error {
+	settings, err := config.Load()
+	if err != nil {
+		return err
+	}
+	flags := flag.NewFlagSet("log retention", flag.ContinueOnError)
+	days := flags.Int("days", settings.HistoryRetentionDays, "drop changes older than this many days (0 keeps them)")
+	entries := flags.Int("max", settings.HistoryMaxEntries, "keep at most this many changes (0 for no limit)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *days < 0 || *entries < 0 {
+		return fmt.Errorf("retention limits cannot be negative")
+	}
+	if flags.NFlag() == 0 {
+		fmt.Printf("days: %d\nmax: %d\n", settings.HistoryRetentionDays, settings.HistoryMaxEntries)
+		return nil
+	}
+	settings.HistoryRetentionDays = *days
+	settings.HistoryMaxEntries = *entries
+	if err := config.Save(settings); err != nil {
+		return err
+	}
+	return pruneHistory(settings, time.Now())
+}
@@ -1,33 +1,162 @@
 package main
 
 import (
+	"Termile/internal/config"
+	"Termile/internal/hooks"
 	"Termile/internal/task"
 	"Termile/internal/ui"
 	"Termile/pkg/storage"
+	"errors"
+	"fmt"
 	"log"
+	"os"
+	"os/user"
+	"time"
 
 	"github.com/gizak/termui/v3"
 )
 
-const projectFile = "projects.json"
+const (
+	historyFile = "history.jsonl"
+
+	// Number of journaled ops after which they are compacted into the
+	// projects, views and people files
+	journalCompactEvery = 500
+)
 
 func main() {
+	// Subcommands run without the terminal UI
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "termile: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	if err := termui.Init(); err != nil {
 		log.Fatalf("failed to initialize termui: %v", err)
 	}
 	defer termui.Close()
-	projects, err := storage.LoadProjects(projectFile)
+	taskManager := loadTaskManager()
+	settings, err := config.Load()
 	if err != nil {
-		log.Printf("failed to load projects: %v", err)
+		log.Printf("failed to load settings: %v", err)
 	}
-	taskManager := task.NewTaskManager()
-	taskManager.SetProjects(projects) // Use the setter to load projects into taskManager
 
 	// Start the UI
-	ui.StartUI(taskManager)
+	ui.StartUI(taskManager, store, settings.AutosaveDelay())
 
 	// Save tasks when the app exits
-	if err := storage.SaveProjects(projectFile, taskManager.ListProjects()); err != nil {
-		log.Printf("failed to save projects: %v", err)
+	if taskManager.Dirty() {
+		if err := saveTaskManager(); err != nil {
+			log.Printf("%v", err)
+		}
+	}
+}
+
+// store keeps the TaskManager loaded by loadTaskManager
+var store *storage.Store
+
+// replica replicates the store when replication is on, or is nil
+var replica *storage.Replica
+
+// onVeto, when set, is called with the changes hooks veto in the
+// TaskManagers loaded from then on
+var onVeto task.VetoListener
+
+// loadTaskManager creates a TaskManager holding the saved projects, views and
+// people, with the current user from the settings. The ops journaled since
+// they were last saved are replayed on top of them. Every change made to it is
+// journaled right away and appended to the history, every save is committed
+// in sync mode, and every change is recorded in the op log of a replica.
+// Changes are first passed to the hook scripts, which may veto them.
+func loadTaskManager() *task.TaskManager {
+	taskManager := task.NewTaskManager()
+	store = storage.NewStore(".", taskManager, journalCompactEvery)
+	if !storage.LockSupported {
+		log.Printf("files cannot be locked on this system: do not run several copies at once")
+	}
+	if repo, err := storage.OpenRepo("."); err != nil {
+		log.Printf("failed to open the sync repository: %v", err)
+	} else if repo != nil {
+		store.SetRepo(repo)
+	}
+	if err := store.Load(); err != nil {
+		log.Printf("%v", err)
+	}
+	if r, err := storage.OpenReplica(store); err != nil {
+		log.Printf("failed to open the replica: %v", err)
+	} else {
+		replica = r
+	}
+
+	settings, err := config.Load()
+	if err != nil {
+		log.Printf("failed to load settings: %v", err)
+	}
+	currentUser := settings.CurrentUser
+	if currentUser == "" {
+		if account, err := user.Current(); err == nil {
+			currentUser = account.Username
+		}
+	}
+	taskManager.SetCurrentUser(currentUser)
+
+	if err := pruneHistory(settings, time.Now()); err != nil {
+		log.Printf("failed to prune history: %v", err)
+	}
+	if dir, err := hooks.Dir(); err != nil {
+		log.Printf("failed to find the hooks: %v", err)
+	} else {
+		taskManager.SetHook(hooks.NewRunner(dir, settings.HookTimeout()).Hook)
+	}
+	if onVeto != nil {
+		taskManager.OnVeto(onVeto)
+	}
+	taskManager.OnChange(func(changes []task.Change) {
+		if err := storage.AppendHistory(historyFile, changes); err != nil {
+			log.Printf("failed to record history: %v", err)
+		}
+	})
+	return taskManager
+}
+
+// pruneHistory drops the changes older than the retention period and the
+// oldest changes beyond the maximum number of entries
+func pruneHistory(settings config.Config, now time.Time) error {
+	if settings.HistoryRetentionDays <= 0 && settings.HistoryMaxEntries <= 0 {
+		return nil
+	}
+	changes, err := storage.LoadHistory(historyFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	first := 0
+	if settings.HistoryMaxEntries > 0 && len(changes) > settings.HistoryMaxEntries {
+		first = len(changes) - settings.HistoryMaxEntries
+	}
+	cutoff := time.Time{}
+	if settings.HistoryRetentionDays > 0 {
+		cutoff = now.AddDate(0, 0, -settings.HistoryRetentionDays)
+	}
+	_, err = storage.PruneHistory(historyFile, func(index int, change task.Change) bool {
+		return index >= first && !change.Time.Before(cutoff)
+	})
+	return err
+}
+
+// saveTaskManager saves the TaskManager loaded by loadTaskManager and
+// empties the journal, whose ops the saved files now include. Changes other
+// processes saved in the meantime are merged first.
+func saveTaskManager() error {
+	err := store.Save()
+	if errors.Is(err, storage.ErrModified) {
+		if err = store.Merge(); err == nil {
+			err = store.Save()
+		}
 	}
+	return err
 }
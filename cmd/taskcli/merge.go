@@ -0,0 +1,90 @@
+package main
+
+import (
+	"Termile/internal/merge"
+	"Termile/internal/report"
+	"Termile/internal/task"
+	"Termile/internal/ui"
+	"Termile/pkg/storage"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runMerge merges the changes made to a base projects file in two diverged
+// copies of it. The merged projects are written to the output file or
+// standard output and the conflicts to standard error; conflicts left
+// unresolved keep our side and make the command fail, as git expects from a
+// merge driver.
+func runMerge(args []string) error {
+	flags := flag.NewFlagSet("merge", flag.ContinueOnError)
+	output := flags.String("o", "", "file to write the merged projects to (default: standard output)")
+	format := flags.String("format", report.FormatTable, "conflict format: table, csv or json")
+	interactive := flags.Bool("i", false, "resolve the conflicts in the terminal UI")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 3 {
+		return fmt.Errorf("usage: termile merge [-o file] [-format table|csv|json] [-i] <base> <ours> <theirs>")
+	}
+
+	var versions [3][]task.Project
+	for i, filename := range flags.Args() {
+		projects, err := loadMergeInput(filename)
+		if err != nil {
+			return err
+		}
+		versions[i] = projects
+	}
+	result := merge.Projects(versions[0], versions[1], versions[2])
+
+	if *interactive && len(result.Conflicts) > 0 {
+		write, err := ui.ResolveConflicts(result)
+		if err != nil {
+			return err
+		}
+		if !write {
+			return fmt.Errorf("merge aborted, nothing was written")
+		}
+	}
+
+	if *output != "" {
+		if err := storage.SaveProjects(*output, result.Projects); err != nil {
+			return err
+		}
+	} else {
+		data, err := json.Marshal(result.Projects)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stdout.Write(append(data, '\n')); err != nil {
+			return err
+		}
+	}
+
+	if len(result.Conflicts) > 0 {
+		if err := report.WriteConflicts(os.Stderr, *format, result.Conflicts); err != nil {
+			return err
+		}
+	}
+	if unresolved := result.Unresolved(); unresolved > 0 {
+		return fmt.Errorf("%d conflicts left unresolved, the merged projects keep our side", unresolved)
+	}
+	return nil
+}
+
+// loadMergeInput loads one version of the projects. A missing or empty file,
+// as git passes for a file added on both sides, holds no projects.
+func loadMergeInput(filename string) ([]task.Project, error) {
+	info, err := os.Stat(filename)
+	if errors.Is(err, os.ErrNotExist) || err == nil && info.Size() == 0 {
+		return nil, nil
+	}
+	projects, err := storage.LoadProjects(filename)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %v", filename, err)
+	}
+	return projects, nil
+}
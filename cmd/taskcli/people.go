@@ -0,0 +1,96 @@
+package main
+
+import (
+	"Termile/internal/config"
+	"flag"
+	"fmt"
+)
+
+// runPeople implements the people list, add, remove, rename and me commands
+func runPeople(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("people needs a subcommand: list, add, remove, rename or me")
+	}
+	taskManager := loadTaskManager()
+
+	switch args[0] {
+	case "list":
+		for _, person := range taskManager.ListPeople() {
+			marker := " "
+			if person.Handle == taskManager.CurrentUser() {
+				marker = "*"
+			}
+			fmt.Printf("%s %-12s %-20s %-24s %s\n", marker, person.Handle, person.Name, person.Email, person.Color)
+		}
+		return nil
+
+	case "add":
+		flags := flag.NewFlagSet("people add", flag.ContinueOnError)
+		name := flags.String("name", "", "display name")
+		email := flags.String("email", "", "email address")
+		color := flags.String("color", "", "colour: red, green, yellow, blue, magenta, cyan or white")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() != 1 {
+			return fmt.Errorf("usage: termile people add [-name name] [-email email] [-color colour] <handle>")
+		}
+		person, _ := taskManager.FindPerson(flags.Arg(0))
+		person.Handle = flags.Arg(0)
+		if *name != "" {
+			person.Name = *name
+		}
+		if *email != "" {
+			person.Email = *email
+		}
+		if *color != "" {
+			person.Color = *color
+		}
+		if err := taskManager.AddPerson(person); err != nil {
+			return err
+		}
+
+	case "remove":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: termile people remove <handle>")
+		}
+		if err := taskManager.RemovePerson(args[1]); err != nil {
+			return err
+		}
+
+	case "rename":
+		if len(args) != 3 {
+			return fmt.Errorf("usage: termile people rename <old> <new>")
+		}
+		changed, err := taskManager.RenameAssignee(args[1], args[2])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Reassigned %d tasks and subtasks from %q to %q\n", changed, args[1], args[2])
+
+	case "me":
+		settings, err := config.Load()
+		if err != nil {
+			return err
+		}
+		if len(args) == 1 {
+			if settings.CurrentUser == "" {
+				fmt.Println("No current user is set")
+			} else {
+				fmt.Println(settings.CurrentUser)
+			}
+			return nil
+		}
+		person, ok := taskManager.FindPerson(args[1])
+		if !ok {
+			return fmt.Errorf("person %q not found, add them with termile people add", args[1])
+		}
+		settings.CurrentUser = person.Handle
+		return config.Save(settings)
+
+	default:
+		return fmt.Errorf("unknown people subcommand %q", args[0])
+	}
+
+	return saveTaskManager()
+}
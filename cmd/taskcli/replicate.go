@@ -0,0 +1,56 @@
+package main
+
+import (
+	"Termile/pkg/storage"
+	"errors"
+	"flag"
+	"fmt"
+)
+
+// runReplicate implements the replicate and replicate init commands
+func runReplicate(args []string) error {
+	if len(args) > 0 && args[0] == "init" {
+		return runReplicateInit(args[1:])
+	}
+	if len(args) != 0 {
+		return fmt.Errorf("usage: termile replicate")
+	}
+
+	loadTaskManager()
+	if replica == nil {
+		return errors.New("replication is off; run termile replicate init -dir path first")
+	}
+	received, sent, err := replica.Exchange()
+	if err != nil {
+		return err
+	}
+	if err := saveTaskManager(); err != nil {
+		return err
+	}
+	fmt.Printf("Received %d and sent %d changes through %s\n", received, sent, replica.Dir())
+	return nil
+}
+
+// runReplicateInit turns on replication through a shared directory
+func runReplicateInit(args []string) error {
+	flags := flag.NewFlagSet("replicate init", flag.ContinueOnError)
+	dir := flags.String("dir", "", "directory shared with the other replicas")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 || *dir == "" {
+		return fmt.Errorf("usage: termile replicate init -dir path")
+	}
+
+	loadTaskManager()
+	if replica != nil {
+		return fmt.Errorf("replication is already on through %s", replica.Dir())
+	}
+	r, err := storage.InitReplica(store, *dir)
+	if err != nil {
+		return err
+	}
+	replica = r
+	fmt.Printf("Replication is on through %s\n", *dir)
+	return nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"Termile/internal/report"
+	"Termile/internal/task"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// runReport implements the report commands
+func runReport(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("report needs a subcommand: time, burndown or velocity")
+	}
+	switch args[0] {
+	case "time":
+		return runTimeReport(args[1:])
+	case "burndown":
+		return runBurndownReport(args[1:])
+	case "velocity":
+		return runVelocityReport(args[1:])
+	}
+	return fmt.Errorf("unknown report %q", args[0])
+}
+
+// runTimeReport prints the time logged between two dates
+func runTimeReport(args []string) error {
+	flags := flag.NewFlagSet("report time", flag.ContinueOnError)
+	from := flags.String("from", "", "first day of the report (default: start of this week)")
+	to := flags.String("to", "today", "last day of the report")
+	by := flags.String("by", report.ByProject, "group by project, assignee or day")
+	format := flags.String("format", report.FormatTable, "output format: table, csv or json")
+	step := flags.String("round", "", "round each entry to a step such as 15m")
+	mode := flags.String("round-mode", "nearest", "rounding mode: nearest, up or down")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	fromDate, err := task.ParseDate(*from, now)
+	if err != nil {
+		return err
+	}
+	if fromDate == nil {
+		weekStart := task.StartOfWeek(now)
+		fromDate = &weekStart
+	}
+	toDate, err := task.ParseDate(*to, now)
+	if err != nil {
+		return err
+	}
+	if toDate == nil {
+		return fmt.Errorf("-to needs a date")
+	}
+	rounding, err := report.ParseRounding(*step, *mode)
+	if err != nil {
+		return err
+	}
+
+	// The last day is included in the report
+	rows, err := report.TimeReport(loadTaskManager().ListProjects(), *fromDate, toDate.AddDate(0, 0, 1), *by, rounding, now)
+	if err != nil {
+		return err
+	}
+	return report.WriteTimeReport(os.Stdout, *format, *by, rows)
+}
+
+// runBurndownReport prints the open tasks of a project at the end of each day
+func runBurndownReport(args []string) error {
+	flags := flag.NewFlagSet("report burndown", flag.ContinueOnError)
+	projectID := flags.Int("project", 0, "project ID")
+	from := flags.String("from", "", "first day (default: the day the first task was created)")
+	to := flags.String("to", "today", "last day")
+	format := flags.String("format", report.FormatCSV, "output format: table, csv or json")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	project, err := findProject(loadTaskManager(), *projectID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	fromDate, err := task.ParseDate(*from, now)
+	if err != nil {
+		return err
+	}
+	if fromDate == nil {
+		start := report.ProjectStart(project, now)
+		fromDate = &start
+	}
+	toDate, err := task.ParseDate(*to, now)
+	if err != nil {
+		return err
+	}
+	if toDate == nil {
+		return fmt.Errorf("-to needs a date")
+	}
+	return report.WriteBurndown(os.Stdout, *format, report.Burndown(project, *fromDate, *toDate))
+}
+
+// runVelocityReport prints the weekly throughput of one or all projects
+func runVelocityReport(args []string) error {
+	flags := flag.NewFlagSet("report velocity", flag.ContinueOnError)
+	projectID := flags.Int("project", 0, "project ID (default: all projects)")
+	weeks := flags.Int("weeks", 8, "number of weeks ending with the current one")
+	format := flags.String("format", report.FormatCSV, "output format: table, csv or json")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *weeks < 1 {
+		return fmt.Errorf("-weeks must be at least 1")
+	}
+
+	taskManager := loadTaskManager()
+	projects := taskManager.ListProjects()
+	if *projectID != 0 {
+		project, err := findProject(taskManager, *projectID)
+		if err != nil {
+			return err
+		}
+		projects = []task.Project{project}
+	}
+	points := []report.VelocityPoint{}
+	for _, project := range projects {
+		points = append(points, report.Velocity(project, *weeks, time.Now())...)
+	}
+	return report.WriteVelocity(os.Stdout, *format, points)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"Termile/internal/api"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// runServe implements the serve command
+func runServe(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := flags.String("addr", "127.0.0.1:8080", "address to listen on")
+	allowOrigin := flags.String("allow-origin", "", "comma-separated origins of other sites allowed to use the API")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return fmt.Errorf("usage: termile serve [-addr host:port] [-allow-origin origins]")
+	}
+
+	taskManager := loadTaskManager()
+	handler := api.NewServer(taskManager, store)
+	handler.SetAddr(*addr)
+	for _, origin := range strings.Split(*allowOrigin, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			handler.AllowOrigins(origin)
+		}
+	}
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	server.RegisterOnShutdown(handler.Close)
+
+	// Stop on Ctrl-C, letting the requests in progress finish
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go handler.Watch(ctx, time.Second)
+	stopped := make(chan struct{})
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdown)
+		close(stopped)
+	}()
+
+	fmt.Printf("Serving the API on http://%s, see /openapi.json\n", *addr)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	<-stopped
+	if taskManager.Dirty() {
+		return saveTaskManager()
+	}
+	return nil
+}
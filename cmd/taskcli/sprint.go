@@ -0,0 +1,161 @@
+package main
+
+import (
+	"Termile/internal/task"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// runSprint implements the sprint list, add, assign, show and close commands
+func runSprint(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("sprint needs a subcommand: list, add, assign, show or close")
+	}
+	taskManager := loadTaskManager()
+	now := time.Now()
+
+	switch args[0] {
+	case "list":
+		ids, err := parseIDs(args[1:])
+		if err != nil {
+			return err
+		}
+		if len(ids) != 1 {
+			return fmt.Errorf("usage: termile sprint list <project>")
+		}
+		if _, err := findProject(taskManager, ids[0]); err != nil {
+			return err
+		}
+		for _, sprint := range taskManager.ListSprints(ids[0]) {
+			progress := taskManager.SprintProgress(ids[0], sprint.ID)
+			state := "open"
+			if sprint.Closed {
+				state = "closed"
+			}
+			fmt.Printf("%d. %s  %s to %s  %d/%d done  %s\n", sprint.ID, sprint.Name,
+				sprint.Start.Format(task.DateLayout), sprint.End.Format(task.DateLayout), progress.Done, progress.Total, state)
+		}
+		return nil
+
+	case "add":
+		flags := flag.NewFlagSet("sprint add", flag.ContinueOnError)
+		goal := flags.String("goal", "", "goal of the sprint")
+		start := flags.String("start", "today", "first day of the sprint")
+		end := flags.String("end", "", "last day of the sprint (default: -days after the start)")
+		days := flags.Int("days", 14, "length of the sprint in days")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() < 2 {
+			return fmt.Errorf("usage: termile sprint add [-goal text] [-start date] [-end date | -days n] <project> <name>")
+		}
+		ids, err := parseIDs(flags.Args()[:1])
+		if err != nil {
+			return err
+		}
+		startDate, err := task.ParseDate(*start, now)
+		if err != nil {
+			return err
+		}
+		if startDate == nil {
+			return fmt.Errorf("-start needs a date")
+		}
+		endDate, err := task.ParseDate(*end, *startDate)
+		if err != nil {
+			return err
+		}
+		if endDate == nil {
+			last := startDate.AddDate(0, 0, *days-1)
+			endDate = &last
+		}
+		sprint := task.Sprint{Name: strings.Join(flags.Args()[1:], " "), Goal: *goal, Start: *startDate, End: *endDate}
+		id, err := taskManager.AddSprint(ids[0], sprint)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Added sprint %d %q\n", id, sprint.Name)
+
+	case "assign":
+		ids, err := parseIDs(args[1:])
+		if err != nil {
+			return err
+		}
+		if len(ids) < 3 {
+			return fmt.Errorf("usage: termile sprint assign <project> <sprint|0> <task>...")
+		}
+		for _, taskID := range ids[2:] {
+			if err := taskManager.AssignSprint(ids[0], taskID, ids[1]); err != nil {
+				return err
+			}
+		}
+
+	case "show", "close":
+		ids, err := parseIDs(args[1:])
+		if err != nil {
+			return err
+		}
+		if len(ids) < 1 || len(ids) > 2 {
+			return fmt.Errorf("usage: termile sprint %s <project> [sprint]", args[0])
+		}
+		project, err := findProject(taskManager, ids[0])
+		if err != nil {
+			return err
+		}
+		var sprint task.Sprint
+		if len(ids) == 2 {
+			found := false
+			for _, s := range project.Sprints {
+				if s.ID == ids[1] {
+					sprint, found = s, true
+				}
+			}
+			if !found {
+				return fmt.Errorf("sprint %d not found", ids[1])
+			}
+		} else {
+			active, ok := taskManager.ActiveSprint(project.ID, now)
+			if !ok {
+				return fmt.Errorf("project %d has no open sprint", project.ID)
+			}
+			sprint = active
+		}
+
+		if args[0] == "show" {
+			printSprint(taskManager, project, sprint)
+			return nil
+		}
+		summary, err := taskManager.CloseSprint(project.ID, sprint.ID, now)
+		if err != nil {
+			return err
+		}
+		fmt.Print(summary)
+
+	default:
+		return fmt.Errorf("unknown sprint subcommand %q", args[0])
+	}
+
+	return saveTaskManager()
+}
+
+// printSprint prints the goal, progress and tasks of a sprint
+func printSprint(taskManager *task.TaskManager, project task.Project, sprint task.Sprint) {
+	fmt.Printf("%s (%s to %s)\n", sprint.Name, sprint.Start.Format(task.DateLayout), sprint.End.Format(task.DateLayout))
+	if sprint.Goal != "" {
+		fmt.Printf("Goal: %s\n", sprint.Goal)
+	}
+	progress := taskManager.SprintProgress(project.ID, sprint.ID)
+	fmt.Printf("%d of %d tasks done (%d%%)", progress.Done, progress.Total, progress.Percent())
+	if progress.Estimate > 0 {
+		fmt.Printf(", %s of %s", task.FormatEstimate(progress.DoneEstimate, project.Unit()), task.FormatEstimate(progress.Estimate, project.Unit()))
+	}
+	fmt.Println()
+	for _, t := range taskManager.SprintTasks(project.ID, sprint.ID) {
+		mark := "[ ]"
+		if t.Complete {
+			mark = "[x]"
+		}
+		fmt.Printf("  %s %d. %s (%s)\n", mark, t.ID, t.Title, t.Status)
+	}
+}
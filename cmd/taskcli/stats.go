@@ -0,0 +1,45 @@
+package main
+
+import (
+	"Termile/internal/report"
+	"Termile/internal/task"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runStats prints lead or cycle time percentiles of completed tasks
+func runStats(args []string) error {
+	flags := flag.NewFlagSet("stats", flag.ContinueOnError)
+	metric := flags.String("metric", report.CycleTime, "lead or cycle")
+	by := flags.String("by", "", "group by project or assignee (default: all tasks together)")
+	projectID := flags.Int("project", 0, "project ID (default: all projects)")
+	format := flags.String("format", report.FormatTable, "output format: table, csv or json")
+	histogram := flags.Bool("histogram", false, "also draw a histogram")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	taskManager := loadTaskManager()
+	projects := taskManager.ListProjects()
+	if *projectID != 0 {
+		project, err := findProject(taskManager, *projectID)
+		if err != nil {
+			return err
+		}
+		projects = []task.Project{project}
+	}
+	samples := report.FlowSamples(projects)
+	stats, err := report.Summarize(samples, *metric, *by)
+	if err != nil {
+		return err
+	}
+	if err := report.WriteFlowStats(os.Stdout, *format, stats); err != nil {
+		return err
+	}
+	if *histogram {
+		fmt.Println()
+		return report.WriteHistogram(os.Stdout, report.Histogram(report.Durations(samples, *metric)))
+	}
+	return nil
+}
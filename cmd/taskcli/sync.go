@@ -0,0 +1,78 @@
+package main
+
+import (
+	"Termile/internal/report"
+	"Termile/internal/ui"
+	"Termile/pkg/storage"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// runSync implements the sync and sync init commands
+func runSync(args []string) error {
+	if len(args) > 0 && args[0] == "init" {
+		return runSyncInit(args[1:])
+	}
+
+	flags := flag.NewFlagSet("sync", flag.ContinueOnError)
+	interactive := flags.Bool("i", false, "resolve conflicts in the terminal UI")
+	format := flags.String("format", report.FormatTable, "conflict format: table, csv or json")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return fmt.Errorf("usage: termile sync [-i] [-format table|csv|json]")
+	}
+
+	loadTaskManager()
+	var resolve storage.Resolver
+	if *interactive {
+		resolve = ui.ResolveConflicts
+	}
+	result, err := store.Sync(resolve)
+	var conflictErr *storage.ConflictError
+	if errors.As(err, &conflictErr) {
+		if err := report.WriteConflicts(os.Stderr, *format, conflictErr.Conflicts); err != nil {
+			return err
+		}
+		return fmt.Errorf("%v, nothing was synced; run termile sync -i to resolve them", err)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Pulled %d and pushed %d commits\n", result.Pulled, result.Pushed)
+	return nil
+}
+
+// runSyncInit turns on sync mode in the current directory
+func runSyncInit(args []string) error {
+	flags := flag.NewFlagSet("sync init", flag.ContinueOnError)
+	remoteURL := flags.String("remote", "", "URL of the remote repository to sync with")
+	remote := flags.String("name", storage.DefaultRemote, "name of the remote")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return fmt.Errorf("usage: termile sync init [-remote url] [-name remote]")
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	driver := fmt.Sprintf("'%s' merge -o %%A %%O %%A %%B", strings.ReplaceAll(executable, "'", `'\''`))
+	loadTaskManager()
+	repo, err := storage.InitRepo(".", driver, *remote, *remoteURL)
+	if err != nil {
+		return err
+	}
+	store.SetRepo(repo)
+	if err := saveTaskManager(); err != nil {
+		return err
+	}
+	fmt.Println("Sync mode is on: every save is committed")
+	return nil
+}
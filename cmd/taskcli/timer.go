@@ -0,0 +1,115 @@
+package main
+
+import (
+	"Termile/internal/task"
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// runTimer implements the timer start, stop, status and totals commands
+func runTimer(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("timer needs a subcommand: start, stop, status or totals")
+	}
+	taskManager := loadTaskManager()
+
+	switch args[0] {
+	case "start":
+		flags := flag.NewFlagSet("timer start", flag.ContinueOnError)
+		note := flags.String("note", "", "note for the time entry")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() < 2 || flags.NArg() > 3 {
+			return fmt.Errorf("usage: termile timer start [-note text] <project> <task> [subtask]")
+		}
+		ids, err := parseIDs(flags.Args())
+		if err != nil {
+			return err
+		}
+		subtaskID := 0
+		if len(ids) == 3 {
+			subtaskID = ids[2]
+		}
+		// A hook vetoing the timer makes StartTimer fail as well
+		if err := taskManager.StartTimer(ids[0], ids[1], subtaskID, *note); err != nil {
+			if timer, running := taskManager.RunningTimer(); running && errors.Is(err, task.ErrTimerRunning) {
+				return fmt.Errorf("%v on %q since %s", err, timer.Title, timer.Entry.Start.Format(time.Kitchen))
+			}
+			return err
+		}
+		timer, _ := taskManager.RunningTimer()
+		fmt.Printf("Started timer on %q\n", timer.Title)
+
+	case "stop":
+		timer, err := taskManager.StopTimer()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Stopped timer on %q after %s\n", timer.Title, task.FormatDuration(timer.Entry.Duration(time.Now())))
+
+	case "status":
+		timer, running := taskManager.RunningTimer()
+		if !running {
+			fmt.Println("No timer is running")
+			return nil
+		}
+		fmt.Printf("Timer running on %q (project %d, task %d) for %s\n",
+			timer.Title, timer.ProjectID, timer.TaskID, task.FormatDuration(timer.Entry.Duration(time.Now())))
+		return nil
+
+	case "totals":
+		flags := flag.NewFlagSet("timer totals", flag.ContinueOnError)
+		by := flags.String("by", "project", "group totals by task, project or assignee")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		return printTimeTotals(taskManager, *by)
+
+	default:
+		return fmt.Errorf("unknown timer subcommand %q", args[0])
+	}
+
+	return saveTaskManager()
+}
+
+// printTimeTotals prints the logged time grouped by task, project or assignee
+func printTimeTotals(taskManager *task.TaskManager, by string) error {
+	now := time.Now()
+	switch by {
+	case "task":
+		for _, project := range taskManager.ListProjects() {
+			for _, t := range project.Tasks {
+				if d := t.TrackedTime(now); d > 0 {
+					fmt.Printf("%-8s %s › %d. %s\n", task.FormatDuration(d), project.Name, t.ID, t.Title)
+				}
+			}
+		}
+	case "project":
+		for _, project := range taskManager.ListProjects() {
+			if d := taskManager.ProjectTime(project.ID, now); d > 0 {
+				fmt.Printf("%-8s %d. %s\n", task.FormatDuration(d), project.ID, project.Name)
+			}
+		}
+	case "assignee":
+		totals := taskManager.AssigneeTime(now)
+		assignees := make([]string, 0, len(totals))
+		for assignee := range totals {
+			assignees = append(assignees, assignee)
+		}
+		sort.Strings(assignees)
+		for _, assignee := range assignees {
+			name := assignee
+			if name == "" {
+				name = "(unassigned)"
+			}
+			fmt.Printf("%-8s %s\n", task.FormatDuration(totals[assignee]), name)
+		}
+	default:
+		return fmt.Errorf("unknown grouping %q, expected task, project or assignee", by)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"Termile/internal/report"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// runWorkload prints the open work of each person, or the open tasks of one
+func runWorkload(args []string) error {
+	flags := flag.NewFlagSet("workload", flag.ContinueOnError)
+	person := flags.String("person", "", "list the open tasks of one person")
+	format := flags.String("format", report.FormatTable, "output format: table, csv or json")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	taskManager := loadTaskManager()
+	now := time.Now()
+	rows := report.Workload(taskManager.ListProjects(), taskManager.ListPeople(), now)
+	if *person == "" {
+		return report.WriteWorkload(os.Stdout, *format, rows)
+	}
+
+	handle := strings.TrimPrefix(*person, "@")
+	if registered, ok := taskManager.FindPerson(handle); ok {
+		handle = registered.Handle
+	}
+	for _, row := range rows {
+		if strings.EqualFold(row.Person, handle) {
+			for _, ref := range row.Tasks {
+				line := fmt.Sprintf("%s › %d. %s (%s)", ref.ProjectName, ref.Task.ID, ref.Task.Title, ref.Task.Status)
+				if ref.Task.IsOverdue(now) {
+					line += " overdue"
+				}
+				fmt.Println(line)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("nobody called %q has open tasks", *person)
+}
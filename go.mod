@@ -2,8 +2,9 @@ module Termile
 
 go 1.23.1
 
+require github.com/gizak/termui/v3 v3.1.0
+
 require (
-	github.com/gizak/termui/v3 v3.1.0 // indirect
 	github.com/mattn/go-runewidth v0.0.16 // indirect
 	github.com/mitchellh/go-wordwrap v1.0.1 // indirect
 	github.com/nsf/termbox-go v1.1.1 // indirect
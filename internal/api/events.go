@@ -0,0 +1,239 @@
+package api
+
+import (
+	"Termile/internal/task"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// eventLogSize is the number of events kept for the clients resuming a
+// stream.
+const eventLogSize = 1000
+
+// keepAliveInterval is how often idle streams are written to, so that
+// proxies do not close them.
+const keepAliveInterval = 30 * time.Second
+
+// EventReset is the type of the event telling a client resuming a stream
+// that events were missed, e.g. because the server restarted: it should
+// fetch the resources again.
+const EventReset = "reset"
+
+// hub keeps the latest events of the TaskManager and passes new ones on to
+// the streams.
+type hub struct {
+	mu       sync.Mutex
+	log      []task.Event
+	dropped  int // Revision of the latest event dropped from the log
+	revision int // Revision of the latest event
+	streams  map[chan []task.Event]bool
+}
+
+// newHub creates a hub for a TaskManager at a revision.
+func newHub(revision int) *hub {
+	return &hub{dropped: revision, revision: revision, streams: map[chan []task.Event]bool{}}
+}
+
+// publish logs the events of a change and passes them on. A stream too slow
+// to take them is closed; its client can resume it.
+func (h *hub) publish(events []task.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.log = append(h.log, events...)
+	if extra := len(h.log) - eventLogSize; extra > 0 {
+		h.dropped = h.log[extra-1].Revision
+		h.log = append([]task.Event(nil), h.log[extra:]...)
+	}
+	h.revision = events[len(events)-1].Revision
+	for stream := range h.streams {
+		select {
+		case stream <- events:
+		default:
+			close(stream)
+			delete(h.streams, stream)
+		}
+	}
+}
+
+// subscribe opens a stream of the events after the current revision. If
+// resume is set, the logged events after the since revision come first, and
+// complete reports whether they are all of them.
+func (h *hub) subscribe(since int, resume bool) (backlog []task.Event, stream chan []task.Event, complete bool, revision int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	stream = make(chan []task.Event, 64)
+	h.streams[stream] = true
+	if !resume {
+		return nil, stream, true, h.revision
+	}
+	for _, event := range h.log {
+		if event.Revision > since {
+			backlog = append(backlog, event)
+		}
+	}
+	return backlog, stream, since >= h.dropped && since <= h.revision, h.revision
+}
+
+// unsubscribe closes a stream.
+func (h *hub) unsubscribe(stream chan []task.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.streams[stream] {
+		close(stream)
+		delete(h.streams, stream)
+	}
+}
+
+// resumeFrom returns the revision a stream resumes from, given by the since
+// query parameter or the Last-Event-ID header of a reconnecting
+// EventSource, and whether one was given.
+func resumeFrom(r *http.Request) (int, bool, error) {
+	value := r.URL.Query().Get("since")
+	if value == "" {
+		value = r.Header.Get("Last-Event-ID")
+	}
+	if value == "" {
+		return 0, false, nil
+	}
+	since, err := strconv.Atoi(value)
+	if err != nil || since < 0 {
+		return 0, false, errorf(http.StatusBadRequest, "since must be a revision number")
+	}
+	return since, true, nil
+}
+
+// resetEvent returns the event telling a client that it missed events.
+func resetEvent(revision int) task.Event {
+	return task.Event{Revision: revision, Time: time.Now(), Type: EventReset, Data: json.RawMessage("null")}
+}
+
+// streamEvents streams the events as server-sent events: one per created,
+// updated or deleted entity, named after its type, with the revision as the
+// ID of the last event of each change.
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request) {
+	if err := s.checkRequest(r); err != nil {
+		writeError(w, err)
+		return
+	}
+	since, resume, err := resumeFrom(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, fmt.Errorf("streaming is not supported"))
+		return
+	}
+	backlog, stream, complete, revision := s.hub.subscribe(since, resume)
+	defer s.hub.unsubscribe(stream)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if !complete {
+		writeServerSentEvents(w, []task.Event{resetEvent(revision)})
+	}
+	writeServerSentEvents(w, backlog)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case events, open := <-stream:
+			if !open {
+				return
+			}
+			if writeServerSentEvents(w, events) != nil {
+				return
+			}
+			flusher.Flush()
+		case <-keepAlive.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeServerSentEvents writes events in the text/event-stream format. Only
+// the last event of a change carries its ID, so that a client cut off in the
+// middle of a change resumes from the one before.
+func writeServerSentEvents(w io.Writer, events []task.Event) error {
+	for i, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		id := ""
+		if i == len(events)-1 || events[i+1].Revision != event.Revision {
+			id = fmt.Sprintf("id: %d\n", event.Revision)
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\n%sdata: %s\n\n", event.Type, id, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// streamWebSocketEvents streams the events over a WebSocket, one JSON text
+// message per event. Browsers let pages of any origin open WebSockets, so
+// the handshake is refused unless the origin is the server's own or allowed.
+func (s *Server) streamWebSocketEvents(w http.ResponseWriter, r *http.Request) {
+	if err := s.checkRequest(r); err != nil {
+		writeError(w, err)
+		return
+	}
+	since, resume, err := resumeFrom(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	conn, err := upgradeWebSocket(w, r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	defer conn.Close()
+	backlog, stream, complete, revision := s.hub.subscribe(since, resume)
+	defer s.hub.unsubscribe(stream)
+
+	closed := make(chan struct{})
+	go func() {
+		// Answers pings and notices the client closing the connection
+		conn.discardMessages()
+		close(closed)
+	}()
+	if !complete {
+		backlog = append([]task.Event{resetEvent(revision)}, backlog...)
+	}
+	if conn.writeEvents(backlog) != nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case events, open := <-stream:
+			if !open || conn.writeEvents(events) != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if conn.writeFrame(opPing, nil) != nil {
+				return
+			}
+		}
+	}
+}
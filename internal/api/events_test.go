@@ -0,0 +1,72 @@
+package api
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// handshake opens a WebSocket to /events/ws from a page of origin, or from
+// outside a browser if origin is empty, and returns the status answered.
+func handshake(t *testing.T, server *httptest.Server, origin string) int {
+	t.Helper()
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	r, err := http.NewRequest(http.MethodGet, server.URL+"/events/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Connection", "Upgrade")
+	r.Header.Set("Upgrade", "websocket")
+	r.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	r.Header.Set("Sec-WebSocket-Version", "13")
+	if origin != "" {
+		r.Header.Set("Origin", origin)
+	}
+	if err := r.Write(conn); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestWebSocketChecksTheOrigin(t *testing.T) {
+	s := newTestServer(t)
+	server := httptest.NewServer(s)
+	defer server.Close()
+	tests := []struct {
+		origin string
+		status int
+	}{
+		{"", http.StatusSwitchingProtocols},
+		{server.URL, http.StatusSwitchingProtocols},
+		{"http://localhost:3000", http.StatusSwitchingProtocols},
+		{"https://example.com", http.StatusForbidden},
+		{"null", http.StatusForbidden},
+	}
+	for _, test := range tests {
+		if status := handshake(t, server, test.origin); status != test.status {
+			t.Errorf("handshake from %q answered %d, want %d", test.origin, status, test.status)
+		}
+	}
+}
+
+func TestEventsCheckTheHost(t *testing.T) {
+	s := newTestServer(t)
+	for _, path := range []string{"/events", "/events/ws"} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://example.com:8080"+path, nil))
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s answered %d for another host, want %d", path, w.Code, http.StatusForbidden)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+)
+
+// openAPIDocument describes the API in OpenAPI 3.1.
+//
+//go:embed openapi.json
+var openAPIDocument []byte
+
+// openAPI serves the OpenAPI document.
+func (s *Server) openAPI(r *http.Request) (*response, error) {
+	return &response{status: http.StatusOK, body: json.RawMessage(openAPIDocument)}, nil
+}
@@ -0,0 +1,584 @@
+package api
+
+import (
+	"Termile/internal/task"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fields a client can set, in the order they are applied
+var (
+	projectFields = []string{"Name", "Description", "EstimateUnit", "Workflow"}
+	taskFields    = []string{"Title", "Description", "AssignedTo", "Status", "Complete", "Start", "Due", "DependsOn", "Estimate", "SprintID"}
+	subtaskFields = []string{"Title", "Description", "AssignedTo", "Complete", "Estimate"}
+)
+
+// projectResource is a project without its tasks, which are resources of
+// their own.
+type projectResource struct {
+	task.Project
+	Tasks []task.Task `json:"Tasks,omitempty"`
+}
+
+// taskResource is a task of a project, without its subtasks.
+type taskResource struct {
+	ProjectID int
+	task.Task
+	Subtasks []task.Subtask `json:"Subtasks,omitempty"`
+}
+
+// subtaskResource is a subtask of a task.
+type subtaskResource struct {
+	ProjectID int
+	TaskID    int
+	task.Subtask
+}
+
+func newProjectResource(p task.Project) projectResource {
+	return projectResource{Project: p}
+}
+
+func newTaskResource(projectID int, t task.Task) taskResource {
+	return taskResource{ProjectID: projectID, Task: t}
+}
+
+func newSubtaskResource(projectID, taskID int, s task.Subtask) subtaskResource {
+	return subtaskResource{ProjectID: projectID, TaskID: taskID, Subtask: s}
+}
+
+// project returns the project of the request path.
+func (s *Server) project(r *http.Request) (task.Project, error) {
+	id, err := pathID(r, "project")
+	if err != nil {
+		return task.Project{}, err
+	}
+	for _, p := range s.tm.ListProjects() {
+		if p.ID == id {
+			return p, nil
+		}
+	}
+	return task.Project{}, errorf(http.StatusNotFound, "project %d not found", id)
+}
+
+// task returns the task of the request path, with its project.
+func (s *Server) task(r *http.Request) (task.Project, task.Task, error) {
+	p, err := s.project(r)
+	if err != nil {
+		return p, task.Task{}, err
+	}
+	id, err := pathID(r, "task")
+	if err != nil {
+		return p, task.Task{}, err
+	}
+	for _, t := range p.Tasks {
+		if t.ID == id {
+			return p, t, nil
+		}
+	}
+	return p, task.Task{}, errorf(http.StatusNotFound, "task %d not found in project %d", id, p.ID)
+}
+
+// subtask returns the subtask of the request path, with its project and
+// task.
+func (s *Server) subtask(r *http.Request) (task.Project, task.Task, task.Subtask, error) {
+	p, t, err := s.task(r)
+	if err != nil {
+		return p, t, task.Subtask{}, err
+	}
+	id, err := pathID(r, "subtask")
+	if err != nil {
+		return p, t, task.Subtask{}, err
+	}
+	for _, subtask := range t.Subtasks {
+		if subtask.ID == id {
+			return p, t, subtask, nil
+		}
+	}
+	return p, t, task.Subtask{}, errorf(http.StatusNotFound, "subtask %d not found in task %d", id, t.ID)
+}
+
+// invalid turns the error of a TaskManager update into 400 Bad Request.
+func invalid(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*httpError); ok {
+		return err
+	}
+	if _, ok := err.(*task.VetoError); ok {
+		return err
+	}
+	return errorf(http.StatusBadRequest, "%v", err)
+}
+
+// listProjects lists the projects whose name or description contains the q
+// query parameter.
+func (s *Server) listProjects(r *http.Request) (*response, error) {
+	words := strings.Fields(strings.ToLower(r.URL.Query().Get("q")))
+	projects := []projectResource{}
+	for _, p := range s.tm.ListProjects() {
+		text := strings.ToLower(p.Name + " " + p.Description)
+		if containsAll(text, words) {
+			projects = append(projects, newProjectResource(p))
+		}
+	}
+	return paginate(r, projects)
+}
+
+// containsAll reports whether a text contains all the words.
+func containsAll(text string, words []string) bool {
+	for _, word := range words {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Server) getProject(r *http.Request) (*response, error) {
+	p, err := s.project(r)
+	if err != nil {
+		return nil, err
+	}
+	return &response{status: http.StatusOK, body: newProjectResource(p)}, nil
+}
+
+func (s *Server) createProject(r *http.Request) (*response, error) {
+	fields := task.Project{}
+	given, err := decodeFields(r, projectFields, &fields)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(fields.Name) == "" {
+		return nil, errorf(http.StatusBadRequest, "a project needs a Name")
+	}
+
+	var id int
+	err = s.tm.Update(func() error {
+		s.tm.AddProject(task.Project{Name: fields.Name, Description: fields.Description, Tasks: []task.Task{}, CreatedAt: time.Now()})
+		projects := s.tm.ListProjects()
+		id = projects[len(projects)-1].ID
+		if err := s.applyProject(id, given, fields); err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, invalid(err)
+	}
+	// Read after the change, which hooks may have rewritten
+	created, err := s.findProject(id)
+	if err != nil {
+		return nil, err
+	}
+	return &response{status: http.StatusCreated, body: newProjectResource(created), location: fmt.Sprintf("/projects/%d", created.ID)}, nil
+}
+
+func (s *Server) updateProject(r *http.Request) (*response, error) {
+	p, err := s.project(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkMatch(r, newProjectResource(p)); err != nil {
+		return nil, err
+	}
+	fields := p
+	given, err := decodeFields(r, projectFields, &fields)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(fields.Name) == "" {
+		return nil, errorf(http.StatusBadRequest, "a project needs a Name")
+	}
+	if err := s.tm.Update(func() error { return s.applyProject(p.ID, given, fields) }); err != nil {
+		return nil, invalid(err)
+	}
+	updated, err := s.findProject(p.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &response{status: http.StatusOK, body: newProjectResource(updated)}, nil
+}
+
+// applyProject sets the given fields of a project.
+func (s *Server) applyProject(id int, given map[string]bool, fields task.Project) error {
+	if given["Name"] || given["Description"] {
+		s.tm.EditProject(id, fields.Name, fields.Description)
+	}
+	if given["EstimateUnit"] {
+		if err := s.tm.SetEstimateUnit(id, fields.EstimateUnit); err != nil {
+			return err
+		}
+	}
+	if given["Workflow"] {
+		if err := s.tm.SetWorkflow(id, fields.Workflow); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) deleteProject(r *http.Request) (*response, error) {
+	p, err := s.project(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkMatch(r, newProjectResource(p)); err != nil {
+		return nil, err
+	}
+	if err := s.tm.Update(func() error { s.tm.RemoveProject(p.ID); return nil }); err != nil {
+		return nil, err
+	}
+	return &response{status: http.StatusNoContent}, nil
+}
+
+// findProject returns a project just changed. It is a conflict if the
+// project is gone, e.g. because a hook rewrote the change.
+func (s *Server) findProject(id int) (task.Project, error) {
+	for _, p := range s.tm.ListProjects() {
+		if p.ID == id {
+			return p, nil
+		}
+	}
+	return task.Project{}, errorf(http.StatusConflict, "project %d no longer exists", id)
+}
+
+// filter parses the q query parameter, a filter query as typed in the UI,
+// e.g. "is:open @sara due:week".
+func filter(r *http.Request) (task.Filter, error) {
+	f, err := task.ParseFilter(r.URL.Query().Get("q"), time.Now())
+	if err != nil {
+		return f, errorf(http.StatusBadRequest, "invalid q: %v", err)
+	}
+	return f, nil
+}
+
+// findTasks lists the tasks of every project matching the q query parameter.
+func (s *Server) findTasks(r *http.Request) (*response, error) {
+	f, err := filter(r)
+	if err != nil {
+		return nil, err
+	}
+	tasks := []taskResource{}
+	for _, ref := range s.tm.FindTasks(f) {
+		tasks = append(tasks, newTaskResource(ref.ProjectID, ref.Task))
+	}
+	return paginate(r, tasks)
+}
+
+// listTasks lists the tasks of a project matching the q query parameter.
+func (s *Server) listTasks(r *http.Request) (*response, error) {
+	p, err := s.project(r)
+	if err != nil {
+		return nil, err
+	}
+	f, err := filter(r)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(f.Assignee, "me") && s.tm.CurrentUser() != "" {
+		f.Assignee = s.tm.CurrentUser()
+	}
+	tasks := []taskResource{}
+	for _, t := range p.Tasks {
+		if f.Match(p, t) {
+			tasks = append(tasks, newTaskResource(p.ID, t))
+		}
+	}
+	return paginate(r, tasks)
+}
+
+func (s *Server) getTask(r *http.Request) (*response, error) {
+	p, t, err := s.task(r)
+	if err != nil {
+		return nil, err
+	}
+	return &response{status: http.StatusOK, body: newTaskResource(p.ID, t)}, nil
+}
+
+func (s *Server) createTask(r *http.Request) (*response, error) {
+	p, err := s.project(r)
+	if err != nil {
+		return nil, err
+	}
+	fields := task.Task{}
+	given, err := decodeFields(r, taskFields, &fields)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(fields.Title) == "" {
+		return nil, errorf(http.StatusBadRequest, "a task needs a Title")
+	}
+
+	var id int
+	err = s.tm.Update(func() error {
+		s.tm.AddTask(p.ID, task.Task{Title: fields.Title, Description: fields.Description, Subtasks: []task.Subtask{}, CreatedAt: time.Now()})
+		tasks := s.tm.ListTasks(p.ID)
+		id = tasks[len(tasks)-1].ID
+		delete(given, "Title")
+		delete(given, "Description")
+		if err := s.applyTask(p.ID, id, given, fields); err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, invalid(err)
+	}
+	created, err := s.findTask(p.ID, id)
+	if err != nil {
+		return nil, err
+	}
+	return &response{status: http.StatusCreated, body: newTaskResource(p.ID, created), location: fmt.Sprintf("/projects/%d/tasks/%d", p.ID, created.ID)}, nil
+}
+
+func (s *Server) updateTask(r *http.Request) (*response, error) {
+	p, t, err := s.task(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkMatch(r, newTaskResource(p.ID, t)); err != nil {
+		return nil, err
+	}
+	fields := t
+	given, err := decodeFields(r, taskFields, &fields)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(fields.Title) == "" {
+		return nil, errorf(http.StatusBadRequest, "a task needs a Title")
+	}
+	if err := s.tm.Update(func() error { return s.applyTask(p.ID, t.ID, given, fields) }); err != nil {
+		return nil, invalid(err)
+	}
+	updated, err := s.findTask(p.ID, t.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &response{status: http.StatusOK, body: newTaskResource(p.ID, updated)}, nil
+}
+
+// applyTask sets the given fields of a task. A status and a completion both
+// given are applied in that order.
+func (s *Server) applyTask(projectID, taskID int, given map[string]bool, fields task.Task) error {
+	tm := s.tm
+	var err error
+	for _, name := range taskFields {
+		if !given[name] {
+			continue
+		}
+		switch name {
+		case "Title", "Description":
+			err = tm.SetTaskText(projectID, taskID, fields.Title, fields.Description)
+		case "AssignedTo":
+			tm.AssignTaskTo(projectID, taskID, fields.AssignedTo)
+		case "Status":
+			err = tm.SetTaskStatus(projectID, taskID, fields.Status)
+		case "Complete":
+			var t task.Task
+			if t, err = s.findTask(projectID, taskID); err == nil && t.Complete != fields.Complete {
+				tm.ToggleComplete(projectID, taskID)
+			}
+		case "Start":
+			err = tm.SetTaskStart(projectID, taskID, fields.Start)
+		case "Due":
+			err = tm.SetTaskDue(projectID, taskID, fields.Due)
+		case "DependsOn":
+			err = tm.SetTaskDependencies(projectID, taskID, fields.DependsOn)
+		case "Estimate":
+			err = tm.SetTaskEstimate(projectID, taskID, fields.Estimate)
+		case "SprintID":
+			err = tm.AssignSprint(projectID, taskID, fields.SprintID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) deleteTask(r *http.Request) (*response, error) {
+	p, t, err := s.task(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkMatch(r, newTaskResource(p.ID, t)); err != nil {
+		return nil, err
+	}
+	if err := s.tm.Update(func() error { s.tm.RemoveTask(p.ID, t.ID); return nil }); err != nil {
+		return nil, err
+	}
+	return &response{status: http.StatusNoContent}, nil
+}
+
+// findTask returns a task just changed, like findProject.
+func (s *Server) findTask(projectID, taskID int) (task.Task, error) {
+	for _, t := range s.tm.ListTasks(projectID) {
+		if t.ID == taskID {
+			return t, nil
+		}
+	}
+	return task.Task{}, errorf(http.StatusConflict, "task %d no longer exists in project %d", taskID, projectID)
+}
+
+// listSubtasks lists the subtasks of a task, filtered by the complete and
+// assignee query parameters.
+func (s *Server) listSubtasks(r *http.Request) (*response, error) {
+	p, t, err := s.task(r)
+	if err != nil {
+		return nil, err
+	}
+	query := r.URL.Query()
+	var complete *bool
+	if value := query.Get("complete"); value != "" {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, errorf(http.StatusBadRequest, "complete must be true or false")
+		}
+		complete = &b
+	}
+	assignee := strings.TrimPrefix(query.Get("assignee"), "@")
+	subtasks := []subtaskResource{}
+	for _, subtask := range t.Subtasks {
+		if complete != nil && subtask.Complete != *complete {
+			continue
+		}
+		if assignee != "" && !hasAssignee(subtask.AssignedTo, assignee) {
+			continue
+		}
+		subtasks = append(subtasks, newSubtaskResource(p.ID, t.ID, subtask))
+	}
+	return paginate(r, subtasks)
+}
+
+// hasAssignee reports whether an assignee list holds a person.
+func hasAssignee(assignedTo, handle string) bool {
+	for _, assignee := range task.SplitAssignees(assignedTo) {
+		if strings.EqualFold(assignee, handle) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) getSubtask(r *http.Request) (*response, error) {
+	p, t, subtask, err := s.subtask(r)
+	if err != nil {
+		return nil, err
+	}
+	return &response{status: http.StatusOK, body: newSubtaskResource(p.ID, t.ID, subtask)}, nil
+}
+
+func (s *Server) createSubtask(r *http.Request) (*response, error) {
+	p, t, err := s.task(r)
+	if err != nil {
+		return nil, err
+	}
+	fields := task.Subtask{}
+	given, err := decodeFields(r, subtaskFields, &fields)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(fields.Title) == "" {
+		return nil, errorf(http.StatusBadRequest, "a subtask needs a Title")
+	}
+
+	var id int
+	err = s.tm.Update(func() error {
+		s.tm.AddSubtask(p.ID, t.ID, task.Subtask{Title: fields.Title, Description: fields.Description, CreatedAt: time.Now()})
+		subtasks := s.tm.ListSubtasks(p.ID, t.ID)
+		id = subtasks[len(subtasks)-1].ID
+		delete(given, "Title")
+		delete(given, "Description")
+		if err := s.applySubtask(p.ID, t.ID, id, given, fields); err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, invalid(err)
+	}
+	created, err := s.findSubtask(p.ID, t.ID, id)
+	if err != nil {
+		return nil, err
+	}
+	return &response{status: http.StatusCreated, body: newSubtaskResource(p.ID, t.ID, created), location: fmt.Sprintf("/projects/%d/tasks/%d/subtasks/%d", p.ID, t.ID, created.ID)}, nil
+}
+
+func (s *Server) updateSubtask(r *http.Request) (*response, error) {
+	p, t, subtask, err := s.subtask(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkMatch(r, newSubtaskResource(p.ID, t.ID, subtask)); err != nil {
+		return nil, err
+	}
+	fields := subtask
+	given, err := decodeFields(r, subtaskFields, &fields)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(fields.Title) == "" {
+		return nil, errorf(http.StatusBadRequest, "a subtask needs a Title")
+	}
+	if err := s.tm.Update(func() error { return s.applySubtask(p.ID, t.ID, subtask.ID, given, fields) }); err != nil {
+		return nil, invalid(err)
+	}
+	updated, err := s.findSubtask(p.ID, t.ID, subtask.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &response{status: http.StatusOK, body: newSubtaskResource(p.ID, t.ID, updated)}, nil
+}
+
+// applySubtask sets the given fields of a subtask.
+func (s *Server) applySubtask(projectID, taskID, subtaskID int, given map[string]bool, fields task.Subtask) error {
+	tm := s.tm
+	var err error
+	for _, name := range subtaskFields {
+		if !given[name] {
+			continue
+		}
+		switch name {
+		case "Title", "Description":
+			err = tm.SetSubtaskText(projectID, taskID, subtaskID, fields.Title, fields.Description)
+		case "AssignedTo":
+			tm.AssignSubtaskTo(projectID, taskID, subtaskID, fields.AssignedTo)
+		case "Complete":
+			err = tm.SetSubtaskComplete(projectID, taskID, subtaskID, fields.Complete)
+		case "Estimate":
+			err = tm.SetSubtaskEstimate(projectID, taskID, subtaskID, fields.Estimate)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) deleteSubtask(r *http.Request) (*response, error) {
+	p, t, subtask, err := s.subtask(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkMatch(r, newSubtaskResource(p.ID, t.ID, subtask)); err != nil {
+		return nil, err
+	}
+	if err := s.tm.Update(func() error { s.tm.RemoveSubtask(p.ID, t.ID, subtask.ID); return nil }); err != nil {
+		return nil, err
+	}
+	return &response{status: http.StatusNoContent}, nil
+}
+
+// findSubtask returns a subtask just changed, like findProject.
+func (s *Server) findSubtask(projectID, taskID, subtaskID int) (task.Subtask, error) {
+	for _, subtask := range s.tm.ListSubtasks(projectID, taskID) {
+		if subtask.ID == subtaskID {
+			return subtask, nil
+		}
+	}
+	return task.Subtask{}, errorf(http.StatusConflict, "subtask %d no longer exists in task %d", subtaskID, taskID)
+}
@@ -0,0 +1,432 @@
+// Package api serves the projects, tasks and subtasks of a TaskManager as a
+// JSON REST API, for dashboards and editor plugins to read and change them.
+package api
+
+import (
+	"Termile/internal/task"
+	"Termile/pkg/storage"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Page sizes of list endpoints
+const (
+	DefaultLimit = 50
+	MaxLimit     = 500
+)
+
+// maxBodySize is the size of the largest request body accepted.
+const maxBodySize = 1 << 20
+
+// Server handles the requests of the API. Requests are handled one at a
+// time, each one first reloading the files if another process changed them;
+// changes are saved before they are answered. The changes, including those
+// reloaded, are streamed to the clients of /events.
+type Server struct {
+	mu      sync.Mutex
+	tm      *task.TaskManager
+	store   *storage.Store
+	mux     *http.ServeMux
+	hub     *hub
+	addr    string   // Address listened on, see SetAddr
+	origins []string // Origins allowed besides the server's own
+}
+
+// response is the answer of a handler, written as JSON.
+type response struct {
+	status   int
+	body     any // Not written if nil
+	location string
+	header   http.Header
+}
+
+// httpError is an error answered with a status code.
+type httpError struct {
+	status  int
+	message string
+}
+
+func (e *httpError) Error() string {
+	return e.message
+}
+
+// errorf returns an error answered with a status code.
+func errorf(status int, format string, args ...any) error {
+	return &httpError{status: status, message: fmt.Sprintf(format, args...)}
+}
+
+// NewServer creates a server for a TaskManager kept by a store.
+func NewServer(tm *task.TaskManager, store *storage.Store) *Server {
+	s := &Server{tm: tm, store: store, mux: http.NewServeMux(), hub: newHub(tm.Revision())}
+	tm.Subscribe(s.hub.publish)
+	s.mux.HandleFunc("GET /events", s.streamEvents)
+	s.mux.HandleFunc("GET /events/ws", s.streamWebSocketEvents)
+	s.handle("GET /openapi.json", s.openAPI)
+	s.handle("GET /tasks", s.findTasks)
+	s.handle("GET /projects", s.listProjects)
+	s.handle("POST /projects", s.createProject)
+	s.handle("GET /projects/{project}", s.getProject)
+	s.handle("PATCH /projects/{project}", s.updateProject)
+	s.handle("DELETE /projects/{project}", s.deleteProject)
+	s.handle("GET /projects/{project}/tasks", s.listTasks)
+	s.handle("POST /projects/{project}/tasks", s.createTask)
+	s.handle("GET /projects/{project}/tasks/{task}", s.getTask)
+	s.handle("PATCH /projects/{project}/tasks/{task}", s.updateTask)
+	s.handle("DELETE /projects/{project}/tasks/{task}", s.deleteTask)
+	s.handle("GET /projects/{project}/tasks/{task}/subtasks", s.listSubtasks)
+	s.handle("POST /projects/{project}/tasks/{task}/subtasks", s.createSubtask)
+	s.handle("GET /projects/{project}/tasks/{task}/subtasks/{subtask}", s.getSubtask)
+	s.handle("PATCH /projects/{project}/tasks/{task}/subtasks/{subtask}", s.updateSubtask)
+	s.handle("DELETE /projects/{project}/tasks/{task}/subtasks/{subtask}", s.deleteSubtask)
+	return s
+}
+
+// SetAddr sets the address the server listens on. Requests must name its
+// host or localhost, which keeps out the pages of domains rebound to the
+// address. When the address leaves the host out or is a wildcard address,
+// requests may name any IP address.
+func (s *Server) SetAddr(addr string) {
+	s.addr = addr
+}
+
+// AllowOrigins allows the pages of other origins, e.g.
+// "http://localhost:3000", to use the API. Requests from pages of other
+// origins than the server's own are refused otherwise.
+func (s *Server) AllowOrigins(origins ...string) {
+	s.origins = append(s.origins, origins...)
+}
+
+// Close ends the event streams, which would otherwise keep the server from
+// shutting down.
+func (s *Server) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	for stream := range s.hub.streams {
+		close(stream)
+		delete(s.hub.streams, stream)
+	}
+}
+
+// ServeHTTP handles a request.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// handle registers the handler of a route. Its response is saved, tagged
+// with an ETag and written; an unchanged resource is answered with 304 Not
+// Modified when the request gives its ETag in If-None-Match.
+func (s *Server) handle(pattern string, handler func(r *http.Request) (*response, error)) {
+	s.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		if err := s.checkRequest(r); err != nil {
+			writeError(w, err)
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+
+		if err := s.reload(); err != nil {
+			writeError(w, err)
+			return
+		}
+		resp, err := handler(r)
+		if err == nil && s.tm.Dirty() {
+			err = s.save()
+		}
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		for name, values := range resp.header {
+			w.Header()[name] = values
+		}
+		if resp.location != "" {
+			w.Header().Set("Location", resp.location)
+		}
+		if resp.body == nil {
+			w.WriteHeader(resp.status)
+			return
+		}
+		data, err := json.MarshalIndent(resp.body, "", "  ")
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		tag := etag(data)
+		w.Header().Set("ETag", tag)
+		if r.Method == http.MethodGet && matches(r.Header.Get("If-None-Match"), tag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(resp.status)
+		w.Write(append(data, '\n'))
+	})
+}
+
+// Watch reloads the files whenever another process changes them, checking
+// them every interval until ctx is done, so that their changes are streamed
+// without waiting for a request.
+func (s *Server) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			if err := s.reload(); err != nil {
+				log.Printf("failed to reload changed files: %v", err)
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// checkRequest fails if a request names another host, comes from a page of
+// another origin, or gives a body that is not JSON. Pages of any site can
+// send forms to the server, and make the browser send them to a domain of
+// their own that resolves to the server.
+func (s *Server) checkRequest(r *http.Request) error {
+	if err := s.checkHost(r); err != nil {
+		return err
+	}
+	if err := s.checkOrigin(r); err != nil {
+		return err
+	}
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || mediaType != "application/json" {
+			return errorf(http.StatusUnsupportedMediaType, "the body must be sent as application/json")
+		}
+	}
+	return nil
+}
+
+// checkHost fails with 403 Forbidden unless a request names localhost, a
+// loopback address or the host listened on.
+func (s *Server) checkHost(r *http.Request) error {
+	host := hostname(r.Host)
+	if strings.EqualFold(host, "localhost") {
+		return nil
+	}
+	ip := net.ParseIP(host)
+	if ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	listened := hostname(s.addr)
+	if listened != "" && strings.EqualFold(host, listened) {
+		return nil
+	}
+	// Listening on every interface, the server is reached at any of its IPs
+	if ip != nil && (listened == "" || net.ParseIP(listened).IsUnspecified()) {
+		return nil
+	}
+	return errorf(http.StatusForbidden, "unknown host %q", r.Host)
+}
+
+// checkOrigin fails with 403 Forbidden if a request comes from a page of
+// another origin than the server's own that is not allowed. Requests from
+// outside a browser give no origin.
+func (s *Server) checkOrigin(r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" || slices.Contains(s.origins, origin) {
+		return nil
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if u, err := url.Parse(origin); err == nil && u.Scheme == scheme && strings.EqualFold(u.Host, r.Host) {
+		return nil
+	}
+	return errorf(http.StatusForbidden, "requests from %s are not allowed", origin)
+}
+
+// hostname returns the host of an address, without its port or the brackets
+// of an IPv6 address.
+func hostname(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return strings.Trim(addr, "[]")
+}
+
+// reload brings in the changes other processes made to the files.
+func (s *Server) reload() error {
+	modified, err := s.store.Modified()
+	if err != nil || !modified {
+		return err
+	}
+	return s.store.Merge()
+}
+
+// save saves the changes, first bringing in those other processes made to
+// the files since they were read.
+func (s *Server) save() error {
+	err := s.store.Save()
+	if errors.Is(err, storage.ErrModified) {
+		if err = s.store.Merge(); err == nil {
+			err = s.store.Save()
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("the change was made but could not be saved: %v", err)
+	}
+	return nil
+}
+
+// writeError answers an error as a JSON object with an "error" message.
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var httpErr *httpError
+	var maxBytesErr *http.MaxBytesError
+	var vetoErr *task.VetoError
+	switch {
+	case errors.As(err, &httpErr):
+		status = httpErr.status
+	case errors.As(err, &maxBytesErr):
+		status = http.StatusRequestEntityTooLarge
+	case errors.As(err, &vetoErr):
+		status = http.StatusUnprocessableEntity
+	default:
+		log.Printf("%s", err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// etag returns the strong entity tag of a representation.
+func etag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf(`"%x"`, sum[:8])
+}
+
+// resourceTag returns the entity tag of a resource, as answered by GET.
+func resourceTag(resource any) string {
+	data, err := json.MarshalIndent(resource, "", "  ")
+	if err != nil {
+		panic(fmt.Sprintf("api: cannot encode %T: %v", resource, err))
+	}
+	return etag(data)
+}
+
+// matches reports whether an If-Match or If-None-Match header lists a tag.
+// Weak tags never match, since the tags of the API are strong.
+func matches(header, tag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkMatch fails with 412 Precondition Failed if the request gives an
+// If-Match header that the current version of a resource does not match,
+// i.e. it was changed since the client read it.
+func checkMatch(r *http.Request, resource any) error {
+	header := r.Header.Get("If-Match")
+	if header != "" && !matches(header, resourceTag(resource)) {
+		return errorf(http.StatusPreconditionFailed, "the resource was changed since it was read, fetch it again")
+	}
+	return nil
+}
+
+// pathID parses the ID of a path segment.
+func pathID(r *http.Request, name string) (int, error) {
+	id, err := strconv.Atoi(r.PathValue(name))
+	if err != nil {
+		return 0, errorf(http.StatusNotFound, "%s %q not found", name, r.PathValue(name))
+	}
+	return id, nil
+}
+
+// decodeFields decodes the JSON object of a request body into v, which must
+// hold the current values of the fields the body leaves out, and returns the
+// names of the fields it gives. Only the fields in allowed can be given.
+func decodeFields(r *http.Request, allowed []string, v any) (map[string]bool, error) {
+	var fields map[string]json.RawMessage
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, errorf(http.StatusBadRequest, "the body must be a JSON object: %v", err)
+	}
+	given := map[string]bool{}
+	for name := range fields {
+		if !slices.Contains(allowed, name) {
+			return nil, errorf(http.StatusBadRequest, "unknown or read-only field %q, expected one of %s", name, strings.Join(allowed, ", "))
+		}
+		given[name] = true
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, errorf(http.StatusBadRequest, "invalid field: %v", err)
+	}
+	return given, nil
+}
+
+// page is a page of a list endpoint.
+type page[T any] struct {
+	Items  []T `json:"items"`
+	Total  int `json:"total"` // Number of items matching the filters
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+// paginate answers the page of items selected by the limit and offset query
+// parameters, with a Link header to the next page if there is one.
+func paginate[T any](r *http.Request, items []T) (*response, error) {
+	query := r.URL.Query()
+	limit, offset := DefaultLimit, 0
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > MaxLimit {
+			return nil, errorf(http.StatusBadRequest, "limit must be between 1 and %d", MaxLimit)
+		}
+		limit = n
+	}
+	if value := query.Get("offset"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return nil, errorf(http.StatusBadRequest, "offset must be a positive number")
+		}
+		offset = n
+	}
+
+	result := page[T]{Items: []T{}, Total: len(items), Offset: offset, Limit: limit}
+	if offset < len(items) {
+		result.Items = items[offset:min(offset+limit, len(items))]
+	}
+	resp := &response{status: http.StatusOK, body: result}
+	if offset+limit < len(items) {
+		next := url.Values{}
+		for name, values := range query {
+			next[name] = values
+		}
+		next.Set("offset", strconv.Itoa(offset+limit))
+		next.Set("limit", strconv.Itoa(limit))
+		resp.header = http.Header{"Link": {fmt.Sprintf(`<%s?%s>; rel="next"`, r.URL.Path, next.Encode())}}
+	}
+	return resp, nil
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"Termile/internal/task"
+	"Termile/pkg/storage"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestServer returns a server listening on 127.0.0.1:8080 for a
+// TaskManager saved in a temporary directory.
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	tm := task.NewTaskManager()
+	s := NewServer(tm, storage.NewStore(t.TempDir(), tm, 0))
+	s.SetAddr("127.0.0.1:8080")
+	s.AllowOrigins("http://localhost:3000")
+	t.Cleanup(s.Close)
+	return s
+}
+
+// serve answers a request creating a project, with the given headers.
+func serve(s *Server, host string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "http://"+host+"/projects", strings.NewReader(`{"Name": "Website"}`))
+	for name, values := range header {
+		r.Header[name] = values
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w
+}
+
+func TestServerChecksRequests(t *testing.T) {
+	json := "application/json"
+	tests := []struct {
+		name   string
+		host   string
+		header http.Header
+		status int
+	}{
+		{"JSON body", "127.0.0.1:8080", http.Header{"Content-Type": {json}}, http.StatusCreated},
+		{"JSON body with a charset", "localhost:8080", http.Header{"Content-Type": {json + "; charset=utf-8"}}, http.StatusCreated},
+		{"form body", "127.0.0.1:8080", http.Header{"Content-Type": {"application/x-www-form-urlencoded"}}, http.StatusUnsupportedMediaType},
+		{"text body", "127.0.0.1:8080", http.Header{"Content-Type": {"text/plain"}}, http.StatusUnsupportedMediaType},
+		{"no content type", "127.0.0.1:8080", nil, http.StatusUnsupportedMediaType},
+		{"same origin", "127.0.0.1:8080", http.Header{"Content-Type": {json}, "Origin": {"http://127.0.0.1:8080"}}, http.StatusCreated},
+		{"allowed origin", "127.0.0.1:8080", http.Header{"Content-Type": {json}, "Origin": {"http://localhost:3000"}}, http.StatusCreated},
+		{"other origin", "127.0.0.1:8080", http.Header{"Content-Type": {json}, "Origin": {"https://example.com"}}, http.StatusForbidden},
+		{"opaque origin", "127.0.0.1:8080", http.Header{"Content-Type": {json}, "Origin": {"null"}}, http.StatusForbidden},
+		{"other host", "example.com:8080", http.Header{"Content-Type": {json}}, http.StatusForbidden},
+		{"rebound host with its own origin", "example.com:8080", http.Header{"Content-Type": {json}, "Origin": {"http://example.com:8080"}}, http.StatusForbidden},
+		{"IPv6 loopback", "[::1]:8080", http.Header{"Content-Type": {json}}, http.StatusCreated},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := serve(newTestServer(t), test.host, test.header)
+			if w.Code != test.status {
+				t.Errorf("status = %d, want %d: %s", w.Code, test.status, w.Body)
+			}
+		})
+	}
+}
+
+func TestServerOnEveryInterfaceAcceptsIPs(t *testing.T) {
+	s := newTestServer(t)
+	s.SetAddr(":8080")
+	header := http.Header{"Content-Type": {"application/json"}}
+	if w := serve(s, "192.168.1.10:8080", header); w.Code != http.StatusCreated {
+		t.Errorf("status = %d for an IP, want %d", w.Code, http.StatusCreated)
+	}
+	if w := serve(s, "example.com:8080", header); w.Code != http.StatusForbidden {
+		t.Errorf("status = %d for a domain, want %d", w.Code, http.StatusForbidden)
+	}
+}
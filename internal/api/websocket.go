@@ -0,0 +1,202 @@
+package api
+
+import (
+	"Termile/internal/task"
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// websocketGUID is appended to the key of a WebSocket handshake, see RFC 6455.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Opcodes of WebSocket frames
+const (
+	opText  = 0x1
+	opClose = 0x8
+	opPing  = 0x9
+	opPong  = 0xA
+)
+
+// maxFrameSize is the size of the largest frame read from a client, which
+// has nothing to send but control frames.
+const maxFrameSize = 64 * 1024
+
+// writeTimeout bounds the time a frame takes to write to a stalled client.
+const writeTimeout = 10 * time.Second
+
+// websocketConn is the server side of a WebSocket connection, only as much of
+// RFC 6455 as streaming messages to a client takes.
+type websocketConn struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+	mu   sync.Mutex // Serializes writes
+}
+
+// headerHas reports whether a comma-separated header lists a token.
+func headerHas(r *http.Request, name, token string) bool {
+	for _, value := range r.Header.Values(name) {
+		for _, item := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(item), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// upgradeWebSocket performs the opening handshake of a WebSocket.
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request) (*websocketConn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !headerHas(r, "Connection", "upgrade") || !headerHas(r, "Upgrade", "websocket") || key == "" {
+		return nil, errorf(http.StatusBadRequest, "expected a WebSocket handshake")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, errorf(http.StatusUpgradeRequired, "unsupported WebSocket version")
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("the connection cannot be upgraded")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+		base64.StdEncoding.EncodeToString(sum[:]))
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// Reads no longer time out, the client may stay silent
+	conn.SetDeadline(time.Time{})
+	return &websocketConn{conn: conn, rw: rw}, nil
+}
+
+// Close closes the connection.
+func (c *websocketConn) Close() error {
+	return c.conn.Close()
+}
+
+// writeFrame writes a final, unmasked frame.
+func (c *websocketConn) writeFrame(opcode byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.bufferFrame(opcode, payload)
+}
+
+// bufferFrame writes a frame and flushes it, c.mu being held.
+func (c *websocketConn) bufferFrame(opcode byte, payload []byte) error {
+	header := []byte{0x80 | opcode, 0}
+	switch n := len(payload); {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xFFFF:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	c.rw.Write(header)
+	c.rw.Write(payload)
+	return c.rw.Flush()
+}
+
+// writeEvents writes events as text messages, one per event.
+func (c *websocketConn) writeEvents(events []task.Event) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		if err := c.bufferFrame(opText, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readFrame reads a frame from the client, whose frames are masked.
+func (c *websocketConn) readFrame() (opcode byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.rw, header[:]); err != nil {
+		return 0, nil, err
+	}
+	opcode = header[0] & 0x0F
+	size := uint64(header[1] & 0x7F)
+	switch size {
+	case 126:
+		var n [2]byte
+		if _, err := io.ReadFull(c.rw, n[:]); err != nil {
+			return 0, nil, err
+		}
+		size = uint64(binary.BigEndian.Uint16(n[:]))
+	case 127:
+		var n [8]byte
+		if _, err := io.ReadFull(c.rw, n[:]); err != nil {
+			return 0, nil, err
+		}
+		size = binary.BigEndian.Uint64(n[:])
+	}
+	if size > maxFrameSize {
+		return 0, nil, fmt.Errorf("frame of %d bytes is too large", size)
+	}
+	var mask [4]byte
+	masked := header[1]&0x80 != 0
+	if masked {
+		if _, err := io.ReadFull(c.rw, mask[:]); err != nil {
+			return 0, nil, err
+		}
+	}
+	payload = make([]byte, size)
+	if _, err := io.ReadFull(c.rw, payload); err != nil {
+		return 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return opcode, payload, nil
+}
+
+// discardMessages reads the frames of the client until it closes the
+// connection, answering pings and ignoring messages.
+func (c *websocketConn) discardMessages() {
+	for {
+		opcode, payload, err := c.readFrame()
+		if err != nil {
+			return
+		}
+		switch opcode {
+		case opPing:
+			if c.writeFrame(opPong, payload) != nil {
+				return
+			}
+		case opClose:
+			// Echo the status code of the client
+			if len(payload) > 2 {
+				payload = payload[:2]
+			}
+			c.writeFrame(opClose, payload)
+			return
+		}
+	}
+}
@@ -0,0 +1,110 @@
+// Package config reads and writes the user settings of termile, kept in
+// $XDG_CONFIG_HOME/termile/config.json.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultAutosaveSeconds is the autosave delay used when none is configured.
+const DefaultAutosaveSeconds = 5
+
+// DefaultHookTimeoutSeconds is how long a hook script may run when no
+// timeout is configured.
+const DefaultHookTimeoutSeconds = 5
+
+// Config holds the user settings.
+type Config struct {
+	CurrentUser string `json:"current_user,omitempty"` // Handle of the person using termile
+
+	// History retention; zero keeps the history forever
+	HistoryRetentionDays int `json:"history_retention_days,omitempty"`
+	HistoryMaxEntries    int `json:"history_max_entries,omitempty"`
+
+	// Seconds without changes after which the UI saves; zero uses
+	// DefaultAutosaveSeconds and a negative value turns autosave off
+	AutosaveSeconds int `json:"autosave_seconds,omitempty"`
+
+	// Seconds a hook script may run before it is killed and the change
+	// vetoed; zero uses DefaultHookTimeoutSeconds
+	HookTimeoutSeconds int `json:"hook_timeout_seconds,omitempty"`
+}
+
+// AutosaveDelay returns how long the UI waits after a change before saving,
+// or zero if it does not save automatically.
+func (c Config) AutosaveDelay() time.Duration {
+	switch {
+	case c.AutosaveSeconds < 0:
+		return 0
+	case c.AutosaveSeconds == 0:
+		return DefaultAutosaveSeconds * time.Second
+	}
+	return time.Duration(c.AutosaveSeconds) * time.Second
+}
+
+// HookTimeout returns how long a hook script may run.
+func (c Config) HookTimeout() time.Duration {
+	if c.HookTimeoutSeconds <= 0 {
+		return DefaultHookTimeoutSeconds * time.Second
+	}
+	return time.Duration(c.HookTimeoutSeconds) * time.Second
+}
+
+// Dir returns the directory holding the termile settings.
+func Dir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "termile"), nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "termile"), nil
+}
+
+// Path returns the path of the settings file.
+func Path() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.json"), nil
+}
+
+// Load reads the settings, returning the defaults when none were saved.
+func Load() (Config, error) {
+	var config Config
+	path, err := Path()
+	if err != nil {
+		return config, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return config, err
+	}
+	err = json.Unmarshal(data, &config)
+	return config, err
+}
+
+// Save writes the settings, creating their directory if needed.
+func Save(config Config) error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
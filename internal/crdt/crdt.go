@@ -0,0 +1,217 @@
+// Package crdt represents the projects, views and people as a conflict-free
+// replicated data type, so that copies edited apart converge once they have
+// exchanged their operations, whatever the order they receive them in.
+//
+// Every entity is an element of an observed-remove set (OR-set): projects,
+// the tasks of a project and the subtasks of a task, people and views. An
+// entity is present while one of the adds of its UID was not observed by a
+// remove, so an entity added again or changed concurrently with its removal
+// is kept. Each field of an entity is a last-writer-wins (LWW) register,
+// ordered by Lamport timestamps. Comments and time entries, which people add
+// to concurrently, are OR-sets of items.
+package crdt
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// Kinds of Op
+const (
+	OpAdd        = "add"         // Adds an entity
+	OpRemove     = "remove"      // Removes the observed adds of an entity
+	OpSet        = "set"         // Sets a field of an entity
+	OpAddItem    = "add-item"    // Adds an item to a list field
+	OpRemoveItem = "remove-item" // Removes the observed adds of an item
+)
+
+// Stamp is a Lamport timestamp identifying an op. Ops made by the same site
+// have increasing counters, and an op made after receiving another one has a
+// greater counter; the site breaks ties.
+type Stamp struct {
+	Counter uint64 `json:"c"`
+	Site    string `json:"s"`
+}
+
+// Less orders stamps.
+func (s Stamp) Less(o Stamp) bool {
+	if s.Counter != o.Counter {
+		return s.Counter < o.Counter
+	}
+	return s.Site < o.Site
+}
+
+// Op is one operation on the replicated state.
+type Op struct {
+	Stamp  Stamp           `json:"stamp"`
+	Kind   string          `json:"kind"`
+	Entity string          `json:"entity"` // task.EntityProject, EntityTask, ...
+	UID    string          `json:"uid"`
+	Parent string          `json:"parent,omitempty"` // UID of the project of a task or the task of a subtask
+	Field  string          `json:"field,omitempty"`
+	Value  json.RawMessage `json:"value,omitempty"` // Of a field or an item
+	Tags   []Stamp         `json:"tags,omitempty"`  // Adds observed by a remove
+}
+
+// register is a LWW register.
+type register struct {
+	value json.RawMessage
+	stamp Stamp
+}
+
+// item is an element of the OR-set of a list field.
+type item struct {
+	value json.RawMessage
+	adds  map[Stamp]bool
+}
+
+// element is an entity of the OR-set, with its fields.
+type element struct {
+	entity string
+	parent string
+	first  Stamp // First add, which orders the entities and gives their parent
+	adds   map[Stamp]bool
+	fields map[string]register
+	items  map[string]map[string]*item // By field and value
+}
+
+// Doc is the replicated state of one site.
+type Doc struct {
+	site     string
+	clock    uint64
+	seen     map[Stamp]bool
+	removed  map[Stamp]bool // Adds observed by removes
+	elements map[string]*element
+}
+
+// NewDoc creates an empty state for a site, which must be unique among the
+// sites ever making ops.
+func NewDoc(site string) *Doc {
+	return &Doc{site: site, seen: map[Stamp]bool{}, removed: map[Stamp]bool{}, elements: map[string]*element{}}
+}
+
+// Has reports whether an op was applied.
+func (d *Doc) Has(stamp Stamp) bool {
+	return d.seen[stamp]
+}
+
+// next returns the stamp of a new op.
+func (d *Doc) next() Stamp {
+	d.clock++
+	return Stamp{Counter: d.clock, Site: d.site}
+}
+
+// element returns the element of a UID, creating it if needed.
+func (d *Doc) element(entity, uid string) *element {
+	e := d.elements[uid]
+	if e == nil {
+		e = &element{entity: entity, adds: map[Stamp]bool{}, fields: map[string]register{}, items: map[string]map[string]*item{}}
+		d.elements[uid] = e
+	}
+	return e
+}
+
+// Apply applies an op, made here or received from another site. Applying is
+// idempotent and commutative: ops applied in any order, any number of times,
+// give the same state. It returns false if the op was already applied.
+func (d *Doc) Apply(op Op) bool {
+	if d.seen[op.Stamp] {
+		return false
+	}
+	d.seen[op.Stamp] = true
+	d.clock = max(d.clock, op.Stamp.Counter)
+
+	e := d.element(op.Entity, op.UID)
+	switch op.Kind {
+	case OpAdd:
+		// The first add places the entity, whatever the order adds arrive in
+		if e.first == (Stamp{}) || op.Stamp.Less(e.first) {
+			e.first, e.parent = op.Stamp, op.Parent
+		}
+		if !d.removed[op.Stamp] {
+			e.adds[op.Stamp] = true
+		}
+	case OpRemove:
+		for _, tag := range op.Tags {
+			d.removed[tag] = true
+			delete(e.adds, tag)
+		}
+	case OpSet:
+		if current, ok := e.fields[op.Field]; !ok || current.stamp.Less(op.Stamp) {
+			e.fields[op.Field] = register{value: op.Value, stamp: op.Stamp}
+		}
+	case OpAddItem, OpRemoveItem:
+		items := e.items[op.Field]
+		if items == nil {
+			items = map[string]*item{}
+			e.items[op.Field] = items
+		}
+		it := items[string(op.Value)]
+		if it == nil {
+			it = &item{value: op.Value, adds: map[Stamp]bool{}}
+			items[string(op.Value)] = it
+		}
+		if op.Kind == OpRemoveItem {
+			for _, tag := range op.Tags {
+				d.removed[tag] = true
+				delete(it.adds, tag)
+			}
+		} else if !d.removed[op.Stamp] {
+			it.adds[op.Stamp] = true
+		}
+	}
+	return true
+}
+
+// live reports whether the entity of a UID is present, with its parents.
+func (d *Doc) live(uid string) bool {
+	for uid != "" {
+		e := d.elements[uid]
+		if e == nil || len(e.adds) == 0 {
+			return false
+		}
+		uid = e.parent
+	}
+	return true
+}
+
+// tags returns the adds of an entity or item, for a remove to observe.
+func tags(adds map[Stamp]bool) []Stamp {
+	observed := make([]Stamp, 0, len(adds))
+	for tag := range adds {
+		observed = append(observed, tag)
+	}
+	sort.Slice(observed, func(i, j int) bool { return observed[i].Less(observed[j]) })
+	return observed
+}
+
+// children returns the UIDs of the live entities of a kind under a parent,
+// in the order they were first added.
+func (d *Doc) children(entity, parent string) []string {
+	var uids []string
+	for uid, e := range d.elements {
+		if e.entity == entity && e.parent == parent && d.live(uid) {
+			uids = append(uids, uid)
+		}
+	}
+	sort.Slice(uids, func(i, j int) bool {
+		a, b := d.elements[uids[i]].first, d.elements[uids[j]].first
+		if a != b {
+			return a.Less(b)
+		}
+		return uids[i] < uids[j]
+	})
+	return uids
+}
+
+// liveItems returns the values of the live items of a list field.
+func (e *element) liveItems(field string) []json.RawMessage {
+	var values []json.RawMessage
+	for _, it := range e.items[field] {
+		if len(it.adds) > 0 {
+			values = append(values, it.value)
+		}
+	}
+	sort.Slice(values, func(i, j int) bool { return string(values[i]) < string(values[j]) })
+	return values
+}
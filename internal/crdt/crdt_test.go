@@ -0,0 +1,330 @@
+package crdt
+
+import (
+	"Termile/internal/merge"
+	"Termile/internal/task"
+	"encoding/json"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// site is a Doc with the ops it made, to deliver to the other sites.
+type site struct {
+	doc *Doc
+	ops []Op
+}
+
+// newSites creates sites sharing a base state recorded by the first one.
+func newSites(base merge.State, names ...string) []*site {
+	sites := make([]*site, len(names))
+	for i, name := range names {
+		sites[i] = &site{doc: NewDoc(name)}
+	}
+	ops := sites[0].doc.Record(base)
+	for _, s := range sites[1:] {
+		deliver(s.doc, ops)
+	}
+	sites[0].ops = ops
+	return sites
+}
+
+// edit changes the state of a site and records the ops it made.
+func (s *site) edit(fn func(state *merge.State)) {
+	state := s.doc.Materialize()
+	fn(&state)
+	s.ops = append(s.ops, s.doc.Record(state)...)
+}
+
+// deliver applies ops to a Doc.
+func deliver(d *Doc, ops []Op) {
+	for _, op := range ops {
+		d.Apply(op)
+	}
+}
+
+// allOps returns the ops made by every site.
+func allOps(sites []*site) []Op {
+	var ops []Op
+	for _, s := range sites {
+		ops = append(ops, s.ops...)
+	}
+	return ops
+}
+
+// converge applies the ops of every site to fresh Docs in several shuffled
+// orders, some ops twice, and checks that they all materialize the same
+// state, which it returns.
+func converge(t *testing.T, sites []*site) merge.State {
+	t.Helper()
+	ops := allOps(sites)
+	rng := rand.New(rand.NewSource(1))
+	var want []byte
+	var state merge.State
+	for i := 0; i < 20; i++ {
+		shuffled := append([]Op(nil), ops...)
+		shuffled = append(shuffled, ops[:rng.Intn(len(ops)+1)]...)
+		rng.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+		d := NewDoc("reader")
+		deliver(d, shuffled)
+		state = d.Materialize()
+		got := mustMarshal(state)
+		if want == nil {
+			want = got
+		} else if string(got) != string(want) {
+			t.Fatalf("order %d materialized\n%s\nwant\n%s", i, got, want)
+		}
+	}
+	// The sites themselves converge once they received every op
+	for _, s := range sites {
+		deliver(s.doc, ops)
+		if got := mustMarshal(s.doc.Materialize()); string(got) != string(want) {
+			t.Fatalf("site %s materialized\n%s\nwant\n%s", s.doc.site, got, want)
+		}
+	}
+	return state
+}
+
+// baseState is a project with two tasks, the second depending on the first,
+// which has a subtask.
+func baseState() merge.State {
+	return merge.State{Projects: []task.Project{{
+		ID: 1, UID: "p1", Name: "Website",
+		Tasks: []task.Task{
+			{ID: 1, UID: "t1", Title: "Design", Status: "todo", Subtasks: []task.Subtask{{ID: 1, UID: "s1", Title: "Mockups"}}},
+			{ID: 2, UID: "t2", Title: "Build", Status: "todo", DependsOn: []int{1}},
+		},
+	}}}
+}
+
+// findTask returns the task of a UID in the first project, or nil.
+func findTask(state merge.State, uid string) *task.Task {
+	if len(state.Projects) == 0 {
+		return nil
+	}
+	for i, t := range state.Projects[0].Tasks {
+		if t.UID == uid {
+			return &state.Projects[0].Tasks[i]
+		}
+	}
+	return nil
+}
+
+// removeTask removes the task of a UID from the first project.
+func removeTask(state *merge.State, uid string) {
+	p := &state.Projects[0]
+	for i, t := range p.Tasks {
+		if t.UID == uid {
+			p.Tasks = append(p.Tasks[:i], p.Tasks[i+1:]...)
+			return
+		}
+	}
+}
+
+func TestConvergesInAnyOrder(t *testing.T) {
+	sites := newSites(baseState(), "a", "b", "c")
+	a, b, c := sites[0], sites[1], sites[2]
+	a.edit(func(s *merge.State) {
+		findTask(*s, "t1").Title = "Design the pages"
+		findTask(*s, "t2").Status = "doing"
+	})
+	b.edit(func(s *merge.State) {
+		findTask(*s, "t1").Title = "Design the site"
+		findTask(*s, "t1").Estimate = 3
+	})
+	c.edit(func(s *merge.State) {
+		s.Projects[0].Name = "Web site"
+		findTask(*s, "t1").Subtasks[0].Complete = true
+	})
+	// b edits again after receiving the edits of a
+	deliver(b.doc, a.ops)
+	b.edit(func(s *merge.State) { findTask(*s, "t2").Status = "review" })
+
+	state := converge(t, sites)
+	t1, t2 := findTask(state, "t1"), findTask(state, "t2")
+	// Concurrent titles are ordered by stamp: b's counter ties with a's, and
+	// the site breaks the tie
+	if t1.Title != "Design the site" || t1.Estimate != 3 {
+		t.Errorf("t1 = %q, estimate %v, want the title and estimate of b", t1.Title, t1.Estimate)
+	}
+	if t2.Status != "review" {
+		t.Errorf("t2 status = %q, want the later status of b", t2.Status)
+	}
+	if state.Projects[0].Name != "Web site" || !t1.Subtasks[0].Complete {
+		t.Errorf("edits of c lost: %+v", state.Projects[0])
+	}
+	if len(t2.DependsOn) != 1 || t2.DependsOn[0] != 1 {
+		t.Errorf("t2 depends on %v, want [1]", t2.DependsOn)
+	}
+}
+
+func TestAddRemoveRaces(t *testing.T) {
+	tests := []struct {
+		name   string
+		a, b   func(s *merge.State)
+		t1, t2 bool // Whether the tasks are present after merging
+		title  string
+	}{
+		{
+			name: "remove on both sides",
+			a:    func(s *merge.State) { removeTask(s, "t1") },
+			b:    func(s *merge.State) { removeTask(s, "t1") },
+			t1:   false, t2: true,
+		},
+		{
+			name: "remove and edit another task",
+			a:    func(s *merge.State) { removeTask(s, "t1") },
+			b:    func(s *merge.State) { findTask(*s, "t2").Title = "Build it" },
+			t1:   false, t2: true,
+		},
+		{
+			name: "edit wins over a concurrent remove",
+			a:    func(s *merge.State) { removeTask(s, "t1") },
+			b:    func(s *merge.State) { findTask(*s, "t1").Title = "Design again" },
+			t1:   true, t2: true, title: "Design again",
+		},
+		{
+			name: "comment wins over a concurrent remove",
+			a:    func(s *merge.State) { removeTask(s, "t1") },
+			b: func(s *merge.State) {
+				findTask(*s, "t1").Comments = []task.Comment{{ID: 1, Author: "sara", Body: "keep this"}}
+			},
+			t1: true, t2: true, title: "Design",
+		},
+		{
+			name: "remove the project and edit a task",
+			a:    func(s *merge.State) { s.Projects = nil },
+			b:    func(s *merge.State) { findTask(*s, "t2").Title = "Build it" },
+			t1:   false, t2: false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sites := newSites(baseState(), "a", "b")
+			sites[0].edit(test.a)
+			sites[1].edit(test.b)
+			state := converge(t, sites)
+			t1, t2 := findTask(state, "t1"), findTask(state, "t2")
+			if (t1 != nil) != test.t1 || (t2 != nil) != test.t2 {
+				t.Fatalf("t1 present %v, t2 present %v, want %v and %v", t1 != nil, t2 != nil, test.t1, test.t2)
+			}
+			if t1 != nil && t1.Title != test.title {
+				t.Errorf("t1 title = %q, want %q", t1.Title, test.title)
+			}
+			if t1 == nil && t2 != nil && len(t2.DependsOn) != 0 {
+				t.Errorf("t2 depends on %v, a removed task", t2.DependsOn)
+			}
+		})
+	}
+}
+
+func TestRemovedThenAddedAgain(t *testing.T) {
+	sites := newSites(baseState(), "a", "b")
+	a, b := sites[0], sites[1]
+	removed := findTask(a.doc.Materialize(), "t1")
+	a.edit(func(s *merge.State) { removeTask(s, "t1") })
+	deliver(b.doc, a.ops)
+	// b restores the task it received the removal of, as an undo would
+	b.edit(func(s *merge.State) {
+		s.Projects[0].Tasks = append([]task.Task{*removed}, s.Projects[0].Tasks...)
+	})
+	if findTask(converge(t, sites), "t1") == nil {
+		t.Fatal("task added again after its removal is missing")
+	}
+}
+
+func TestCommentsAndTimeEntries(t *testing.T) {
+	day := time.Date(2024, 10, 7, 9, 0, 0, 0, time.UTC)
+	end := day.Add(time.Hour)
+	base := baseState()
+	findTask(base, "t1").Comments = []task.Comment{{ID: 1, Author: "sara", Body: "first", CreatedAt: day}}
+	findTask(base, "t1").TimeEntries = []task.TimeEntry{{Start: day, End: &end}}
+
+	sites := newSites(base, "a", "b", "c")
+	a, b, c := sites[0], sites[1], sites[2]
+	a.edit(func(s *merge.State) {
+		t1 := findTask(*s, "t1")
+		t1.Comments = append(t1.Comments, task.Comment{ID: 2, Author: "ali", Body: "from a", CreatedAt: day.Add(2 * time.Hour)})
+		t1.TimeEntries = append(t1.TimeEntries, task.TimeEntry{Start: day.Add(3 * time.Hour)})
+	})
+	b.edit(func(s *merge.State) {
+		// Numbered 2 on b as well, renumbered when merged
+		t1 := findTask(*s, "t1")
+		t1.Comments = append(t1.Comments, task.Comment{ID: 2, Author: "bo", Body: "from b", CreatedAt: day.Add(time.Hour)})
+	})
+	c.edit(func(s *merge.State) {
+		t1 := findTask(*s, "t1")
+		t1.Comments = nil
+		t1.TimeEntries = nil
+	})
+
+	t1 := findTask(converge(t, sites), "t1")
+	var bodies []string
+	for i, comment := range t1.Comments {
+		if comment.ID != i+1 {
+			t.Errorf("comment %q numbered %d, want %d", comment.Body, comment.ID, i+1)
+		}
+		bodies = append(bodies, comment.Body)
+	}
+	if got := string(mustMarshal(bodies)); got != `["from b","from a"]` {
+		t.Errorf("comments = %s, want the added ones by time, without the removed one", got)
+	}
+	if len(t1.TimeEntries) != 1 || !t1.TimeEntries[0].Start.Equal(day.Add(3*time.Hour)) || t1.TimeEntries[0].End != nil {
+		t.Errorf("time entries = %+v, want only the running entry of a", t1.TimeEntries)
+	}
+}
+
+func TestRenumberClashingIDs(t *testing.T) {
+	sites := newSites(baseState(), "a", "b")
+	a, b := sites[0], sites[1]
+	// Both copies number their new task 3; b's new task depends on it
+	a.edit(func(s *merge.State) {
+		s.Projects[0].Tasks = append(s.Projects[0].Tasks, task.Task{ID: 3, UID: "a3", Title: "Deploy"})
+	})
+	b.edit(func(s *merge.State) {
+		s.Projects[0].Tasks = append(s.Projects[0].Tasks,
+			task.Task{ID: 3, UID: "b3", Title: "Test"},
+			task.Task{ID: 4, UID: "b4", Title: "Release", DependsOn: []int{3, 2}})
+	})
+	// A new project numbered 2 on both copies
+	a.edit(func(s *merge.State) {
+		s.Projects = append(s.Projects, task.Project{ID: 2, UID: "pa", Name: "Blog"})
+	})
+	b.edit(func(s *merge.State) {
+		s.Projects = append(s.Projects, task.Project{ID: 2, UID: "pb", Name: "Shop"})
+	})
+
+	state := converge(t, sites)
+	ids := map[string]int{}
+	for _, p := range state.Projects {
+		ids[p.UID] = p.ID
+		for _, t := range p.Tasks {
+			ids[t.UID] = t.ID
+		}
+	}
+	// a's adds tie with b's on the counter, and site a orders first
+	want := map[string]int{"p1": 1, "pa": 2, "pb": 3, "t1": 1, "t2": 2, "a3": 3, "b3": 5, "b4": 4}
+	if got, expected := mustMarshal(ids), mustMarshal(want); string(got) != string(expected) {
+		t.Fatalf("IDs = %s, want %s", got, expected)
+	}
+	if deps := findTask(state, "b4").DependsOn; len(deps) != 2 || deps[0] != 5 || deps[1] != 2 {
+		t.Errorf("b4 depends on %v, want [5 2] after renumbering b3", deps)
+	}
+}
+
+func TestOpsRoundTripAsJSON(t *testing.T) {
+	sites := newSites(baseState(), "a")
+	data, err := json.Marshal(sites[0].ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ops []Op
+	if err := json.Unmarshal(data, &ops); err != nil {
+		t.Fatal(err)
+	}
+	d := NewDoc("b")
+	deliver(d, ops)
+	if got, want := mustMarshal(d.Materialize()), mustMarshal(sites[0].doc.Materialize()); string(got) != string(want) {
+		t.Errorf("decoded ops materialized\n%s\nwant\n%s", got, want)
+	}
+}
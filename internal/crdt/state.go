@@ -0,0 +1,316 @@
+package crdt
+
+import (
+	"Termile/internal/merge"
+	"Termile/internal/task"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// listFields are the fields replicated as OR-sets of items rather than
+// registers.
+var listFields = map[string]bool{"Comments": true, "TimeEntries": true}
+
+// Record makes and applies the ops turning the replicated state into the
+// given one, e.g. after a local change, and returns them. Entities are
+// matched by UID, people by handle and views by name; a field equal to its
+// replicated value needs no op.
+func (d *Doc) Record(state merge.State) []Op {
+	var ops []Op
+	emit := func(op Op) {
+		op.Stamp = d.next()
+		d.Apply(op)
+		ops = append(ops, op)
+	}
+	present := map[string]bool{}
+
+	for _, p := range state.Projects {
+		d.recordEntity(task.EntityProject, p.UID, "", p, nil, present, emit)
+		taskUIDs := map[int]string{}
+		for _, t := range p.Tasks {
+			taskUIDs[t.ID] = t.UID
+		}
+		for _, t := range p.Tasks {
+			// Dependencies are replicated by UID, since IDs may be renumbered
+			dependsOn := []string{}
+			for _, id := range t.DependsOn {
+				if uid, ok := taskUIDs[id]; ok {
+					dependsOn = append(dependsOn, uid)
+				}
+			}
+			d.recordEntity(task.EntityTask, t.UID, p.UID, t, map[string]any{"DependsOn": dependsOn}, present, emit)
+			for _, s := range t.Subtasks {
+				d.recordEntity(task.EntitySubtask, s.UID, t.UID, s, nil, present, emit)
+			}
+		}
+	}
+	for _, person := range state.People {
+		d.recordEntity(task.EntityPerson, personUID(person.Handle), "", person, nil, present, emit)
+	}
+	for _, view := range state.Views {
+		d.recordEntity(task.EntityView, viewUID(view.Name), "", view, nil, present, emit)
+	}
+
+	var removed []string
+	for uid, e := range d.elements {
+		if !present[uid] && len(e.adds) > 0 {
+			removed = append(removed, uid)
+		}
+	}
+	sort.Strings(removed)
+	for _, uid := range removed {
+		e := d.elements[uid]
+		emit(Op{Kind: OpRemove, Entity: e.entity, UID: uid, Tags: tags(e.adds)})
+	}
+	return ops
+}
+
+// personUID is the UID of a person in the replicated state.
+func personUID(handle string) string {
+	return task.EntityPerson + ":" + strings.ToLower(handle)
+}
+
+// viewUID is the UID of a view in the replicated state.
+func viewUID(name string) string {
+	return task.EntityView + ":" + name
+}
+
+// recordEntity makes the ops adding an entity if it is not present, and
+// setting its fields that differ. overrides replaces the value of fields.
+// Changing an entity adds it again, so that a copy removing it concurrently,
+// which cannot have observed that add, does not lose the change.
+func (d *Doc) recordEntity(entity, uid, parent string, value any, overrides map[string]any, present map[string]bool, emit func(Op)) {
+	present[uid] = true
+	e := d.elements[uid]
+	if e == nil || len(e.adds) == 0 {
+		emit(Op{Kind: OpAdd, Entity: entity, UID: uid, Parent: parent})
+		e = d.elements[uid]
+	} else {
+		added := false
+		emitAdd := emit
+		emit = func(op Op) {
+			if !added {
+				added = true
+				emitAdd(Op{Kind: OpAdd, Entity: entity, UID: uid, Parent: parent})
+			}
+			emitAdd(op)
+		}
+	}
+
+	fields := fieldMap(value)
+	for name, v := range overrides {
+		fields[name] = mustMarshal(v)
+	}
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		if name != "UID" && name != "Tasks" && name != "Subtasks" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if listFields[name] {
+			d.recordItems(e, entity, uid, name, fields[name], emit)
+			continue
+		}
+		if current, ok := e.fields[name]; !ok || !bytes.Equal(current.value, fields[name]) {
+			emit(Op{Kind: OpSet, Entity: entity, UID: uid, Field: name, Value: fields[name]})
+		}
+	}
+}
+
+// recordItems makes the ops adding and removing the items of a list field.
+func (d *Doc) recordItems(e *element, entity, uid, field string, list json.RawMessage, emit func(Op)) {
+	want := map[string]bool{}
+	for _, value := range itemValues(field, list) {
+		want[string(value)] = true
+		if it := e.items[field][string(value)]; it == nil || len(it.adds) == 0 {
+			emit(Op{Kind: OpAddItem, Entity: entity, UID: uid, Field: field, Value: value})
+		}
+	}
+	for _, value := range e.liveItems(field) {
+		if !want[string(value)] {
+			emit(Op{Kind: OpRemoveItem, Entity: entity, UID: uid, Field: field, Value: value, Tags: tags(e.items[field][string(value)].adds)})
+		}
+	}
+}
+
+// itemValues encodes the items of a list field one by one. Comments are
+// numbered when the state is materialized, so their IDs are left out.
+func itemValues(field string, list json.RawMessage) []json.RawMessage {
+	var items []map[string]json.RawMessage
+	if err := json.Unmarshal(list, &items); err != nil {
+		panic(fmt.Sprintf("crdt: cannot decode %s: %v", field, err))
+	}
+	values := make([]json.RawMessage, len(items))
+	for i, item := range items {
+		if field == "Comments" {
+			delete(item, "ID")
+		}
+		values[i] = mustMarshal(item)
+	}
+	return values
+}
+
+// Materialize returns the replicated state. Entities are in the order they
+// were first added. Since copies number new entities apart, an ID used by
+// several projects, tasks or subtasks stays with the one added first and the
+// others are renumbered.
+func (d *Doc) Materialize() merge.State {
+	var state merge.State
+	dependsOn := map[string][]string{}
+	for _, projectUID := range d.children(task.EntityProject, "") {
+		var p task.Project
+		d.decode(projectUID, &p)
+		for _, taskUID := range d.children(task.EntityTask, projectUID) {
+			var t task.Task
+			d.decode(taskUID, &t, "DependsOn")
+			if uids := d.elements[taskUID].fields["DependsOn"].value; uids != nil {
+				var list []string
+				if json.Unmarshal(uids, &list) == nil {
+					dependsOn[taskUID] = list
+				}
+			}
+			for _, subtaskUID := range d.children(task.EntitySubtask, taskUID) {
+				var s task.Subtask
+				d.decode(subtaskUID, &s)
+				t.Subtasks = append(t.Subtasks, s)
+			}
+			p.Tasks = append(p.Tasks, t)
+		}
+		state.Projects = append(state.Projects, p)
+	}
+	for _, uid := range d.children(task.EntityPerson, "") {
+		var person task.Person
+		d.decode(uid, &person)
+		state.People = append(state.People, person)
+	}
+	for _, uid := range d.children(task.EntityView, "") {
+		var view task.View
+		d.decode(uid, &view)
+		state.Views = append(state.Views, view)
+	}
+
+	d.renumber(state.Projects)
+	for i := range state.Projects {
+		taskIDs := map[string]int{}
+		for _, t := range state.Projects[i].Tasks {
+			taskIDs[t.UID] = t.ID
+		}
+		for j := range state.Projects[i].Tasks {
+			t := &state.Projects[i].Tasks[j]
+			t.DependsOn = nil
+			for _, uid := range dependsOn[t.UID] {
+				if id, ok := taskIDs[uid]; ok {
+					t.DependsOn = append(t.DependsOn, id)
+				}
+			}
+		}
+	}
+	return state
+}
+
+// decode sets v to the replicated fields of an entity, except the fields in
+// skip.
+func (d *Doc) decode(uid string, v any, skip ...string) {
+	e := d.elements[uid]
+	fields := map[string]json.RawMessage{}
+	for name, r := range e.fields {
+		if !slices.Contains(skip, name) {
+			fields[name] = r.value
+		}
+	}
+	switch e.entity {
+	case task.EntityProject, task.EntityTask, task.EntitySubtask:
+		fields["UID"] = mustMarshal(uid)
+	}
+	for name := range listFields {
+		if values := e.liveItems(name); len(values) > 0 {
+			fields[name] = mustMarshal(values)
+		}
+	}
+	// Fields are decoded one by one, so that a value this version cannot
+	// read leaves only its field unset
+	for name, value := range fields {
+		_ = json.Unmarshal(mustMarshal(map[string]json.RawMessage{name: value}), v)
+	}
+
+	switch entity := v.(type) {
+	case *task.Task:
+		sortItems(&entity.Comments, &entity.TimeEntries)
+	case *task.Subtask:
+		sortItems(&entity.Comments, &entity.TimeEntries)
+	}
+}
+
+// sortItems orders comments and time entries by time and numbers comments.
+func sortItems(comments *[]task.Comment, entries *[]task.TimeEntry) {
+	slices.SortStableFunc(*comments, func(a, b task.Comment) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	for i := range *comments {
+		(*comments)[i].ID = i + 1
+	}
+	slices.SortStableFunc(*entries, func(a, b task.TimeEntry) int { return a.Start.Compare(b.Start) })
+}
+
+// renumber gives the projects, tasks and subtasks whose ID is used by an
+// entity added before them the next free IDs, in the order they were added.
+func (d *Doc) renumber(projects []task.Project) {
+	type numbered struct {
+		id  *int
+		uid string
+	}
+	var kinds [3][]numbered
+	for i := range projects {
+		p := &projects[i]
+		kinds[0] = append(kinds[0], numbered{&p.ID, p.UID})
+		for j := range p.Tasks {
+			t := &p.Tasks[j]
+			kinds[1] = append(kinds[1], numbered{&t.ID, t.UID})
+			for k := range t.Subtasks {
+				kinds[2] = append(kinds[2], numbered{&t.Subtasks[k].ID, t.Subtasks[k].UID})
+			}
+		}
+	}
+	for _, entities := range kinds {
+		sort.SliceStable(entities, func(i, j int) bool {
+			return d.elements[entities[i].uid].first.Less(d.elements[entities[j].uid].first)
+		})
+		used := map[int]bool{}
+		next := 1
+		var clashing []numbered
+		for _, entity := range entities {
+			if *entity.id <= 0 || used[*entity.id] {
+				clashing = append(clashing, entity)
+				continue
+			}
+			used[*entity.id] = true
+			next = max(next, *entity.id+1)
+		}
+		for _, entity := range clashing {
+			*entity.id = next
+			next++
+		}
+	}
+}
+
+// fieldMap encodes an entity as its fields by name.
+func fieldMap(entity any) map[string]json.RawMessage {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(mustMarshal(entity), &fields); err != nil {
+		panic(fmt.Sprintf("crdt: cannot decode %T: %v", entity, err))
+	}
+	return fields
+}
+
+// mustMarshal encodes a value that always encodes.
+func mustMarshal(v any) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("crdt: cannot encode %T: %v", v, err))
+	}
+	return data
+}
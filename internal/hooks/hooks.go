@@ -0,0 +1,197 @@
+// Package hooks runs the hook scripts of the user, kept in
+// $XDG_CONFIG_HOME/termile/hooks, on the projects, tasks and subtasks a
+// change adds, modifies, completes or deletes. As in Taskwarrior, a script
+// reads the entity as JSON on its standard input, and may rewrite it by
+// printing another version on its standard output, or veto the change by
+// exiting with a non-zero status.
+package hooks
+
+import (
+	"Termile/internal/config"
+	"Termile/internal/task"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Events scripts run on. A script runs on the event its name starts with,
+// e.g. on-add or on-add.require-assignee.
+const (
+	OnAdd      = "on-add"      // Reads the added entity
+	OnModify   = "on-modify"   // Reads the entity before and after, one per line
+	OnComplete = "on-complete" // As on-modify, for a task or subtask completed
+	OnDelete   = "on-delete"   // Reads the deleted entity, cannot rewrite it
+)
+
+// Runner runs the scripts of a directory.
+type Runner struct {
+	dir     string
+	timeout time.Duration
+}
+
+// Dir returns the directory holding the hook scripts of the user.
+func Dir() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "hooks"), nil
+}
+
+// NewRunner creates a Runner of the scripts in dir, which are killed and
+// the change vetoed if they run longer than timeout. The directory is read
+// on every change, so that scripts can be added without restarting.
+func NewRunner(dir string, timeout time.Duration) *Runner {
+	return &Runner{dir: dir, timeout: timeout}
+}
+
+// Hook runs the scripts of a change, as a task.Hook. A modification
+// completing a task or subtask runs the on-modify scripts, then the
+// on-complete scripts with the entity they returned.
+func (r *Runner) Hook(op task.Op, old json.RawMessage) (json.RawMessage, error) {
+	switch op.Action {
+	case task.ChangeAdd:
+		return r.run(OnAdd, op, nil, op.Value)
+	case task.ChangeDelete:
+		_, err := r.run(OnDelete, op, nil, old)
+		return nil, err
+	}
+	value, err := r.run(OnModify, op, old, op.Value)
+	if err != nil || !completes(old, value) {
+		return value, err
+	}
+	return r.run(OnComplete, op, old, value)
+}
+
+// scripts returns the paths of the executable scripts of an event, in the
+// order of their names.
+func (r *Runner) scripts(event string) ([]string, error) {
+	entries, err := os.ReadDir(r.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), event) || entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		if info.Mode()&0111 != 0 {
+			paths = append(paths, filepath.Join(r.dir, entry.Name()))
+		}
+	}
+	return paths, nil
+}
+
+// run runs the scripts of an event one after the other, each reading the
+// entity the previous one returned, and returns the entity the last one
+// returned.
+func (r *Runner) run(event string, op task.Op, old, value json.RawMessage) (json.RawMessage, error) {
+	paths, err := r.scripts(event)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the hooks: %v", err)
+	}
+	for _, path := range paths {
+		// Entities are encoded on one line
+		var input bytes.Buffer
+		if old != nil && event != OnDelete {
+			input.Write(old)
+			input.WriteByte('\n')
+		}
+		input.Write(value)
+		input.WriteByte('\n')
+
+		output, err := r.exec(path, event, op, input.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		if output != nil && event != OnDelete {
+			value = output
+		}
+	}
+	return value, nil
+}
+
+// exec runs a script, returning the entity it printed, or nil if it printed
+// none.
+func (r *Runner) exec(path, event string, op task.Op, input []byte) (json.RawMessage, error) {
+	name := filepath.Base(path)
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Env = append(os.Environ(),
+		"TERMILE_EVENT="+event,
+		"TERMILE_ENTITY="+op.Entity,
+		"TERMILE_PROJECT_ID="+strconv.Itoa(op.ProjectID),
+		"TERMILE_TASK_ID="+strconv.Itoa(op.TaskID),
+		"TERMILE_SUBTASK_ID="+strconv.Itoa(op.SubtaskID))
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	// Do not wait for processes the script left behind holding its output
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return nil, fmt.Errorf("hook %s timed out after %v", name, r.timeout)
+	case errors.As(err, &exitErr):
+		message := feedback(stdout.Bytes(), stderr.Bytes())
+		if message == "" {
+			message = exitErr.Error()
+		}
+		return nil, fmt.Errorf("hook %s vetoed the change: %s", name, message)
+	case err != nil:
+		return nil, fmt.Errorf("hook %s: %v", name, err)
+	}
+
+	// The entity is a JSON object on the first line, any other line is
+	// feedback. The TaskManager checks it is the same entity.
+	line, _, _ := bytes.Cut(stdout.Bytes(), []byte("\n"))
+	line = bytes.TrimSpace(line)
+	if event == OnDelete || !bytes.HasPrefix(line, []byte("{")) || !json.Valid(line) {
+		return nil, nil
+	}
+	return json.RawMessage(line), nil
+}
+
+// feedback returns the message a script printed when vetoing a change: the
+// lines of its standard output that are not JSON, or else its standard
+// error.
+func feedback(stdout, stderr []byte) string {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(stdout))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !json.Valid([]byte(line)) {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return strings.Join(strings.Fields(string(stderr)), " ")
+	}
+	return strings.Join(lines, " ")
+}
+
+// completes reports whether a change of a task or subtask completes it.
+func completes(old, new json.RawMessage) bool {
+	var before, after struct{ Complete bool }
+	json.Unmarshal(old, &before)
+	json.Unmarshal(new, &after)
+	return !before.Complete && after.Complete
+}
@@ -0,0 +1,563 @@
+// Package merge merges two versions of the projects that diverged from a
+// common base, e.g. the projects.json of two machines syncing the same
+// files. Projects, tasks and subtasks are matched by UID, people by handle
+// and views by name, and merged field by field, so that changes made on both
+// sides to different fields or different entities are all kept.
+package merge
+
+import (
+	"Termile/internal/task"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"time"
+)
+
+// Sides of a merge
+const (
+	Base   = "base"
+	Ours   = "ours"
+	Theirs = "theirs"
+)
+
+// Conflict is a change made on both sides that cannot be merged: a field set
+// to different values, or an entity deleted on one side and modified on the
+// other. The merged projects hold our side until the conflict is resolved.
+type Conflict struct {
+	Entity     string          `json:"entity"` // task.EntityProject, EntityTask, EntitySubtask, EntityPerson or EntityView
+	ProjectID  int             `json:"project_id,omitempty"`
+	TaskID     int             `json:"task_id,omitempty"`
+	SubtaskID  int             `json:"subtask_id,omitempty"`
+	Key        string          `json:"key,omitempty"`   // Handle of a person or name of a view
+	Title      string          `json:"title"`           // Name of the project or title of the task or subtask
+	Field      string          `json:"field,omitempty"` // Empty when the entity was deleted on one side
+	Base       json.RawMessage `json:"base"`            // null when the entity is missing on that side
+	Ours       json.RawMessage `json:"ours"`
+	Theirs     json.RawMessage `json:"theirs"`
+	Resolution string          `json:"resolution,omitempty"` // Ours or Theirs once resolved
+}
+
+// key identifies the conflict across merges of the same inputs.
+func (c Conflict) key() string {
+	return fmt.Sprintf("%s/%d/%d/%d/%s/%s", c.Entity, c.ProjectID, c.TaskID, c.SubtaskID, c.Key, c.Field)
+}
+
+// State is what termile saves: the projects, views and people.
+type State struct {
+	Projects []task.Project
+	Views    []task.View
+	People   []task.Person
+}
+
+// Result is the outcome of a merge.
+type Result struct {
+	State
+	Conflicts []Conflict
+
+	base, ours, theirs State
+}
+
+// Merge merges the changes made to base in ours and theirs. Projects, tasks
+// and subtasks added on their side get new IDs when ours added others with
+// the same IDs.
+func Merge(base, ours, theirs State) *Result {
+	// The inputs are copied since entities saved before UIDs existed get
+	// theirs, and entities added on their side may be renumbered
+	for _, state := range []*State{&base, &ours, &theirs} {
+		var projects []task.Project
+		if err := json.Unmarshal(mustMarshal(state.Projects), &projects); err != nil {
+			panic(fmt.Sprintf("merge: cannot copy projects: %v", err))
+		}
+		task.AssignUIDs(projects)
+		state.Projects = projects
+	}
+	renumberAdded(base.Projects, ours.Projects, theirs.Projects)
+	r := &Result{base: base, ours: ours, theirs: theirs}
+	r.merge()
+	return r
+}
+
+// Projects merges the changes made to the base projects in ours and theirs,
+// like Merge.
+func Projects(base, ours, theirs []task.Project) *Result {
+	return Merge(State{Projects: base}, State{Projects: ours}, State{Projects: theirs})
+}
+
+// Unresolved returns the number of conflicts not resolved yet.
+func (r *Result) Unresolved() int {
+	count := 0
+	for _, conflict := range r.Conflicts {
+		if conflict.Resolution == "" {
+			count++
+		}
+	}
+	return count
+}
+
+// Resolve resolves the i-th conflict by taking the given side, Ours or
+// Theirs, and merges again.
+func (r *Result) Resolve(i int, side string) error {
+	if i < 0 || i >= len(r.Conflicts) {
+		return fmt.Errorf("no conflict %d", i)
+	}
+	if side != Ours && side != Theirs {
+		return fmt.Errorf("cannot resolve a conflict with %q, expected %s or %s", side, Ours, Theirs)
+	}
+	r.Conflicts[i].Resolution = side
+	r.merge()
+	return nil
+}
+
+// merge merges the inputs again, applying the resolutions of the conflicts.
+func (r *Result) merge() {
+	m := merger{resolutions: map[string]string{}}
+	for _, conflict := range r.Conflicts {
+		if conflict.Resolution != "" {
+			m.resolutions[conflict.key()] = conflict.Resolution
+		}
+	}
+	r.Projects = mergeEntities(r.base.Projects, r.ours.Projects, r.theirs.Projects, func(p task.Project) string { return p.UID }, m.project)
+	if r.Projects == nil {
+		r.Projects = []task.Project{}
+	}
+	r.Views = mergeEntities(r.base.Views, r.ours.Views, r.theirs.Views, func(v task.View) string { return v.Name },
+		func(base, ours, theirs *task.View) *task.View {
+			return mergeKeyed(&m, task.EntityView, func(v task.View) string { return v.Name }, base, ours, theirs)
+		})
+	r.People = mergeEntities(r.base.People, r.ours.People, r.theirs.People, func(p task.Person) string { return p.Handle },
+		func(base, ours, theirs *task.Person) *task.Person {
+			return mergeKeyed(&m, task.EntityPerson, func(p task.Person) string { return p.Handle }, base, ours, theirs)
+		})
+	r.Conflicts = m.conflicts
+}
+
+// merger collects the conflicts of a merge.
+type merger struct {
+	resolutions map[string]string // Sides chosen for conflicts, by key
+	conflicts   []Conflict
+}
+
+// conflict records a conflict and returns the side to take.
+func (m *merger) conflict(c Conflict) string {
+	c.Resolution = m.resolutions[c.key()]
+	m.conflicts = append(m.conflicts, c)
+	if c.Resolution == Theirs {
+		return Theirs
+	}
+	return Ours
+}
+
+// mergeEntities merges lists of entities matched by key, in our order followed
+// by the entities only they have. merge is called with nil for the sides
+// missing an entity and returns nil to leave it out.
+func mergeEntities[T any, K comparable](base, ours, theirs []T, key func(T) K, merge func(base, ours, theirs *T) *T) []T {
+	index := func(items []T) map[K]*T {
+		byKey := make(map[K]*T, len(items))
+		for i := range items {
+			byKey[key(items[i])] = &items[i]
+		}
+		return byKey
+	}
+	baseByKey, oursByKey, theirsByKey := index(base), index(ours), index(theirs)
+
+	var merged []T
+	for i := range ours {
+		if item := merge(baseByKey[key(ours[i])], &ours[i], theirsByKey[key(ours[i])]); item != nil {
+			merged = append(merged, *item)
+		}
+	}
+	for i := range theirs {
+		if oursByKey[key(theirs[i])] != nil {
+			continue
+		}
+		if item := merge(baseByKey[key(theirs[i])], nil, &theirs[i]); item != nil {
+			merged = append(merged, *item)
+		}
+	}
+	return merged
+}
+
+// mergeDeleted merges an entity missing on at least one side. An entity
+// deleted on one side stays deleted unless the other side changed it, which
+// is a conflict.
+func mergeDeleted[T any](m *merger, c Conflict, base, ours, theirs *T) *T {
+	switch {
+	case ours == nil && theirs == nil:
+		return nil
+	case base == nil && ours == nil:
+		return theirs
+	case base == nil:
+		return ours
+	}
+	kept := ours
+	if kept == nil {
+		kept = theirs
+	}
+	if equal(*base, *kept) {
+		return nil
+	}
+	c.Base, c.Ours, c.Theirs = entityJSON(base), entityJSON(ours), entityJSON(theirs)
+	if m.conflict(c) == Theirs {
+		return theirs
+	}
+	return ours
+}
+
+// project merges a project and its tasks.
+func (m *merger) project(base, ours, theirs *task.Project) *task.Project {
+	c := Conflict{Entity: task.EntityProject}
+	for _, p := range []*task.Project{theirs, ours} {
+		if p != nil {
+			c.ProjectID, c.Title = p.ID, p.Name
+		}
+	}
+	if ours == nil || theirs == nil {
+		return mergeDeleted(m, c, base, ours, theirs)
+	}
+	if base == nil {
+		base = &task.Project{}
+	}
+	merged := &task.Project{}
+	m.fields(c, base, ours, theirs, merged, "Tasks")
+	merged.Tasks = mergeEntities(base.Tasks, ours.Tasks, theirs.Tasks, func(t task.Task) string { return t.UID },
+		func(base, ours, theirs *task.Task) *task.Task { return m.task(c.ProjectID, base, ours, theirs) })
+	return merged
+}
+
+// task merges a task and its subtasks.
+func (m *merger) task(projectID int, base, ours, theirs *task.Task) *task.Task {
+	c := Conflict{Entity: task.EntityTask, ProjectID: projectID}
+	for _, t := range []*task.Task{theirs, ours} {
+		if t != nil {
+			c.TaskID, c.Title = t.ID, t.Title
+		}
+	}
+	if ours == nil || theirs == nil {
+		return mergeDeleted(m, c, base, ours, theirs)
+	}
+	if base == nil {
+		base = &task.Task{}
+	}
+	merged := &task.Task{}
+	m.fields(c, base, ours, theirs, merged, "Subtasks")
+	merged.Subtasks = mergeEntities(base.Subtasks, ours.Subtasks, theirs.Subtasks, func(s task.Subtask) string { return s.UID },
+		func(base, ours, theirs *task.Subtask) *task.Subtask {
+			return m.subtask(projectID, c.TaskID, base, ours, theirs)
+		})
+	return merged
+}
+
+// subtask merges a subtask.
+func (m *merger) subtask(projectID, taskID int, base, ours, theirs *task.Subtask) *task.Subtask {
+	c := Conflict{Entity: task.EntitySubtask, ProjectID: projectID, TaskID: taskID}
+	for _, s := range []*task.Subtask{theirs, ours} {
+		if s != nil {
+			c.SubtaskID, c.Title = s.ID, s.Title
+		}
+	}
+	if ours == nil || theirs == nil {
+		return mergeDeleted(m, c, base, ours, theirs)
+	}
+	if base == nil {
+		base = &task.Subtask{}
+	}
+	merged := &task.Subtask{}
+	m.fields(c, base, ours, theirs, merged, "")
+	return merged
+}
+
+// mergeKeyed merges a person or a view.
+func mergeKeyed[T any](m *merger, entity string, key func(T) string, base, ours, theirs *T) *T {
+	c := Conflict{Entity: entity}
+	for _, item := range []*T{theirs, ours} {
+		if item != nil {
+			c.Key, c.Title = key(*item), key(*item)
+		}
+	}
+	if ours == nil || theirs == nil {
+		return mergeDeleted(m, c, base, ours, theirs)
+	}
+	if base == nil {
+		base = new(T)
+	}
+	merged := new(T)
+	m.fields(c, base, ours, theirs, merged, "")
+	return merged
+}
+
+// groups lists the fields merged as one, under the name of the first one:
+// the completion of a task or subtask is its status with the completion time.
+var groups = map[reflect.Type][][]string{
+	reflect.TypeOf(task.Task{}):    {{"Status", "Complete", "CompletedAt"}},
+	reflect.TypeOf(task.Subtask{}): {{"Complete", "CompletedAt"}},
+}
+
+// fields merges the fields of an entity into merged, except the nested
+// entities named by skip. A field changed on one side takes that side, and a
+// field changed to different values on both sides is a conflict. Comments and
+// time entries are lists both sides add to, so they are merged item by item.
+func (m *merger) fields(c Conflict, base, ours, theirs, merged any, skip string) {
+	baseFields, oursFields, theirsFields := fieldMap(base), fieldMap(ours), fieldMap(theirs)
+	mergedFields := map[string]json.RawMessage{}
+
+	typ := reflect.TypeOf(merged).Elem()
+	grouped := map[string][]string{}
+	member := map[string]bool{}
+	for _, group := range groups[typ] {
+		grouped[group[0]] = group
+		for _, name := range group[1:] {
+			member[name] = true
+		}
+	}
+
+	for i := 0; i < typ.NumField(); i++ {
+		name := typ.Field(i).Name
+		if name == skip || member[name] {
+			continue
+		}
+		names := grouped[name]
+		if names == nil {
+			names = []string{name}
+		}
+		value := func(fields map[string]json.RawMessage) json.RawMessage {
+			if len(names) == 1 {
+				return fields[name]
+			}
+			group := map[string]json.RawMessage{}
+			for _, name := range names {
+				group[name] = fields[name]
+			}
+			return mustMarshal(group)
+		}
+		b, o, t := value(baseFields), value(oursFields), value(theirsFields)
+
+		var side map[string]json.RawMessage
+		switch {
+		case bytes.Equal(o, t), bytes.Equal(t, b):
+			side = oursFields
+		case bytes.Equal(o, b):
+			side = theirsFields
+		case name == "Comments":
+			mergedFields[name] = mergeList(b, o, t, commentKey, mergeComments)
+			continue
+		case name == "TimeEntries":
+			mergedFields[name] = mergeList(b, o, t, timeEntryKey, sortTimeEntries)
+			continue
+		default:
+			conflict := c
+			conflict.Field, conflict.Base, conflict.Ours, conflict.Theirs = name, b, o, t
+			side = oursFields
+			if m.conflict(conflict) == Theirs {
+				side = theirsFields
+			}
+		}
+		for _, name := range names {
+			mergedFields[name] = side[name]
+		}
+	}
+
+	if err := json.Unmarshal(mustMarshal(mergedFields), merged); err != nil {
+		panic(fmt.Sprintf("merge: cannot decode %T: %v", merged, err))
+	}
+}
+
+// fieldMap encodes an entity as its fields by name.
+func fieldMap(entity any) map[string]json.RawMessage {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(mustMarshal(entity), &fields); err != nil {
+		panic(fmt.Sprintf("merge: cannot decode %T: %v", entity, err))
+	}
+	return fields
+}
+
+// mustMarshal encodes a value that always encodes.
+func mustMarshal(v any) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("merge: cannot encode %T: %v", v, err))
+	}
+	return data
+}
+
+// equal reports whether two values have the same encoding.
+func equal(a, b any) bool {
+	return bytes.Equal(mustMarshal(a), mustMarshal(b))
+}
+
+// entityJSON encodes an entity without its nested entities, or null when it
+// is missing.
+func entityJSON(entity any) json.RawMessage {
+	switch e := entity.(type) {
+	case *task.Project:
+		if e != nil {
+			p := *e
+			p.Tasks = nil
+			return mustMarshal(p)
+		}
+	case *task.Task:
+		if e != nil {
+			t := *e
+			t.Subtasks = nil
+			return mustMarshal(t)
+		}
+	default:
+		if value := reflect.ValueOf(entity); !value.IsNil() {
+			return mustMarshal(value.Elem().Interface())
+		}
+	}
+	return json.RawMessage("null")
+}
+
+// mergeList merges the items of an encoded list that both sides added to or
+// changed, matched by key: an item keeps the side that changed it, ours when
+// both did, and is dropped when one side deleted it and the other did not
+// change it. finish orders the merged items.
+func mergeList[T any](base, ours, theirs json.RawMessage, key func(T) string, finish func(items, ours []T) []T) json.RawMessage {
+	var baseItems, oursItems, theirsItems []T
+	for _, list := range []struct {
+		data  json.RawMessage
+		items *[]T
+	}{{base, &baseItems}, {ours, &oursItems}, {theirs, &theirsItems}} {
+		if err := json.Unmarshal(list.data, list.items); err != nil {
+			panic(fmt.Sprintf("merge: cannot decode %T: %v", list.items, err))
+		}
+	}
+	index := func(items []T) map[string]T {
+		byKey := make(map[string]T, len(items))
+		for _, item := range items {
+			byKey[key(item)] = item
+		}
+		return byKey
+	}
+	baseByKey, oursByKey, theirsByKey := index(baseItems), index(oursItems), index(theirsItems)
+
+	var merged []T
+	for _, item := range oursItems {
+		baseItem, inBase := baseByKey[key(item)]
+		theirsItem, inTheirs := theirsByKey[key(item)]
+		unchanged := inBase && equal(item, baseItem)
+		switch {
+		case unchanged && !inTheirs:
+			continue
+		case unchanged:
+			item = theirsItem
+		}
+		merged = append(merged, item)
+	}
+	for _, item := range theirsItems {
+		if _, inOurs := oursByKey[key(item)]; inOurs {
+			continue
+		}
+		if baseItem, inBase := baseByKey[key(item)]; inBase && equal(item, baseItem) {
+			continue
+		}
+		merged = append(merged, item)
+	}
+	return mustMarshal(finish(merged, oursItems))
+}
+
+// commentKey identifies a comment, whose ID may be reused on the other side.
+func commentKey(c task.Comment) string {
+	return c.CreatedAt.Format(time.RFC3339Nano) + " " + c.Author
+}
+
+// mergeComments orders comments by time, numbering the comments we did not
+// have after our last one.
+func mergeComments(comments, ours []task.Comment) []task.Comment {
+	lastID := 0
+	numbered := map[string]bool{}
+	for _, comment := range ours {
+		lastID = max(lastID, comment.ID)
+		numbered[commentKey(comment)] = true
+	}
+	for i := range comments {
+		if !numbered[commentKey(comments[i])] {
+			lastID++
+			comments[i].ID = lastID
+		}
+	}
+	slices.SortStableFunc(comments, func(a, b task.Comment) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return comments
+}
+
+// timeEntryKey identifies a time entry by its start.
+func timeEntryKey(e task.TimeEntry) string {
+	return e.Start.Format(time.RFC3339Nano)
+}
+
+// sortTimeEntries orders time entries by start.
+func sortTimeEntries(entries, _ []task.TimeEntry) []task.TimeEntry {
+	slices.SortStableFunc(entries, func(a, b task.TimeEntry) int { return a.Start.Compare(b.Start) })
+	return entries
+}
+
+// renumberAdded gives new IDs to the projects, tasks and subtasks they
+// added when ours used the same IDs for other entities, and makes the tasks
+// depending on renumbered tasks depend on their new IDs.
+func renumberAdded(base, ours, theirs []task.Project) {
+	// IDs are numbered across all projects and tasks
+	taken, inBase := ids(ours), ids(base)
+	next := map[string]int{}
+	for _, projects := range [][]task.Project{base, ours, theirs} {
+		for entity, used := range ids(projects) {
+			for id := range used {
+				next[entity] = max(next[entity], id+1)
+			}
+			for id, uid := range used {
+				if _, ok := taken[entity][id]; !ok {
+					taken[entity][id] = uid
+				}
+			}
+		}
+	}
+	renumber := func(entity string, id *int, uid string) (int, bool) {
+		if owner, ok := taken[entity][*id]; !ok || owner == uid || inBase[entity][*id] == uid {
+			return 0, false
+		}
+		old := *id
+		*id = next[entity]
+		next[entity]++
+		return old, true
+	}
+
+	taskIDs := map[int]int{}
+	for i := range theirs {
+		project := &theirs[i]
+		renumber(task.EntityProject, &project.ID, project.UID)
+		for j := range project.Tasks {
+			t := &project.Tasks[j]
+			if old, ok := renumber(task.EntityTask, &t.ID, t.UID); ok {
+				taskIDs[old] = t.ID
+			}
+			for k := range t.Subtasks {
+				renumber(task.EntitySubtask, &t.Subtasks[k].ID, t.Subtasks[k].UID)
+			}
+		}
+	}
+	for i := range theirs {
+		for j := range theirs[i].Tasks {
+			for k, dependency := range theirs[i].Tasks[j].DependsOn {
+				if id, ok := taskIDs[dependency]; ok {
+					theirs[i].Tasks[j].DependsOn[k] = id
+				}
+			}
+		}
+	}
+}
+
+// ids returns the UIDs of the projects, tasks and subtasks by entity and ID.
+func ids(projects []task.Project) map[string]map[int]string {
+	used := map[string]map[int]string{task.EntityProject: {}, task.EntityTask: {}, task.EntitySubtask: {}}
+	for _, p := range projects {
+		used[task.EntityProject][p.ID] = p.UID
+		for _, t := range p.Tasks {
+			used[task.EntityTask][t.ID] = t.UID
+			for _, s := range t.Subtasks {
+				used[task.EntitySubtask][s.ID] = s.UID
+			}
+		}
+	}
+	return used
+}
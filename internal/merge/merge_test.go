@@ -0,0 +1,255 @@
+package merge
+
+import (
+	"Termile/internal/task"
+	"testing"
+	"time"
+)
+
+// baseProjects is a project with two tasks, the second depending on the
+// first, which has a subtask.
+func baseProjects() []task.Project {
+	created := time.Date(2024, 10, 7, 9, 0, 0, 0, time.UTC)
+	return []task.Project{{
+		ID: 1, UID: "p1", Name: "Website",
+		Tasks: []task.Task{
+			{
+				ID: 1, UID: "t1", Title: "Design", Status: "todo",
+				Subtasks: []task.Subtask{{ID: 1, UID: "s1", Title: "Mockups"}},
+				Comments: []task.Comment{{ID: 1, Author: "sara", Body: "first", CreatedAt: created}},
+			},
+			{ID: 2, UID: "t2", Title: "Build", Status: "todo", DependsOn: []int{1}},
+		},
+	}}
+}
+
+// findTask returns the task of a UID in any project, or nil.
+func findTask(projects []task.Project, uid string) *task.Task {
+	for i := range projects {
+		for j := range projects[i].Tasks {
+			if projects[i].Tasks[j].UID == uid {
+				return &projects[i].Tasks[j]
+			}
+		}
+	}
+	return nil
+}
+
+func TestMerge(t *testing.T) {
+	created := time.Date(2024, 10, 7, 9, 0, 0, 0, time.UTC)
+	done := created.Add(time.Hour)
+	tests := []struct {
+		name      string
+		ours      func(projects []task.Project) []task.Project
+		theirs    func(projects []task.Project) []task.Project
+		conflicts []Conflict // Entity, IDs and Field of the expected conflicts
+		check     func(t *testing.T, merged []task.Project)
+	}{
+		{
+			name: "different fields of a task",
+			ours: func(projects []task.Project) []task.Project {
+				findTask(projects, "t1").Title = "Design the pages"
+				return projects
+			},
+			theirs: func(projects []task.Project) []task.Project {
+				t1 := findTask(projects, "t1")
+				t1.Status, t1.Complete, t1.CompletedAt = "done", true, &done
+				t1.Estimate = 3
+				return projects
+			},
+			check: func(t *testing.T, merged []task.Project) {
+				t1 := findTask(merged, "t1")
+				if t1.Title != "Design the pages" || t1.Status != "done" || !t1.Complete || t1.CompletedAt == nil || t1.Estimate != 3 {
+					t.Errorf("t1 = %+v, want our title with their status and estimate", *t1)
+				}
+			},
+		},
+		{
+			name: "same field changed to the same value",
+			ours: func(projects []task.Project) []task.Project {
+				findTask(projects, "t2").Title = "Build it"
+				return projects
+			},
+			theirs: func(projects []task.Project) []task.Project {
+				findTask(projects, "t2").Title = "Build it"
+				return projects
+			},
+			check: func(t *testing.T, merged []task.Project) {
+				if title := findTask(merged, "t2").Title; title != "Build it" {
+					t.Errorf("t2 title = %q, want %q", title, "Build it")
+				}
+			},
+		},
+		{
+			name: "same field changed to different values",
+			ours: func(projects []task.Project) []task.Project {
+				findTask(projects, "t2").Title = "Build the pages"
+				return projects
+			},
+			theirs: func(projects []task.Project) []task.Project {
+				findTask(projects, "t2").Title = "Build the site"
+				return projects
+			},
+			conflicts: []Conflict{{Entity: task.EntityTask, ProjectID: 1, TaskID: 2, Field: "Title"}},
+			check: func(t *testing.T, merged []task.Project) {
+				if title := findTask(merged, "t2").Title; title != "Build the pages" {
+					t.Errorf("t2 title = %q, want ours until resolved", title)
+				}
+			},
+		},
+		{
+			name: "deleted on their side and modified on ours",
+			ours: func(projects []task.Project) []task.Project {
+				findTask(projects, "t2").Title = "Build it"
+				return projects
+			},
+			theirs: func(projects []task.Project) []task.Project {
+				projects[0].Tasks = projects[0].Tasks[:1]
+				return projects
+			},
+			conflicts: []Conflict{{Entity: task.EntityTask, ProjectID: 1, TaskID: 2}},
+			check: func(t *testing.T, merged []task.Project) {
+				if findTask(merged, "t2") == nil {
+					t.Error("t2 deleted, want our modified task until resolved")
+				}
+			},
+		},
+		{
+			name: "deleted on their side and unchanged on ours",
+			ours: func(projects []task.Project) []task.Project {
+				return projects
+			},
+			theirs: func(projects []task.Project) []task.Project {
+				projects[0].Tasks = projects[0].Tasks[:1]
+				return projects
+			},
+			check: func(t *testing.T, merged []task.Project) {
+				if findTask(merged, "t2") != nil {
+					t.Error("t2 kept, want it deleted")
+				}
+			},
+		},
+		{
+			name: "tasks added with the same IDs",
+			ours: func(projects []task.Project) []task.Project {
+				projects[0].Tasks = append(projects[0].Tasks, task.Task{ID: 3, UID: "ours3", Title: "Deploy"})
+				return projects
+			},
+			theirs: func(projects []task.Project) []task.Project {
+				projects[0].Tasks = append(projects[0].Tasks,
+					task.Task{ID: 3, UID: "theirs3", Title: "Test"},
+					task.Task{ID: 4, UID: "theirs4", Title: "Release", DependsOn: []int{3, 1}})
+				// A subtask numbered like ours in the base
+				findTask(projects, "t2").Subtasks = []task.Subtask{{ID: 1, UID: "theirs-s1", Title: "Pages"}}
+				return projects
+			},
+			check: func(t *testing.T, merged []task.Project) {
+				ours, theirs3, theirs4 := findTask(merged, "ours3"), findTask(merged, "theirs3"), findTask(merged, "theirs4")
+				if ours.ID != 3 || theirs3.ID != 5 || theirs4.ID != 4 {
+					t.Errorf("IDs = %d, %d, %d, want ours kept at 3 and theirs renumbered to 5", ours.ID, theirs3.ID, theirs4.ID)
+				}
+				if deps := theirs4.DependsOn; len(deps) != 2 || deps[0] != 5 || deps[1] != 1 {
+					t.Errorf("theirs4 depends on %v, want [5 1]", deps)
+				}
+				if id := findTask(merged, "t2").Subtasks[0].ID; id != 2 {
+					t.Errorf("their subtask numbered %d, want 2", id)
+				}
+			},
+		},
+		{
+			name: "comments added on both sides",
+			ours: func(projects []task.Project) []task.Project {
+				t1 := findTask(projects, "t1")
+				t1.Comments = append(t1.Comments, task.Comment{ID: 2, Author: "ali", Body: "ours", CreatedAt: created.Add(2 * time.Hour)})
+				return projects
+			},
+			theirs: func(projects []task.Project) []task.Project {
+				t1 := findTask(projects, "t1")
+				t1.Comments[0].Body = "first, edited"
+				t1.Comments = append(t1.Comments, task.Comment{ID: 2, Author: "bo", Body: "theirs", CreatedAt: created.Add(time.Hour)})
+				return projects
+			},
+			check: func(t *testing.T, merged []task.Project) {
+				want := []task.Comment{
+					{ID: 1, Author: "sara", Body: "first, edited", CreatedAt: created},
+					{ID: 3, Author: "bo", Body: "theirs", CreatedAt: created.Add(time.Hour)},
+					{ID: 2, Author: "ali", Body: "ours", CreatedAt: created.Add(2 * time.Hour)},
+				}
+				if got := findTask(merged, "t1").Comments; !equal(got, want) {
+					t.Errorf("comments = %s, want %s", mustMarshal(got), mustMarshal(want))
+				}
+			},
+		},
+		{
+			name: "comment deleted on one side",
+			ours: func(projects []task.Project) []task.Project {
+				findTask(projects, "t1").Comments = nil
+				return projects
+			},
+			theirs: func(projects []task.Project) []task.Project {
+				t1 := findTask(projects, "t1")
+				t1.Comments = append(t1.Comments, task.Comment{ID: 2, Author: "bo", Body: "theirs", CreatedAt: created.Add(time.Hour)})
+				return projects
+			},
+			check: func(t *testing.T, merged []task.Project) {
+				comments := findTask(merged, "t1").Comments
+				if len(comments) != 1 || comments[0].Body != "theirs" || comments[0].ID != 1 {
+					t.Errorf("comments = %s, want only theirs, numbered 1", mustMarshal(comments))
+				}
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := Projects(baseProjects(), test.ours(baseProjects()), test.theirs(baseProjects()))
+			if len(r.Conflicts) != len(test.conflicts) {
+				t.Fatalf("conflicts = %s, want %d", mustMarshal(r.Conflicts), len(test.conflicts))
+			}
+			for i, want := range test.conflicts {
+				got := r.Conflicts[i]
+				if got.Entity != want.Entity || got.ProjectID != want.ProjectID || got.TaskID != want.TaskID || got.Field != want.Field {
+					t.Errorf("conflict %d = %s, want %s", i, mustMarshal(got), mustMarshal(want))
+				}
+			}
+			test.check(t, r.Projects)
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	ours := baseProjects()
+	findTask(ours, "t2").Title = "Build it"
+	theirs := baseProjects()
+	theirs[0].Tasks = theirs[0].Tasks[:1]
+	theirs[0].Name = "Web site"
+
+	r := Projects(baseProjects(), ours, theirs)
+	if r.Unresolved() != 1 {
+		t.Fatalf("%d conflicts unresolved, want 1", r.Unresolved())
+	}
+	if err := r.Resolve(0, Theirs); err != nil {
+		t.Fatal(err)
+	}
+	if r.Unresolved() != 0 || findTask(r.Projects, "t2") != nil {
+		t.Errorf("resolved with theirs: %d unresolved, t2 kept %v", r.Unresolved(), findTask(r.Projects, "t2") != nil)
+	}
+	if r.Projects[0].Name != "Web site" {
+		t.Errorf("project name = %q, want their change kept", r.Projects[0].Name)
+	}
+	if err := r.Resolve(1, Ours); err == nil {
+		t.Error("resolving a missing conflict succeeded")
+	}
+	if err := r.Resolve(0, Base); err == nil {
+		t.Error("resolving with the base succeeded")
+	}
+}
+
+func TestMergeKeepsInputs(t *testing.T) {
+	base, ours, theirs := baseProjects(), baseProjects(), baseProjects()
+	ours[0].Tasks = append(ours[0].Tasks, task.Task{ID: 3, UID: "ours3"})
+	theirs[0].Tasks = append(theirs[0].Tasks, task.Task{ID: 3, UID: "theirs3"})
+	Projects(base, ours, theirs)
+	if theirs[0].Tasks[2].ID != 3 {
+		t.Errorf("their input renumbered to %d", theirs[0].Tasks[2].ID)
+	}
+}
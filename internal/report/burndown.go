@@ -0,0 +1,120 @@
+package report
+
+import (
+	"Termile/internal/task"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// BurndownPoint is the open work of a project at the end of a day.
+type BurndownPoint struct {
+	Day        time.Time `json:"day"`
+	OpenTasks  int       `json:"open_tasks"`
+	OpenPoints float64   `json:"open_estimate"`
+}
+
+// VelocityPoint is the work a project completed during a week.
+type VelocityPoint struct {
+	Project   string    `json:"project"`
+	WeekStart time.Time `json:"week_start"`
+	Completed int       `json:"completed"`
+	Points    float64   `json:"completed_estimate"`
+}
+
+// startOfDay returns midnight at the beginning of t's day.
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// openAt reports whether a task was created and still open at the given time.
+// Tasks saved without a creation time count as existing from the start, and
+// completed tasks without a completion time as already done.
+func openAt(t task.Task, at time.Time) bool {
+	if !t.CreatedAt.IsZero() && !t.CreatedAt.Before(at) {
+		return false
+	}
+	if t.CompletedAt != nil {
+		return !t.CompletedAt.Before(at)
+	}
+	return !t.Complete
+}
+
+// ProjectStart returns the day the first task of a project was created, or
+// the day of now when no task has a creation time.
+func ProjectStart(project task.Project, now time.Time) time.Time {
+	start := startOfDay(now)
+	for _, t := range project.Tasks {
+		if !t.CreatedAt.IsZero() && t.CreatedAt.Before(start) {
+			start = startOfDay(t.CreatedAt)
+		}
+	}
+	return start
+}
+
+// Burndown returns the open tasks and open estimate of a project at the end
+// of each day from the day of from through the day of to.
+func Burndown(project task.Project, from, to time.Time) []BurndownPoint {
+	points := []BurndownPoint{}
+	for day := startOfDay(from); !day.After(to); day = day.AddDate(0, 0, 1) {
+		point := BurndownPoint{Day: day}
+		end := day.AddDate(0, 0, 1)
+		for _, t := range project.Tasks {
+			if openAt(t, end) {
+				point.OpenTasks++
+				point.OpenPoints += t.TotalEstimate()
+			}
+		}
+		points = append(points, point)
+	}
+	return points
+}
+
+// Velocity returns the tasks and estimate a project completed in each of the
+// last weeks weeks, ending with the week containing now.
+func Velocity(project task.Project, weeks int, now time.Time) []VelocityPoint {
+	points := make([]VelocityPoint, weeks)
+	first := task.StartOfWeek(now).AddDate(0, 0, -7*(weeks-1))
+	for i := range points {
+		points[i] = VelocityPoint{Project: project.Name, WeekStart: first.AddDate(0, 0, 7*i)}
+	}
+	for _, t := range project.Tasks {
+		if !t.Complete || t.CompletedAt == nil || t.CompletedAt.Before(first) {
+			continue
+		}
+		week := int(t.CompletedAt.Sub(first).Hours() / (24 * 7))
+		if week < weeks {
+			points[week].Completed++
+			points[week].Points += t.TotalEstimate()
+		}
+	}
+	return points
+}
+
+// WriteBurndown writes burndown points in the given format.
+func WriteBurndown(w io.Writer, format string, points []BurndownPoint) error {
+	table := Table{Header: []string{"day", "open_tasks", "open_estimate"}}
+	for _, point := range points {
+		table.Rows = append(table.Rows, []string{
+			point.Day.Format(task.DateLayout),
+			strconv.Itoa(point.OpenTasks),
+			strconv.FormatFloat(point.OpenPoints, 'f', -1, 64),
+		})
+	}
+	return Write(w, format, table, points)
+}
+
+// WriteVelocity writes velocity points in the given format.
+func WriteVelocity(w io.Writer, format string, points []VelocityPoint) error {
+	table := Table{Header: []string{"project", "week_start", "completed", "completed_estimate"}}
+	for _, point := range points {
+		table.Rows = append(table.Rows, []string{
+			point.Project,
+			point.WeekStart.Format(task.DateLayout),
+			fmt.Sprint(point.Completed),
+			strconv.FormatFloat(point.Points, 'f', -1, 64),
+		})
+	}
+	return Write(w, format, table, points)
+}
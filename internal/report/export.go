@@ -0,0 +1,94 @@
+package report
+
+import (
+	"Termile/internal/task"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// FormatMarkdown is the readable export format accepted by Export.
+const FormatMarkdown = "markdown"
+
+// exportComments joins a comment thread into a single CSV cell.
+func exportComments(comments []task.Comment) string {
+	lines := make([]string, len(comments))
+	for i, comment := range comments {
+		lines[i] = comment.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Export writes projects with their tasks, subtasks and comments as json,
+// csv (one row per task or subtask) or markdown.
+func Export(w io.Writer, format string, projects []task.Project) error {
+	switch format {
+	case FormatMarkdown:
+		return exportMarkdown(w, projects)
+	case FormatCSV, FormatJSON:
+	default:
+		return fmt.Errorf("unknown export format %q, expected json, csv or markdown", format)
+	}
+	table := Table{Header: []string{"project", "task", "subtask", "title", "status", "assigned_to", "due", "description", "comments", "uid"}}
+	for _, project := range projects {
+		for _, t := range project.Tasks {
+			due := ""
+			if t.Due != nil {
+				due = t.Due.Format(task.DateLayout)
+			}
+			table.Rows = append(table.Rows, []string{project.Name, strconv.Itoa(t.ID), "", t.Title, t.Status, t.AssignedTo, due, t.Description, exportComments(t.Comments), t.UID})
+			for _, subtask := range t.Subtasks {
+				status := "open"
+				if subtask.Complete {
+					status = "done"
+				}
+				table.Rows = append(table.Rows, []string{project.Name, strconv.Itoa(t.ID), strconv.Itoa(subtask.ID), subtask.Title, status, subtask.AssignedTo, "", subtask.Description, exportComments(subtask.Comments), subtask.UID})
+			}
+		}
+	}
+	return Write(w, format, table, projects)
+}
+
+// exportMarkdown writes projects as a markdown document with a checklist per
+// project and the comment thread under each task.
+func exportMarkdown(w io.Writer, projects []task.Project) error {
+	var sb strings.Builder
+	writeComments := func(indent string, comments []task.Comment) {
+		for _, comment := range comments {
+			fmt.Fprintf(&sb, "%s> %s\n", indent, comment)
+		}
+	}
+	for _, project := range projects {
+		fmt.Fprintf(&sb, "# %s\n\n", project.Name)
+		if project.Description != "" {
+			fmt.Fprintf(&sb, "%s\n\n", project.Description)
+		}
+		for _, t := range project.Tasks {
+			mark := " "
+			if t.Complete {
+				mark = "x"
+			}
+			fmt.Fprintf(&sb, "- [%s] %s (%s)", mark, t.Title, t.Status)
+			if t.AssignedTo != "" {
+				fmt.Fprintf(&sb, " @%s", strings.Join(t.Assignees(), " @"))
+			}
+			sb.WriteString("\n")
+			if t.Description != "" {
+				fmt.Fprintf(&sb, "  %s\n", t.Description)
+			}
+			writeComments("  ", t.Comments)
+			for _, subtask := range t.Subtasks {
+				mark := " "
+				if subtask.Complete {
+					mark = "x"
+				}
+				fmt.Fprintf(&sb, "  - [%s] %s\n", mark, subtask.Title)
+				writeComments("    ", subtask.Comments)
+			}
+		}
+		sb.WriteString("\n")
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -0,0 +1,228 @@
+package report
+
+import (
+	"Termile/internal/task"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Metrics accepted by Summarize
+const (
+	LeadTime  = "lead"
+	CycleTime = "cycle"
+)
+
+// FlowSample is a completed task with the time it took.
+type FlowSample struct {
+	Project   string
+	Assignees []string
+	TaskID    int
+	Title     string
+	Lead      time.Duration // CreatedAt to CompletedAt
+	Cycle     time.Duration // StartedAt to CompletedAt
+	HasCycle  bool          // false when the task never entered an in-progress status
+}
+
+// FlowSamples collects the completed tasks of projects that have both a
+// creation and a completion time.
+func FlowSamples(projects []task.Project) []FlowSample {
+	samples := []FlowSample{}
+	for _, project := range projects {
+		for _, t := range project.Tasks {
+			if !t.Complete || t.CompletedAt == nil || t.CreatedAt.IsZero() || t.CompletedAt.Before(t.CreatedAt) {
+				continue
+			}
+			sample := FlowSample{
+				Project:   project.Name,
+				Assignees: t.Assignees(),
+				TaskID:    t.ID,
+				Title:     t.Title,
+				Lead:      t.CompletedAt.Sub(t.CreatedAt),
+			}
+			if t.StartedAt != nil && !t.CompletedAt.Before(*t.StartedAt) {
+				sample.Cycle = t.CompletedAt.Sub(*t.StartedAt)
+				sample.HasCycle = true
+			}
+			samples = append(samples, sample)
+		}
+	}
+	return samples
+}
+
+// FlowStats summarises the lead or cycle times of a group of tasks.
+type FlowStats struct {
+	Group string        `json:"group"`
+	Count int           `json:"count"`
+	Mean  time.Duration `json:"mean"`
+	P50   time.Duration `json:"p50"`
+	P85   time.Duration `json:"p85"`
+	P95   time.Duration `json:"p95"`
+	Max   time.Duration `json:"max"`
+}
+
+// Percentile returns the nearest-rank percentile p (0-100) of sorted durations.
+func Percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// Durations returns the lead or cycle times of samples, sorted ascending.
+func Durations(samples []FlowSample, metric string) []time.Duration {
+	durations := []time.Duration{}
+	for _, sample := range samples {
+		switch {
+		case metric == CycleTime && sample.HasCycle:
+			durations = append(durations, sample.Cycle)
+		case metric == LeadTime:
+			durations = append(durations, sample.Lead)
+		}
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	return durations
+}
+
+// Summarize computes flow statistics of samples for the lead or cycle time
+// metric, as a single "all" group or grouped by project or assignee.
+func Summarize(samples []FlowSample, metric string, by string) ([]FlowStats, error) {
+	if metric != LeadTime && metric != CycleTime {
+		return nil, fmt.Errorf("unknown metric %q, expected lead or cycle", metric)
+	}
+	groups := map[string][]FlowSample{}
+	for _, sample := range samples {
+		switch by {
+		case "", "all":
+			groups["all"] = append(groups["all"], sample)
+		case ByProject:
+			groups[sample.Project] = append(groups[sample.Project], sample)
+		case ByAssignee:
+			// A task shared by several people counts for each of them
+			if len(sample.Assignees) == 0 {
+				groups["(unassigned)"] = append(groups["(unassigned)"], sample)
+			}
+			for _, assignee := range sample.Assignees {
+				groups[assignee] = append(groups[assignee], sample)
+			}
+		default:
+			return nil, fmt.Errorf("unknown grouping %q, expected project or assignee", by)
+		}
+	}
+
+	stats := []FlowStats{}
+	for group, groupSamples := range groups {
+		durations := Durations(groupSamples, metric)
+		if len(durations) == 0 {
+			continue
+		}
+		var total time.Duration
+		for _, d := range durations {
+			total += d
+		}
+		stats = append(stats, FlowStats{
+			Group: group,
+			Count: len(durations),
+			Mean:  total / time.Duration(len(durations)),
+			P50:   Percentile(durations, 50),
+			P85:   Percentile(durations, 85),
+			P95:   Percentile(durations, 95),
+			Max:   durations[len(durations)-1],
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return strings.ToLower(stats[i].Group) < strings.ToLower(stats[j].Group)
+	})
+	return stats, nil
+}
+
+// HistogramBucket counts the durations up to an upper bound.
+type HistogramBucket struct {
+	Label string
+	Upper time.Duration // 0 for the last, unbounded bucket
+	Count int
+}
+
+// Histogram counts durations in buckets from under a day to over four weeks.
+func Histogram(durations []time.Duration) []HistogramBucket {
+	day := 24 * time.Hour
+	buckets := []HistogramBucket{
+		{Label: "<1d", Upper: day},
+		{Label: "1-3d", Upper: 3 * day},
+		{Label: "3-7d", Upper: 7 * day},
+		{Label: "1-2w", Upper: 14 * day},
+		{Label: "2-4w", Upper: 28 * day},
+		{Label: ">4w"},
+	}
+	for _, d := range durations {
+		for i := range buckets {
+			if buckets[i].Upper == 0 || d < buckets[i].Upper {
+				buckets[i].Count++
+				break
+			}
+		}
+	}
+	return buckets
+}
+
+// FormatDays formats a duration in days with one decimal, e.g. "2.5d".
+func FormatDays(d time.Duration) string {
+	return fmt.Sprintf("%.1fd", d.Hours()/24)
+}
+
+// WriteFlowStats writes flow statistics in the given format. Durations are
+// written in days in the table and csv formats and in hours in json.
+func WriteFlowStats(w io.Writer, format string, stats []FlowStats) error {
+	table := Table{Header: []string{"GROUP", "COUNT", "MEAN", "P50", "P85", "P95", "MAX"}}
+	if format == FormatCSV {
+		table.Header = []string{"group", "count", "mean_days", "p50_days", "p85_days", "p95_days", "max_days"}
+	}
+	type statsJSON struct {
+		Group     string  `json:"group"`
+		Count     int     `json:"count"`
+		MeanHours float64 `json:"mean_hours"`
+		P50Hours  float64 `json:"p50_hours"`
+		P85Hours  float64 `json:"p85_hours"`
+		P95Hours  float64 `json:"p95_hours"`
+		MaxHours  float64 `json:"max_hours"`
+	}
+	values := []statsJSON{}
+	for _, s := range stats {
+		days := func(d time.Duration) string {
+			if format == FormatCSV {
+				return fmt.Sprintf("%.2f", d.Hours()/24)
+			}
+			return FormatDays(d)
+		}
+		table.Rows = append(table.Rows, []string{s.Group, fmt.Sprint(s.Count), days(s.Mean), days(s.P50), days(s.P85), days(s.P95), days(s.Max)})
+		values = append(values, statsJSON{s.Group, s.Count, roundHours(s.Mean), roundHours(s.P50), roundHours(s.P85), roundHours(s.P95), roundHours(s.Max)})
+	}
+	return Write(w, format, table, values)
+}
+
+// WriteHistogram draws a histogram as text bars.
+func WriteHistogram(w io.Writer, buckets []HistogramBucket) error {
+	largest := 0
+	for _, bucket := range buckets {
+		if bucket.Count > largest {
+			largest = bucket.Count
+		}
+	}
+	for _, bucket := range buckets {
+		bar := ""
+		if largest > 0 && bucket.Count > 0 {
+			bar = strings.Repeat("█", bucket.Count*40/largest) + " "
+		}
+		if _, err := fmt.Fprintf(w, "%-5s %s%d\n", bucket.Label, bar, bucket.Count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
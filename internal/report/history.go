@@ -0,0 +1,56 @@
+package report
+
+import (
+	"Termile/internal/task"
+	"io"
+	"strconv"
+	"time"
+)
+
+// WriteHistory writes recorded changes in the given format.
+func WriteHistory(w io.Writer, format string, changes []task.Change) error {
+	table := Table{Header: []string{"TIME", "ACTOR", "ACTION", "ENTITY", "FIELD", "OLD", "NEW"}}
+	if format == FormatCSV {
+		table.Header = []string{"time", "actor", "action", "entity", "project_id", "task_id", "subtask_id", "key", "field", "old", "new"}
+	}
+	for _, change := range changes {
+		if format == FormatCSV {
+			table.Rows = append(table.Rows, []string{
+				change.Time.Format(time.RFC3339), change.Actor, change.Action, change.Entity,
+				strconv.Itoa(change.ProjectID), strconv.Itoa(change.TaskID), strconv.Itoa(change.SubtaskID),
+				change.Key, change.Field, change.Old, change.New,
+			})
+			continue
+		}
+		table.Rows = append(table.Rows, []string{
+			change.Time.Format("2006-01-02 15:04:05"), change.Actor, change.Action, entityName(change),
+			change.Field, shorten(change.Old), shorten(change.New),
+		})
+	}
+	if changes == nil {
+		changes = []task.Change{}
+	}
+	return Write(w, format, table, changes)
+}
+
+// entityName names the entity of a change, e.g. "task 1/4" or "subtask 1/4/2".
+func entityName(change task.Change) string {
+	switch change.Entity {
+	case task.EntityProject:
+		return "project " + strconv.Itoa(change.ProjectID)
+	case task.EntityTask:
+		return "task " + strconv.Itoa(change.ProjectID) + "/" + strconv.Itoa(change.TaskID)
+	case task.EntitySubtask:
+		return "subtask " + strconv.Itoa(change.ProjectID) + "/" + strconv.Itoa(change.TaskID) + "/" + strconv.Itoa(change.SubtaskID)
+	}
+	return change.Entity + " " + change.Key
+}
+
+// shorten cuts long values so that a table stays readable.
+func shorten(value string) string {
+	const limit = 40
+	if runes := []rune(value); len(runes) > limit {
+		return string(runes[:limit-1]) + "…"
+	}
+	return value
+}
@@ -0,0 +1,62 @@
+package report
+
+import (
+	"Termile/internal/merge"
+	"Termile/internal/task"
+	"encoding/json"
+	"io"
+	"strconv"
+)
+
+// WriteConflicts writes the conflicts of a merge in the given format.
+func WriteConflicts(w io.Writer, format string, conflicts []merge.Conflict) error {
+	table := Table{Header: []string{"ENTITY", "TITLE", "FIELD", "BASE", "OURS", "THEIRS", "RESOLUTION"}}
+	if format == FormatCSV {
+		table.Header = []string{"entity", "project_id", "task_id", "subtask_id", "key", "title", "field", "base", "ours", "theirs", "resolution"}
+	}
+	for _, conflict := range conflicts {
+		if format == FormatCSV {
+			table.Rows = append(table.Rows, []string{
+				conflict.Entity, strconv.Itoa(conflict.ProjectID), strconv.Itoa(conflict.TaskID), strconv.Itoa(conflict.SubtaskID),
+				conflict.Key, conflict.Title, conflict.Field, string(conflict.Base), string(conflict.Ours), string(conflict.Theirs), conflict.Resolution,
+			})
+			continue
+		}
+		field := conflict.Field
+		if field == "" {
+			field = "(deleted)"
+		}
+		resolution := conflict.Resolution
+		if resolution == "" {
+			resolution = "unresolved"
+		}
+		ref := task.Change{Entity: conflict.Entity, ProjectID: conflict.ProjectID, TaskID: conflict.TaskID, SubtaskID: conflict.SubtaskID, Key: conflict.Key}
+		table.Rows = append(table.Rows, []string{
+			entityName(ref), shorten(conflict.Title), field,
+			conflictValue(conflict, conflict.Base), conflictValue(conflict, conflict.Ours), conflictValue(conflict, conflict.Theirs), resolution,
+		})
+	}
+	if conflicts == nil {
+		conflicts = []merge.Conflict{}
+	}
+	return Write(w, format, table, conflicts)
+}
+
+// conflictValue formats one side of a conflict shortly: strings without
+// quotes, and whether the entity is present or deleted on that side when it
+// was deleted on one side.
+func conflictValue(conflict merge.Conflict, value json.RawMessage) string {
+	switch {
+	case conflict.Field == "" && string(value) == "null":
+		return "deleted"
+	case conflict.Field == "":
+		return "present"
+	case string(value) == "null":
+		return "none"
+	}
+	var text string
+	if err := json.Unmarshal(value, &text); err == nil {
+		return shorten(text)
+	}
+	return shorten(string(value))
+}
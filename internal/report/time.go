@@ -0,0 +1,184 @@
+// Package report builds summaries of task data and writes them as tables,
+// CSV or JSON.
+package report
+
+import (
+	"Termile/internal/task"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Groupings accepted by TimeReport
+const (
+	ByProject  = "project"
+	ByAssignee = "assignee"
+	ByDay      = "day"
+)
+
+// Rounding describes how the duration of each time entry is rounded before
+// it is added to a report.
+type Rounding struct {
+	Step time.Duration // 0 disables rounding
+	Mode string        // "nearest", "up" or "down"
+}
+
+// ParseRounding parses a rounding step such as "15m" and a rounding mode.
+func ParseRounding(step string, mode string) (Rounding, error) {
+	r := Rounding{Mode: mode}
+	if step != "" && step != "0" {
+		d, err := time.ParseDuration(step)
+		if err != nil || d < 0 {
+			return Rounding{}, fmt.Errorf("invalid rounding step %q", step)
+		}
+		r.Step = d
+	}
+	switch mode {
+	case "nearest", "up", "down":
+	default:
+		return Rounding{}, fmt.Errorf("unknown rounding mode %q, expected nearest, up or down", mode)
+	}
+	return r, nil
+}
+
+// Apply rounds a duration to the rounding step.
+func (r Rounding) Apply(d time.Duration) time.Duration {
+	if r.Step <= 0 {
+		return d
+	}
+	switch r.Mode {
+	case "up":
+		if rounded := d.Truncate(r.Step); rounded != d {
+			return rounded + r.Step
+		}
+		return d
+	case "down":
+		return d.Truncate(r.Step)
+	}
+	return d.Round(r.Step)
+}
+
+// TimeRow is one group of a time report.
+type TimeRow struct {
+	Group    string
+	Duration time.Duration
+	Entries  int // Entries shared by several assignees count in each of their rows
+}
+
+// TimeReport sums the time entries that started in [from, to), grouped by
+// project, assignee or day. Running entries count up to now. Entries of work
+// assigned to several people are shared evenly between them.
+func TimeReport(projects []task.Project, from, to time.Time, by string, rounding Rounding, now time.Time) ([]TimeRow, error) {
+	if by != ByProject && by != ByAssignee && by != ByDay {
+		return nil, fmt.Errorf("unknown grouping %q, expected project, assignee or day", by)
+	}
+	rows := map[string]*TimeRow{}
+	addTo := func(group string, d time.Duration) {
+		row, ok := rows[group]
+		if !ok {
+			row = &TimeRow{Group: group}
+			rows[group] = row
+		}
+		row.Duration += d
+		row.Entries++
+	}
+	add := func(project task.Project, assignedTo string, entries []task.TimeEntry) {
+		for _, entry := range entries {
+			if entry.Start.Before(from) || !entry.Start.Before(to) {
+				continue
+			}
+			d := rounding.Apply(entry.Duration(now))
+			switch by {
+			case ByAssignee:
+				assignees := task.SplitAssignees(assignedTo)
+				if len(assignees) == 0 {
+					addTo("(unassigned)", d)
+				}
+				for _, assignee := range assignees {
+					addTo(assignee, d/time.Duration(len(assignees)))
+				}
+			case ByDay:
+				addTo(entry.Start.Format(task.DateLayout), d)
+			default:
+				addTo(project.Name, d)
+			}
+		}
+	}
+	for _, project := range projects {
+		for _, t := range project.Tasks {
+			add(project, t.AssignedTo, t.TimeEntries)
+			for _, subtask := range t.Subtasks {
+				assignedTo := subtask.AssignedTo
+				if assignedTo == "" {
+					assignedTo = t.AssignedTo
+				}
+				add(project, assignedTo, subtask.TimeEntries)
+			}
+		}
+	}
+
+	result := make([]TimeRow, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, *row)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return strings.ToLower(result[i].Group) < strings.ToLower(result[j].Group)
+	})
+	return result, nil
+}
+
+// TotalTime sums the durations of report rows.
+func TotalTime(rows []TimeRow) time.Duration {
+	var total time.Duration
+	for _, row := range rows {
+		total += row.Duration
+	}
+	return total
+}
+
+// timeRowJSON is the JSON form of a time report row.
+type timeRowJSON struct {
+	Group   string  `json:"group"`
+	Hours   float64 `json:"hours"`
+	Minutes int     `json:"minutes"`
+	Entries int     `json:"entries"`
+}
+
+// WriteTimeReport writes a time report with a total row in the given format.
+// Grouped by assignee, the total leaves out the number of entries, which
+// would count shared entries several times.
+func WriteTimeReport(w io.Writer, format string, by string, rows []TimeRow) error {
+	table := Table{Header: []string{strings.ToUpper(by), "HOURS", "TIME", "ENTRIES"}}
+	if format == FormatCSV {
+		table.Header = []string{by, "hours", "minutes", "entries"}
+	}
+	values := make([]timeRowJSON, 0, len(rows))
+	entries := 0
+	for _, row := range rows {
+		minutes := int(row.Duration.Round(time.Minute).Minutes())
+		values = append(values, timeRowJSON{Group: row.Group, Hours: roundHours(row.Duration), Minutes: minutes, Entries: row.Entries})
+		if format == FormatCSV {
+			table.Rows = append(table.Rows, []string{row.Group, fmt.Sprintf("%.2f", roundHours(row.Duration)), fmt.Sprint(minutes), fmt.Sprint(row.Entries)})
+		} else {
+			table.Rows = append(table.Rows, []string{row.Group, fmt.Sprintf("%.2f", roundHours(row.Duration)), task.FormatDuration(row.Duration), fmt.Sprint(row.Entries)})
+		}
+		entries += row.Entries
+	}
+	if format == FormatTable {
+		total := TotalTime(rows)
+		totalEntries := fmt.Sprint(entries)
+		if by == ByAssignee {
+			totalEntries = ""
+		}
+		table.Rows = append(table.Rows, []string{"TOTAL", fmt.Sprintf("%.2f", roundHours(total)), task.FormatDuration(total), totalEntries})
+	}
+	return Write(w, format, table, values)
+}
+
+// roundHours converts a duration to hours rounded to two decimals.
+func roundHours(d time.Duration) float64 {
+	return math.Round(d.Hours()*100) / 100
+}
@@ -0,0 +1,41 @@
+package report
+
+import (
+	"Termile/internal/task"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSharedEntriesAreNotCountedTwiceInTheTotal(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Hour)
+	projects := []task.Project{{Name: "Website", Tasks: []task.Task{{
+		AssignedTo:  "ana, bo",
+		TimeEntries: []task.TimeEntry{{Start: start, End: &end}},
+	}}}}
+
+	rows, err := TimeReport(projects, start, start.AddDate(0, 0, 1), ByAssignee, Rounding{}, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].Duration != time.Hour || rows[0].Entries != 1 || rows[1].Duration != time.Hour {
+		t.Fatalf("rows = %+v, want an hour of one entry each for ana and bo", rows)
+	}
+	var out bytes.Buffer
+	if err := WriteTimeReport(&out, FormatTable, ByAssignee, rows); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if total := strings.Fields(lines[len(lines)-1]); len(total) != 3 || total[0] != "TOTAL" || total[2] != "2h00m" {
+		t.Errorf("total row = %q, want 2 hours and no entries", lines[len(lines)-1])
+	}
+
+	rows, _ = TimeReport(projects, start, start.AddDate(0, 0, 1), ByProject, Rounding{}, end)
+	out.Reset()
+	WriteTimeReport(&out, FormatTable, ByProject, rows)
+	if !strings.Contains(out.String(), "TOTAL") || !strings.HasSuffix(strings.TrimSpace(out.String()), " 1") {
+		t.Errorf("report by project = %q, want one entry in the total", out.String())
+	}
+}
@@ -0,0 +1,107 @@
+package report
+
+import (
+	"Termile/internal/task"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Unassigned is the workload group of open tasks nobody is assigned to.
+const Unassigned = "(unassigned)"
+
+// WorkloadRow is the open work of one person across all projects.
+type WorkloadRow struct {
+	Person  string         `json:"person"`
+	Name    string         `json:"name,omitempty"`
+	Open    int            `json:"open_tasks"`
+	Hours   float64        `json:"remaining_hours"`
+	Points  float64        `json:"remaining_points"`
+	Overdue int            `json:"overdue"`
+	Tasks   []task.TaskRef `json:"-"`
+}
+
+// Workload returns the open tasks of every registered person and every other
+// assignee, with their remaining estimates and overdue tasks. The estimate
+// of a task shared by several people is split evenly between them. Tasks
+// without an assignee are grouped under Unassigned.
+func Workload(projects []task.Project, people []task.Person, now time.Time) []WorkloadRow {
+	rows := map[string]*WorkloadRow{}
+	row := func(handle string) *WorkloadRow {
+		key := strings.ToLower(handle)
+		if rows[key] == nil {
+			rows[key] = &WorkloadRow{Person: handle}
+		}
+		return rows[key]
+	}
+	for _, person := range people {
+		row(person.Handle).Name = person.Name
+	}
+
+	for _, project := range projects {
+		for _, t := range project.Tasks {
+			if t.Complete {
+				continue
+			}
+			assignees := t.Assignees()
+			if len(assignees) == 0 {
+				assignees = []string{Unassigned}
+			}
+			share := t.RemainingEstimate() / float64(len(assignees))
+			for _, assignee := range assignees {
+				r := row(assignee)
+				r.Open++
+				if project.Unit() == task.UnitPoints {
+					r.Points += share
+				} else {
+					r.Hours += share
+				}
+				if t.IsOverdue(now) {
+					r.Overdue++
+				}
+				r.Tasks = append(r.Tasks, task.TaskRef{ProjectID: project.ID, ProjectName: project.Name, Task: t})
+			}
+		}
+	}
+
+	result := make([]WorkloadRow, 0, len(rows))
+	for _, r := range rows {
+		r.Hours = math.Round(r.Hours*100) / 100
+		r.Points = math.Round(r.Points*100) / 100
+		result = append(result, *r)
+	}
+	// Busiest people first, the unassigned pile last
+	sort.Slice(result, func(i, j int) bool {
+		if (result[i].Person == Unassigned) != (result[j].Person == Unassigned) {
+			return result[j].Person == Unassigned
+		}
+		if result[i].Open != result[j].Open {
+			return result[i].Open > result[j].Open
+		}
+		return strings.ToLower(result[i].Person) < strings.ToLower(result[j].Person)
+	})
+	return result
+}
+
+// WriteWorkload writes workload rows in the given format.
+func WriteWorkload(w io.Writer, format string, rows []WorkloadRow) error {
+	table := Table{Header: []string{"PERSON", "NAME", "OPEN", "HOURS", "POINTS", "OVERDUE"}}
+	if format == FormatCSV {
+		table.Header = []string{"person", "name", "open_tasks", "remaining_hours", "remaining_points", "overdue"}
+	}
+	for _, row := range rows {
+		table.Rows = append(table.Rows, []string{
+			row.Person,
+			row.Name,
+			strconv.Itoa(row.Open),
+			strconv.FormatFloat(row.Hours, 'f', -1, 64),
+			strconv.FormatFloat(row.Points, 'f', -1, 64),
+			fmt.Sprint(row.Overdue),
+		})
+	}
+	return Write(w, format, table, rows)
+}
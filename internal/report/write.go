@@ -0,0 +1,51 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// Output formats accepted by Write
+const (
+	FormatTable = "table"
+	FormatCSV   = "csv"
+	FormatJSON  = "json"
+)
+
+// Table is the tabular form of a report.
+type Table struct {
+	Header []string
+	Rows   [][]string
+}
+
+// Write writes a report in the given format. The table and csv formats use
+// table, while the json format encodes v.
+func Write(w io.Writer, format string, table Table, v any) error {
+	switch format {
+	case FormatTable:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(table.Header, "\t"))
+		for _, row := range table.Rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(table.Header); err != nil {
+			return err
+		}
+		if err := cw.WriteAll(table.Rows); err != nil {
+			return err
+		}
+		return cw.Error()
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	}
+	return fmt.Errorf("unknown format %q, expected table, csv or json", format)
+}
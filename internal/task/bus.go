@@ -0,0 +1,336 @@
+package task
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// BusEvent is an event published on the Bus of a TaskManager, one of the
+// types below. Every change publishes the created, updated or deleted event
+// of each entity it changed, followed by the events telling what happened to
+// it, e.g. TaskUpdated then TaskCompleted.
+type BusEvent interface {
+	// Name identifies the event for integrations, e.g. "task.completed".
+	Name() string
+}
+
+// Events of projects
+type (
+	ProjectCreated struct{ Project Project }
+	ProjectUpdated struct{ Before, After Project }
+	ProjectDeleted struct{ Project Project }
+)
+
+// Events of tasks. The tasks carry no subtasks, which have events of their
+// own.
+type (
+	TaskCreated struct {
+		ProjectID int
+		Task      Task
+	}
+	TaskUpdated struct {
+		ProjectID     int
+		Before, After Task
+	}
+	TaskDeleted struct {
+		ProjectID int
+		Task      Task
+	}
+	TaskCompleted struct {
+		ProjectID int
+		Task      Task
+	}
+	TaskReopened struct {
+		ProjectID int
+		Task      Task
+	}
+	TaskStatusChanged struct {
+		ProjectID int
+		Task      Task
+		From, To  string
+	}
+	TaskAssigned struct {
+		ProjectID      int
+		Task           Task
+		Added, Removed []string // Handles
+	}
+	TaskCommented struct {
+		ProjectID int
+		Task      Task
+		Comment   Comment
+	}
+)
+
+// Events of subtasks
+type (
+	SubtaskCreated struct {
+		ProjectID, TaskID int
+		Subtask           Subtask
+	}
+	SubtaskUpdated struct {
+		ProjectID, TaskID int
+		Before, After     Subtask
+	}
+	SubtaskDeleted struct {
+		ProjectID, TaskID int
+		Subtask           Subtask
+	}
+	SubtaskCompleted struct {
+		ProjectID, TaskID int
+		Subtask           Subtask
+	}
+	SubtaskReopened struct {
+		ProjectID, TaskID int
+		Subtask           Subtask
+	}
+	SubtaskAssigned struct {
+		ProjectID, TaskID int
+		Subtask           Subtask
+		Added, Removed    []string
+	}
+	SubtaskCommented struct {
+		ProjectID, TaskID int
+		Subtask           Subtask
+		Comment           Comment
+	}
+)
+
+// Events of people and views
+type (
+	PersonAdded   struct{ Person Person }
+	PersonUpdated struct{ Before, After Person }
+	PersonRemoved struct{ Person Person }
+	ViewAdded     struct{ View View }
+	ViewUpdated   struct{ Before, After View }
+	ViewRemoved   struct{ View View }
+)
+
+func (ProjectCreated) Name() string    { return "project.created" }
+func (ProjectUpdated) Name() string    { return "project.updated" }
+func (ProjectDeleted) Name() string    { return "project.deleted" }
+func (TaskCreated) Name() string       { return "task.created" }
+func (TaskUpdated) Name() string       { return "task.updated" }
+func (TaskDeleted) Name() string       { return "task.deleted" }
+func (TaskCompleted) Name() string     { return "task.completed" }
+func (TaskReopened) Name() string      { return "task.reopened" }
+func (TaskStatusChanged) Name() string { return "task.status_changed" }
+func (TaskAssigned) Name() string      { return "task.assigned" }
+func (TaskCommented) Name() string     { return "task.commented" }
+func (SubtaskCreated) Name() string    { return "subtask.created" }
+func (SubtaskUpdated) Name() string    { return "subtask.updated" }
+func (SubtaskDeleted) Name() string    { return "subtask.deleted" }
+func (SubtaskCompleted) Name() string  { return "subtask.completed" }
+func (SubtaskReopened) Name() string   { return "subtask.reopened" }
+func (SubtaskAssigned) Name() string   { return "subtask.assigned" }
+func (SubtaskCommented) Name() string  { return "subtask.commented" }
+func (PersonAdded) Name() string       { return "person.added" }
+func (PersonUpdated) Name() string     { return "person.updated" }
+func (PersonRemoved) Name() string     { return "person.removed" }
+func (ViewAdded) Name() string         { return "view.added" }
+func (ViewUpdated) Name() string       { return "view.updated" }
+func (ViewRemoved) Name() string       { return "view.removed" }
+
+// Bus passes the events of every change to the handlers subscribed to
+// them. Handlers are called in the order they subscribed, after the change,
+// and must not change the TaskManager themselves.
+type Bus struct {
+	handlers []busHandler
+	next     int
+}
+
+// busHandler is a handler of the events of a type, or of every event if
+// eventType is nil.
+type busHandler struct {
+	id        int
+	eventType reflect.Type
+	handle    func(BusEvent)
+}
+
+// On subscribes a handler to the events of type E, e.g.
+//
+//	task.On(tm.Bus(), func(e task.TaskCompleted) { ... })
+//
+// and returns a function unsubscribing it.
+func On[E BusEvent](bus *Bus, handler func(E)) (unsubscribe func()) {
+	return bus.subscribe(reflect.TypeFor[E](), func(e BusEvent) { handler(e.(E)) })
+}
+
+// OnAny subscribes a handler to every event, e.g. to pass them on to
+// another program, and returns a function unsubscribing it.
+func (b *Bus) OnAny(handler func(BusEvent)) (unsubscribe func()) {
+	return b.subscribe(nil, handler)
+}
+
+func (b *Bus) subscribe(eventType reflect.Type, handle func(BusEvent)) func() {
+	b.next++
+	id := b.next
+	b.handlers = append(b.handlers, busHandler{id: id, eventType: eventType, handle: handle})
+	return func() {
+		b.handlers = slices.DeleteFunc(b.handlers, func(h busHandler) bool { return h.id == id })
+	}
+}
+
+// publish passes events to their handlers.
+func (b *Bus) publish(events []BusEvent) {
+	for _, event := range events {
+		eventType := reflect.TypeOf(event)
+		// A handler unsubscribing itself does not skip the next one
+		for _, h := range slices.Clone(b.handlers) {
+			if h.eventType == nil || h.eventType == eventType {
+				h.handle(event)
+			}
+		}
+	}
+}
+
+// Bus returns the bus the TaskManager publishes the events of its changes
+// on.
+func (tm *TaskManager) Bus() *Bus {
+	return tm.bus
+}
+
+// diffBusEvents returns the bus events of the differences between two
+// states.
+func diffBusEvents(before, after snapshot) []BusEvent {
+	var events []BusEvent
+	walkSnapshots(before, after, Change{}, func(ref Change, old, new any) {
+		if old != nil && new != nil && len(diffFields(ref, old, new)) == 0 {
+			return
+		}
+		switch ref.Entity {
+		case EntityProject:
+			events = append(events, projectEvents(old, new)...)
+		case EntityTask:
+			events = append(events, taskEvents(ref.ProjectID, old, new)...)
+		case EntitySubtask:
+			events = append(events, subtaskEvents(ref.ProjectID, ref.TaskID, old, new)...)
+		case EntityPerson:
+			events = append(events, personEvents(old, new)...)
+		case EntityView:
+			events = append(events, viewEvents(old, new)...)
+		}
+	})
+	return events
+}
+
+func projectEvents(old, new any) []BusEvent {
+	switch {
+	case old == nil:
+		p := new.(Project)
+		p.Tasks = nil
+		return []BusEvent{ProjectCreated{p}}
+	case new == nil:
+		p := old.(Project)
+		p.Tasks = nil
+		return []BusEvent{ProjectDeleted{p}}
+	}
+	before, after := old.(Project), new.(Project)
+	before.Tasks, after.Tasks = nil, nil
+	return []BusEvent{ProjectUpdated{before, after}}
+}
+
+func taskEvents(projectID int, old, new any) []BusEvent {
+	switch {
+	case old == nil:
+		t := new.(Task)
+		t.Subtasks = nil
+		return []BusEvent{TaskCreated{projectID, t}}
+	case new == nil:
+		t := old.(Task)
+		t.Subtasks = nil
+		return []BusEvent{TaskDeleted{projectID, t}}
+	}
+	before, after := old.(Task), new.(Task)
+	before.Subtasks, after.Subtasks = nil, nil
+	events := []BusEvent{TaskUpdated{projectID, before, after}}
+	if before.Status != after.Status {
+		events = append(events, TaskStatusChanged{projectID, after, before.Status, after.Status})
+	}
+	if !before.Complete && after.Complete {
+		events = append(events, TaskCompleted{projectID, after})
+	} else if before.Complete && !after.Complete {
+		events = append(events, TaskReopened{projectID, after})
+	}
+	if added, removed := assigneeChanges(before.AssignedTo, after.AssignedTo); len(added)+len(removed) > 0 {
+		events = append(events, TaskAssigned{projectID, after, added, removed})
+	}
+	for _, comment := range newComments(before.Comments, after.Comments) {
+		events = append(events, TaskCommented{projectID, after, comment})
+	}
+	return events
+}
+
+func subtaskEvents(projectID, taskID int, old, new any) []BusEvent {
+	switch {
+	case old == nil:
+		return []BusEvent{SubtaskCreated{projectID, taskID, new.(Subtask)}}
+	case new == nil:
+		return []BusEvent{SubtaskDeleted{projectID, taskID, old.(Subtask)}}
+	}
+	before, after := old.(Subtask), new.(Subtask)
+	events := []BusEvent{SubtaskUpdated{projectID, taskID, before, after}}
+	if !before.Complete && after.Complete {
+		events = append(events, SubtaskCompleted{projectID, taskID, after})
+	} else if before.Complete && !after.Complete {
+		events = append(events, SubtaskReopened{projectID, taskID, after})
+	}
+	if added, removed := assigneeChanges(before.AssignedTo, after.AssignedTo); len(added)+len(removed) > 0 {
+		events = append(events, SubtaskAssigned{projectID, taskID, after, added, removed})
+	}
+	for _, comment := range newComments(before.Comments, after.Comments) {
+		events = append(events, SubtaskCommented{projectID, taskID, after, comment})
+	}
+	return events
+}
+
+func personEvents(old, new any) []BusEvent {
+	switch {
+	case old == nil:
+		return []BusEvent{PersonAdded{new.(Person)}}
+	case new == nil:
+		return []BusEvent{PersonRemoved{old.(Person)}}
+	}
+	return []BusEvent{PersonUpdated{old.(Person), new.(Person)}}
+}
+
+func viewEvents(old, new any) []BusEvent {
+	switch {
+	case old == nil:
+		return []BusEvent{ViewAdded{new.(View)}}
+	case new == nil:
+		return []BusEvent{ViewRemoved{old.(View)}}
+	}
+	return []BusEvent{ViewUpdated{old.(View), new.(View)}}
+}
+
+// assigneeChanges returns the assignees added to and removed from a list.
+func assigneeChanges(before, after string) (added, removed []string) {
+	contains := func(list []string, handle string) bool {
+		return slices.ContainsFunc(list, func(h string) bool { return strings.EqualFold(h, handle) })
+	}
+	old, new := SplitAssignees(before), SplitAssignees(after)
+	for _, handle := range new {
+		if !contains(old, handle) {
+			added = append(added, handle)
+		}
+	}
+	for _, handle := range old {
+		if !contains(new, handle) {
+			removed = append(removed, handle)
+		}
+	}
+	return added, removed
+}
+
+// newComments returns the comments of a thread that were not in its older
+// version.
+func newComments(before, after []Comment) []Comment {
+	var added []Comment
+	for _, comment := range after {
+		if !slices.ContainsFunc(before, func(c Comment) bool { return c.ID == comment.ID }) {
+			added = append(added, comment)
+		}
+	}
+	return added
+}
@@ -0,0 +1,87 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Comment is a timestamped note left on a task or subtask.
+type Comment struct {
+	ID        int
+	Author    string // Handle of the person who wrote it, empty if unknown
+	Body      string
+	CreatedAt time.Time
+}
+
+// String formats a comment as "2024-10-07 14:05 sara: body".
+func (c Comment) String() string {
+	author := c.Author
+	if author == "" {
+		author = "anonymous"
+	}
+	return fmt.Sprintf("%s %s: %s", c.CreatedAt.Format("2006-01-02 15:04"), author, c.Body)
+}
+
+// appendComment adds a comment to a thread, numbering it after the last one.
+func appendComment(comments []Comment, author, body string, now time.Time) ([]Comment, Comment, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return comments, Comment{}, fmt.Errorf("a comment cannot be empty")
+	}
+	comment := Comment{ID: 1, Author: strings.TrimPrefix(strings.TrimSpace(author), "@"), Body: body, CreatedAt: now}
+	if len(comments) > 0 {
+		comment.ID = comments[len(comments)-1].ID + 1
+	}
+	return append(comments, comment), comment, nil
+}
+
+// AddComment adds a comment to a task. An empty author stands for the current user.
+func (tm *TaskManager) AddComment(projectID, taskID int, author, body string, now time.Time) (comment Comment, err error) {
+	defer tm.track(&err, scope{projectID})()
+	if author == "" {
+		author = tm.currentUser
+	}
+	for i := range tm.projects {
+		if tm.projects[i].ID == projectID {
+			for j := range tm.projects[i].Tasks {
+				t := &tm.projects[i].Tasks[j]
+				if t.ID == taskID {
+					comments, comment, err := appendComment(t.Comments, author, body, now)
+					t.Comments = comments
+					return comment, err
+				}
+			}
+			return Comment{}, fmt.Errorf("task %d not found", taskID)
+		}
+	}
+	return Comment{}, fmt.Errorf("project %d not found", projectID)
+}
+
+// AddSubtaskComment adds a comment to a subtask. An empty author stands for
+// the current user.
+func (tm *TaskManager) AddSubtaskComment(projectID, taskID, subtaskID int, author, body string, now time.Time) (comment Comment, err error) {
+	defer tm.track(&err, scope{projectID})()
+	if author == "" {
+		author = tm.currentUser
+	}
+	for i := range tm.projects {
+		if tm.projects[i].ID == projectID {
+			for j := range tm.projects[i].Tasks {
+				if tm.projects[i].Tasks[j].ID == taskID {
+					for k := range tm.projects[i].Tasks[j].Subtasks {
+						subtask := &tm.projects[i].Tasks[j].Subtasks[k]
+						if subtask.ID == subtaskID {
+							comments, comment, err := appendComment(subtask.Comments, author, body, now)
+							subtask.Comments = comments
+							return comment, err
+						}
+					}
+					return Comment{}, fmt.Errorf("subtask %d not found", subtaskID)
+				}
+			}
+			return Comment{}, fmt.Errorf("task %d not found", taskID)
+		}
+	}
+	return Comment{}, fmt.Errorf("project %d not found", projectID)
+}
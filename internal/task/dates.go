@@ -0,0 +1,240 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateLayout is the layout used to read and display dates.
+const DateLayout = "2006-01-02"
+
+// AgendaSection is a titled group of tasks in the agenda.
+type AgendaSection struct {
+	Title string
+	Tasks []TaskRef
+}
+
+// startOfDay returns midnight at the beginning of t's day.
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// StartOfWeek returns midnight on the Monday of the week containing t.
+func StartOfWeek(t time.Time) time.Time {
+	today := startOfDay(t)
+	return today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+}
+
+// ParseDate parses a date such as "2024-10-31", "today", "tomorrow", "+3d",
+// "+2w" or "-1w". An empty string or "none" yields nil, which clears a date.
+func ParseDate(s string, now time.Time) (*time.Time, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	today := startOfDay(now)
+	var date time.Time
+	switch {
+	case s == "" || s == "none":
+		return nil, nil
+	case s == "today":
+		date = today
+	case s == "tomorrow":
+		date = today.AddDate(0, 0, 1)
+	case (strings.HasPrefix(s, "+") || strings.HasPrefix(s, "-")) && len(s) > 2:
+		n, err := strconv.Atoi(s[:len(s)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q", s)
+		}
+		switch s[len(s)-1] {
+		case 'd':
+			date = today.AddDate(0, 0, n)
+		case 'w':
+			date = today.AddDate(0, 0, 7*n)
+		default:
+			return nil, fmt.Errorf("invalid date %q", s)
+		}
+	default:
+		parsed, err := time.ParseInLocation(DateLayout, s, now.Location())
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", s)
+		}
+		date = parsed
+	}
+	return &date, nil
+}
+
+// IsOverdue reports whether an open task's due date is before today.
+func (t Task) IsOverdue(now time.Time) bool {
+	return !t.Complete && t.Due != nil && t.Due.Before(startOfDay(now))
+}
+
+// SetTaskDue sets or clears the due date of a task.
+func (tm *TaskManager) SetTaskDue(projectID, taskID int, due *time.Time) (err error) {
+	defer tm.track(&err, scope{projectID})()
+	for i := range tm.projects {
+		if tm.projects[i].ID == projectID {
+			for j := range tm.projects[i].Tasks {
+				if tm.projects[i].Tasks[j].ID == taskID {
+					tm.projects[i].Tasks[j].Due = due
+					return nil
+				}
+			}
+			return fmt.Errorf("task %d not found", taskID)
+		}
+	}
+	return fmt.Errorf("project %d not found", projectID)
+}
+
+// TasksDue returns the tasks of every project due in [from, to).
+func (tm *TaskManager) TasksDue(from, to time.Time) []TaskRef {
+	var refs []TaskRef
+	for _, project := range tm.projects {
+		for _, t := range project.Tasks {
+			if t.Due != nil && !t.Due.Before(from) && t.Due.Before(to) {
+				refs = append(refs, TaskRef{ProjectID: project.ID, ProjectName: project.Name, Task: t})
+			}
+		}
+	}
+	return refs
+}
+
+// Agenda groups the open tasks of every project into overdue, today,
+// tomorrow and the following seven days.
+func (tm *TaskManager) Agenda(now time.Time) []AgendaSection {
+	today := startOfDay(now)
+	sections := []AgendaSection{
+		{Title: "Overdue"},
+		{Title: "Today"},
+		{Title: "Tomorrow"},
+		{Title: "Next 7 days"},
+	}
+	bounds := []time.Time{
+		{},
+		today,
+		today.AddDate(0, 0, 1),
+		today.AddDate(0, 0, 2),
+		today.AddDate(0, 0, 9),
+	}
+	for _, project := range tm.projects {
+		for _, t := range project.Tasks {
+			if t.Complete || t.Due == nil {
+				continue
+			}
+			for i := range sections {
+				if !t.Due.Before(bounds[i]) && t.Due.Before(bounds[i+1]) {
+					sections[i].Tasks = append(sections[i].Tasks, TaskRef{ProjectID: project.ID, ProjectName: project.Name, Task: t})
+					break
+				}
+			}
+		}
+	}
+	for _, section := range sections {
+		sort.SliceStable(section.Tasks, func(i, j int) bool {
+			return section.Tasks[i].Task.Due.Before(*section.Tasks[j].Task.Due)
+		})
+	}
+	return sections
+}
+
+// Span returns the period a task covers on a timeline: from its start date to
+// its due date, falling back to CreatedAt and CompletedAt for finished work.
+// ok is false when the task has no dates to draw.
+func (t Task) Span() (start, end time.Time, ok bool) {
+	if t.Start != nil {
+		start = *t.Start
+	} else if !t.CreatedAt.IsZero() {
+		start = startOfDay(t.CreatedAt)
+	}
+	switch {
+	case t.Due != nil:
+		end = *t.Due
+	case t.Complete && t.CompletedAt != nil:
+		end = startOfDay(*t.CompletedAt)
+	}
+	if start.IsZero() && end.IsZero() {
+		return time.Time{}, time.Time{}, false
+	}
+	if start.IsZero() {
+		start = end
+	}
+	if end.IsZero() {
+		end = start
+	}
+	// An explicit start date wins over a due date before it
+	if end.Before(start) {
+		if t.Start != nil {
+			end = start
+		} else {
+			start = end
+		}
+	}
+	return start, end, true
+}
+
+// SetTaskStart sets or clears the start date of a task.
+func (tm *TaskManager) SetTaskStart(projectID, taskID int, start *time.Time) (err error) {
+	defer tm.track(&err, scope{projectID})()
+	for i := range tm.projects {
+		if tm.projects[i].ID == projectID {
+			for j := range tm.projects[i].Tasks {
+				if tm.projects[i].Tasks[j].ID == taskID {
+					tm.projects[i].Tasks[j].Start = start
+					return nil
+				}
+			}
+			return fmt.Errorf("task %d not found", taskID)
+		}
+	}
+	return fmt.Errorf("project %d not found", projectID)
+}
+
+// SetTaskDependencies sets the tasks of the same project that a task depends on.
+func (tm *TaskManager) SetTaskDependencies(projectID, taskID int, dependsOn []int) (err error) {
+	defer tm.track(&err, scope{projectID})()
+	for i := range tm.projects {
+		if tm.projects[i].ID != projectID {
+			continue
+		}
+		tasks := tm.projects[i].Tasks
+		graph := map[int][]int{}
+		index := -1
+		for j, t := range tasks {
+			graph[t.ID] = t.DependsOn
+			if t.ID == taskID {
+				index = j
+			}
+		}
+		if index == -1 {
+			return fmt.Errorf("task %d not found", taskID)
+		}
+		for _, id := range dependsOn {
+			if _, ok := graph[id]; !ok {
+				return fmt.Errorf("task %d not found in project", id)
+			}
+		}
+		graph[taskID] = dependsOn
+		if dependsOnTask(graph, taskID, taskID, map[int]bool{}) {
+			return fmt.Errorf("dependencies of task %d would form a cycle", taskID)
+		}
+		tm.projects[i].Tasks[index].DependsOn = dependsOn
+		return nil
+	}
+	return fmt.Errorf("project %d not found", projectID)
+}
+
+// dependsOnTask reports whether from transitively depends on target.
+func dependsOnTask(graph map[int][]int, from, target int, visited map[int]bool) bool {
+	for _, id := range graph[from] {
+		if id == target {
+			return true
+		}
+		if !visited[id] {
+			visited[id] = true
+			if dependsOnTask(graph, id, target, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,246 @@
+package task
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Units in which a project sizes its tasks
+const (
+	UnitHours  = "hours"
+	UnitPoints = "points"
+)
+
+// Unit returns the estimate unit of a project, hours unless set to points.
+func (p Project) Unit() string {
+	if p.EstimateUnit == UnitPoints {
+		return UnitPoints
+	}
+	return UnitHours
+}
+
+// hasSubtaskEstimates reports whether any subtask of a task is estimated.
+func (t Task) hasSubtaskEstimates() bool {
+	for _, subtask := range t.Subtasks {
+		if subtask.Estimate > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// TotalEstimate returns the estimate of a task, which is the sum of its
+// subtask estimates when any subtask is estimated.
+func (t Task) TotalEstimate() float64 {
+	if !t.hasSubtaskEstimates() {
+		return t.Estimate
+	}
+	total := 0.0
+	for _, subtask := range t.Subtasks {
+		total += subtask.Estimate
+	}
+	return total
+}
+
+// RemainingEstimate returns the estimate of the work left on a task: nothing
+// once it is complete, otherwise the estimates of its open subtasks when
+// subtasks are estimated.
+func (t Task) RemainingEstimate() float64 {
+	if t.Complete {
+		return 0
+	}
+	if !t.hasSubtaskEstimates() {
+		return t.Estimate
+	}
+	remaining := 0.0
+	for _, subtask := range t.Subtasks {
+		if !subtask.Complete {
+			remaining += subtask.Estimate
+		}
+	}
+	return remaining
+}
+
+// ParseEstimate parses an estimate such as "3", "2.5" or "4h". An empty
+// string clears the estimate.
+func ParseEstimate(s string) (float64, error) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	s = strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(s, "pts"), "h"), "p")
+	if s == "" {
+		return 0, nil
+	}
+	estimate, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || checkEstimate(estimate) != nil {
+		return 0, fmt.Errorf("invalid estimate %q", s)
+	}
+	return estimate, nil
+}
+
+// checkEstimate returns an error if an estimate is negative, infinite or
+// not a number.
+func checkEstimate(estimate float64) error {
+	if estimate < 0 || math.IsNaN(estimate) || math.IsInf(estimate, 0) {
+		return fmt.Errorf("invalid estimate %v", estimate)
+	}
+	return nil
+}
+
+// FormatEstimate formats an estimate in the given unit, e.g. "4.5h" or "3 pts".
+func FormatEstimate(estimate float64, unit string) string {
+	value := strconv.FormatFloat(estimate, 'f', -1, 64)
+	if unit == UnitPoints {
+		return value + " pts"
+	}
+	return value + "h"
+}
+
+// EstimateComparison compares the estimate of a task with the time logged on it.
+type EstimateComparison struct {
+	Unit      string
+	Estimate  float64
+	Remaining float64
+	Actual    time.Duration
+}
+
+// String describes the comparison, e.g. "Estimate 4h · Remaining 1h · Actual 5h00m (+25%)".
+func (c EstimateComparison) String() string {
+	text := fmt.Sprintf("Estimate %s · Remaining %s", FormatEstimate(c.Estimate, c.Unit), FormatEstimate(c.Remaining, c.Unit))
+	if c.Actual == 0 {
+		return text
+	}
+	text += " · Actual " + FormatDuration(c.Actual)
+	switch {
+	case c.Estimate == 0:
+	case c.Unit == UnitHours:
+		text += fmt.Sprintf(" (%+.0f%%)", (c.Actual.Hours()/c.Estimate-1)*100)
+	default:
+		text += fmt.Sprintf(" (%s/pt)", FormatDuration(time.Duration(float64(c.Actual)/c.Estimate)))
+	}
+	return text
+}
+
+// CompareEstimate compares the estimate of a task with its logged time.
+func (tm *TaskManager) CompareEstimate(projectID, taskID int, now time.Time) (EstimateComparison, error) {
+	for _, project := range tm.projects {
+		if project.ID != projectID {
+			continue
+		}
+		for _, t := range project.Tasks {
+			if t.ID == taskID {
+				return EstimateComparison{
+					Unit:      project.Unit(),
+					Estimate:  t.TotalEstimate(),
+					Remaining: t.RemainingEstimate(),
+					Actual:    t.TrackedTime(now),
+				}, nil
+			}
+		}
+		return EstimateComparison{}, fmt.Errorf("task %d not found", taskID)
+	}
+	return EstimateComparison{}, fmt.Errorf("project %d not found", projectID)
+}
+
+// CompareSubtaskEstimate compares the estimate of a subtask with its logged time.
+func (tm *TaskManager) CompareSubtaskEstimate(projectID, taskID, subtaskID int, now time.Time) (EstimateComparison, error) {
+	for _, project := range tm.projects {
+		if project.ID != projectID {
+			continue
+		}
+		for _, subtask := range tm.ListSubtasks(projectID, taskID) {
+			if subtask.ID == subtaskID {
+				comparison := EstimateComparison{
+					Unit:      project.Unit(),
+					Estimate:  subtask.Estimate,
+					Remaining: subtask.Estimate,
+					Actual:    subtask.TrackedTime(now),
+				}
+				if subtask.Complete {
+					comparison.Remaining = 0
+				}
+				return comparison, nil
+			}
+		}
+		return EstimateComparison{}, fmt.Errorf("subtask %d not found", subtaskID)
+	}
+	return EstimateComparison{}, fmt.Errorf("project %d not found", projectID)
+}
+
+// ProjectEstimate compares the estimates of all tasks of a project with their logged time.
+func (tm *TaskManager) ProjectEstimate(projectID int, now time.Time) EstimateComparison {
+	comparison := EstimateComparison{Unit: UnitHours}
+	for _, project := range tm.projects {
+		if project.ID != projectID {
+			continue
+		}
+		comparison.Unit = project.Unit()
+		for _, t := range project.Tasks {
+			comparison.Estimate += t.TotalEstimate()
+			comparison.Remaining += t.RemainingEstimate()
+			comparison.Actual += t.TrackedTime(now)
+		}
+	}
+	return comparison
+}
+
+// SetEstimateUnit sets whether a project estimates in hours or points.
+func (tm *TaskManager) SetEstimateUnit(projectID int, unit string) (err error) {
+	defer tm.track(&err, scope{projectID})()
+	if unit != UnitHours && unit != UnitPoints {
+		return fmt.Errorf("unknown estimate unit %q, expected hours or points", unit)
+	}
+	for i := range tm.projects {
+		if tm.projects[i].ID == projectID {
+			tm.projects[i].EstimateUnit = unit
+			return nil
+		}
+	}
+	return fmt.Errorf("project %d not found", projectID)
+}
+
+// SetTaskEstimate sets the estimate of a task.
+func (tm *TaskManager) SetTaskEstimate(projectID, taskID int, estimate float64) (err error) {
+	defer tm.track(&err, scope{projectID})()
+	if err := checkEstimate(estimate); err != nil {
+		return err
+	}
+	for i := range tm.projects {
+		if tm.projects[i].ID == projectID {
+			for j := range tm.projects[i].Tasks {
+				if tm.projects[i].Tasks[j].ID == taskID {
+					tm.projects[i].Tasks[j].Estimate = estimate
+					return nil
+				}
+			}
+			return fmt.Errorf("task %d not found", taskID)
+		}
+	}
+	return fmt.Errorf("project %d not found", projectID)
+}
+
+// SetSubtaskEstimate sets the estimate of a subtask.
+func (tm *TaskManager) SetSubtaskEstimate(projectID, taskID, subtaskID int, estimate float64) (err error) {
+	defer tm.track(&err, scope{projectID})()
+	if err := checkEstimate(estimate); err != nil {
+		return err
+	}
+	for i := range tm.projects {
+		if tm.projects[i].ID == projectID {
+			for j := range tm.projects[i].Tasks {
+				if tm.projects[i].Tasks[j].ID == taskID {
+					for k := range tm.projects[i].Tasks[j].Subtasks {
+						if tm.projects[i].Tasks[j].Subtasks[k].ID == subtaskID {
+							tm.projects[i].Tasks[j].Subtasks[k].Estimate = estimate
+							return nil
+						}
+					}
+					return fmt.Errorf("subtask %d not found", subtaskID)
+				}
+			}
+			return fmt.Errorf("task %d not found", taskID)
+		}
+	}
+	return fmt.Errorf("project %d not found", projectID)
+}
@@ -0,0 +1,40 @@
+package task
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseEstimate(t *testing.T) {
+	valid := map[string]float64{"": 0, "3": 3, "2.5": 2.5, "4h": 4, "5 pts": 5}
+	for s, want := range valid {
+		if got, err := ParseEstimate(s); err != nil || got != want {
+			t.Errorf("ParseEstimate(%q) = %v, %v, want %v", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"-1", "NaN", "inf", "+Inf", "-infinity", "1e400", "soon"} {
+		if got, err := ParseEstimate(s); err == nil {
+			t.Errorf("ParseEstimate(%q) = %v, want an error", s, got)
+		}
+	}
+}
+
+func TestSetEstimateRejectsInvalidValues(t *testing.T) {
+	tm, ops := newTestManager()
+	tm.AddSubtask(1, 1, Subtask{Title: "Mockups"})
+	*ops = nil
+	for _, estimate := range []float64{-1, math.NaN(), math.Inf(1)} {
+		if err := tm.SetTaskEstimate(1, 1, estimate); err == nil {
+			t.Errorf("SetTaskEstimate(%v) succeeded", estimate)
+		}
+		if err := tm.SetSubtaskEstimate(1, 1, 1, estimate); err == nil {
+			t.Errorf("SetSubtaskEstimate(%v) succeeded", estimate)
+		}
+	}
+	if len(*ops) != 0 {
+		t.Errorf("ops = %+v, want nothing recorded", *ops)
+	}
+	if err := tm.SetTaskEstimate(1, 1, 2.5); err != nil || tm.ListTasks(1)[0].Estimate != 2.5 {
+		t.Errorf("SetTaskEstimate(2.5) = %v, estimate %v", err, tm.ListTasks(1)[0].Estimate)
+	}
+}
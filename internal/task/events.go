@@ -0,0 +1,111 @@
+package task
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Types of Event
+const (
+	EventCreated = "created"
+	EventUpdated = "updated"
+	EventDeleted = "deleted"
+)
+
+// Event reports a project, task, subtask, person or view created, updated or
+// deleted, by a mutation or by reloading files another process changed.
+type Event struct {
+	Revision  int             `json:"revision"` // Revision after the change, shared by its events
+	Time      time.Time       `json:"time"`
+	Type      string          `json:"type"`
+	Entity    string          `json:"entity"`
+	ProjectID int             `json:"project_id,omitempty"`
+	TaskID    int             `json:"task_id,omitempty"`
+	SubtaskID int             `json:"subtask_id,omitempty"`
+	Key       string          `json:"key,omitempty"` // Handle of a person or name of a view
+	Data      json.RawMessage `json:"data"`          // The entity after the change, or before its deletion, as in an Op
+}
+
+// Subscriber is called with the events of each change.
+type Subscriber func(events []Event)
+
+// Subscribe registers a subscriber called after every change, and returns a
+// function unregistering it.
+func (tm *TaskManager) Subscribe(subscriber Subscriber) (unsubscribe func()) {
+	if tm.subscribers == nil {
+		tm.subscribers = map[int]Subscriber{}
+	}
+	tm.nextSubscriber++
+	id := tm.nextSubscriber
+	tm.subscribers[id] = subscriber
+	return func() { delete(tm.subscribers, id) }
+}
+
+// Reload runs fn, which replaces the state without recording a change, e.g.
+// with files another process changed, and publishes what it changed to the
+// subscribers and on the bus.
+func (tm *TaskManager) Reload(fn func() error) error {
+	if len(tm.subscribers) == 0 && len(tm.bus.handlers) == 0 {
+		return fn()
+	}
+	before := tm.takeSnapshot(allProjects)
+	err := fn()
+	after := tm.takeSnapshot(allProjects)
+	now := time.Now()
+	if ops, diffErr := diffOps(before, after, now); len(ops) > 0 || diffErr != nil {
+		saved := !tm.Dirty()
+		tm.revision++
+		if saved {
+			tm.MarkSaved()
+		}
+		tm.publish(before, after, now)
+	}
+	return err
+}
+
+// publish passes what changed between two states to the subscribers, with
+// the current revision, and on the bus.
+func (tm *TaskManager) publish(before, after snapshot, now time.Time) {
+	if len(tm.subscribers) > 0 {
+		events := diffEvents(before, after, now)
+		for i := range events {
+			events[i].Revision = tm.revision
+		}
+		for _, subscriber := range tm.subscribers {
+			if len(events) > 0 {
+				subscriber(events)
+			}
+		}
+	}
+	if len(tm.bus.handlers) > 0 {
+		tm.bus.publish(diffBusEvents(before, after))
+	}
+}
+
+// diffEvents returns the events of the entities that differ between two
+// states.
+func diffEvents(before, after snapshot, now time.Time) []Event {
+	var events []Event
+	walkSnapshots(before, after, Change{}, func(ref Change, old, new any) {
+		event := Event{Time: now, Entity: ref.Entity, ProjectID: ref.ProjectID, TaskID: ref.TaskID, SubtaskID: ref.SubtaskID, Key: ref.Key}
+		var err error
+		switch {
+		case old == nil:
+			event.Type = EventCreated
+			event.Data, err = opValue(new)
+		case new == nil:
+			event.Type = EventDeleted
+			event.Data, err = opValue(old)
+		case len(diffFields(ref, old, new)) > 0:
+			event.Type = EventUpdated
+			event.Data, err = opValue(new)
+		default:
+			return
+		}
+		// An entity that cannot be encoded has no event to send
+		if err == nil {
+			events = append(events, event)
+		}
+	})
+	return events
+}
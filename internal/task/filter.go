@@ -0,0 +1,240 @@
+package task
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filter selects tasks across all projects.
+type Filter struct {
+	Words          []string
+	Assignee       string
+	Project        string
+	Status         string
+	Complete       *bool
+	CreatedSince   time.Time
+	CompletedSince time.Time
+	Due            string
+	now            time.Time
+}
+
+// TaskRef identifies a task together with the project it belongs to.
+type TaskRef struct {
+	ProjectID   int
+	ProjectName string
+	Task        Task
+}
+
+// View is a named, saved filter query.
+type View struct {
+	Name  string
+	Query string
+}
+
+// DefaultViews returns the views offered when none have been saved yet.
+func DefaultViews() []View {
+	return []View{
+		{Name: "Open", Query: "is:open"},
+		{Name: "Overdue", Query: "due:overdue"},
+		{Name: "Due this week", Query: "is:open due:week"},
+		{Name: "Completed this week", Query: "is:done completed:week"},
+	}
+}
+
+// ParseFilter parses a filter query such as "is:open @sara project:web login".
+// Relative periods (today, week, month, Nd) are resolved against now.
+func ParseFilter(query string, now time.Time) (Filter, error) {
+	f := Filter{now: now}
+	for _, word := range strings.Fields(query) {
+		if strings.HasPrefix(word, "@") && len(word) > 1 {
+			f.Assignee = word[1:]
+			continue
+		}
+		key, value, found := strings.Cut(word, ":")
+		if !found || value == "" {
+			f.Words = append(f.Words, strings.ToLower(word))
+			continue
+		}
+		switch strings.ToLower(key) {
+		case "is":
+			switch strings.ToLower(value) {
+			case "open":
+				complete := false
+				f.Complete = &complete
+			case "done", "complete":
+				complete := true
+				f.Complete = &complete
+			default:
+				return Filter{}, fmt.Errorf("unknown status %q", value)
+			}
+		case "assignee":
+			f.Assignee = value
+		case "project":
+			f.Project = value
+		case "status":
+			f.Status = value
+		case "created":
+			since, err := periodStart(value, now)
+			if err != nil {
+				return Filter{}, err
+			}
+			f.CreatedSince = since
+		case "due":
+			switch strings.ToLower(value) {
+			case "overdue", "today", "tomorrow", "week", "none", "any":
+				f.Due = strings.ToLower(value)
+			default:
+				return Filter{}, fmt.Errorf("unknown due period %q", value)
+			}
+		case "completed":
+			since, err := periodStart(value, now)
+			if err != nil {
+				return Filter{}, err
+			}
+			f.CompletedSince = since
+		default:
+			return Filter{}, fmt.Errorf("unknown filter key %q", key)
+		}
+	}
+	return f, nil
+}
+
+// periodStart returns the beginning of a relative period ending at now.
+func periodStart(period string, now time.Time) (time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch strings.ToLower(period) {
+	case "today":
+		return today, nil
+	case "week":
+		return StartOfWeek(now), nil
+	case "month":
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()), nil
+	}
+	if strings.HasSuffix(period, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(period, "d"))
+		if err == nil && days >= 0 {
+			return today.AddDate(0, 0, -days), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unknown period %q", period)
+}
+
+// Match reports whether a task of the given project satisfies the filter.
+func (f Filter) Match(project Project, t Task) bool {
+	if f.Complete != nil && t.Complete != *f.Complete {
+		return false
+	}
+	if f.Assignee != "" && !hasAssignee(t.AssignedTo, f.Assignee) {
+		return false
+	}
+	if f.Status != "" && !strings.EqualFold(t.Status, f.Status) {
+		return false
+	}
+	if f.Project != "" && !strings.Contains(strings.ToLower(project.Name), strings.ToLower(f.Project)) {
+		return false
+	}
+	if !f.CreatedSince.IsZero() && t.CreatedAt.Before(f.CreatedSince) {
+		return false
+	}
+	if !f.CompletedSince.IsZero() && (t.CompletedAt == nil || t.CompletedAt.Before(f.CompletedSince)) {
+		return false
+	}
+	if f.Due != "" && !f.matchDue(t) {
+		return false
+	}
+	text := strings.ToLower(t.Title + " " + t.Description)
+	for _, word := range f.Words {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchDue reports whether a task's due date falls in the filter's due period,
+// evaluated against the time the query was parsed.
+func (f Filter) matchDue(t Task) bool {
+	if f.Due == "none" {
+		return t.Due == nil
+	}
+	if t.Due == nil {
+		return false
+	}
+	today := startOfDay(f.now)
+	switch f.Due {
+	case "overdue":
+		return t.IsOverdue(f.now)
+	case "today":
+		return !t.Due.Before(today) && t.Due.Before(today.AddDate(0, 0, 1))
+	case "tomorrow":
+		return !t.Due.Before(today.AddDate(0, 0, 1)) && t.Due.Before(today.AddDate(0, 0, 2))
+	case "week":
+		return !t.Due.Before(today) && t.Due.Before(today.AddDate(0, 0, 7))
+	}
+	return true
+}
+
+// FindTasks returns the tasks of every project that match the filter. The
+// assignee "me" stands for the current user.
+func (tm *TaskManager) FindTasks(f Filter) []TaskRef {
+	if strings.EqualFold(f.Assignee, "me") && tm.currentUser != "" {
+		f.Assignee = tm.currentUser
+	}
+	var refs []TaskRef
+	for _, project := range tm.projects {
+		for _, t := range project.Tasks {
+			if f.Match(project, t) {
+				refs = append(refs, TaskRef{ProjectID: project.ID, ProjectName: project.Name, Task: t})
+			}
+		}
+	}
+	return refs
+}
+
+// AddView saves a new named view. Views are told apart by name, e.g. when
+// they are replicated, so the name must not be empty or taken.
+func (tm *TaskManager) AddView(view View) (err error) {
+	defer tm.track(&err, noProjects)()
+	view.Name = strings.TrimSpace(view.Name)
+	if err := tm.ValidateViewName(view.Name); err != nil {
+		return err
+	}
+	tm.views = append(tm.views, view)
+	return nil
+}
+
+// ValidateViewName returns an error if a name cannot be given to a new view,
+// e.g. to ask for another one before asking for the query.
+func (tm *TaskManager) ValidateViewName(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("a view needs a name")
+	}
+	for _, view := range tm.views {
+		if view.Name == name {
+			return fmt.Errorf("a view named %q already exists", name)
+		}
+	}
+	return nil
+}
+
+// RemoveView removes the view at the given index.
+func (tm *TaskManager) RemoveView(index int) {
+	defer tm.track(nil, noProjects)()
+	if index < 0 || index >= len(tm.views) {
+		return
+	}
+	tm.views = append(tm.views[:index], tm.views[index+1:]...)
+}
+
+// ListViews returns the saved views.
+func (tm *TaskManager) ListViews() []View {
+	return tm.views
+}
+
+// SetViews replaces the saved views.
+func (tm *TaskManager) SetViews(views []View) {
+	tm.views = views
+}
@@ -0,0 +1,21 @@
+package task
+
+import "testing"
+
+func TestAddViewRejectsTakenNames(t *testing.T) {
+	tm := NewTaskManager()
+	tm.SetViews(DefaultViews())
+	count := len(tm.ListViews())
+	for _, name := range []string{"", "  ", DefaultViews()[0].Name} {
+		if err := tm.AddView(View{Name: name, Query: "is:open"}); err == nil {
+			t.Errorf("AddView(%q) succeeded", name)
+		}
+	}
+	if err := tm.AddView(View{Name: " Mine ", Query: "assignee:me"}); err != nil {
+		t.Fatal(err)
+	}
+	views := tm.ListViews()
+	if len(views) != count+1 || views[count].Name != "Mine" {
+		t.Errorf("views = %+v, want only Mine added", views)
+	}
+}
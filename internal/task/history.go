@@ -0,0 +1,459 @@
+package task
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Actions recorded in a Change
+const (
+	ChangeAdd    = "add"
+	ChangeModify = "modify"
+	ChangeDelete = "delete"
+)
+
+// Kinds of entity a Change applies to
+const (
+	EntityProject = "project"
+	EntityTask    = "task"
+	EntitySubtask = "subtask"
+	EntityPerson  = "person"
+	EntityView    = "view"
+)
+
+// Change records one field of one entity changed by a TaskManager mutation.
+// Additions and deletions are recorded as a single change whose New or Old
+// value is the title (or name) of the entity.
+type Change struct {
+	Time      time.Time `json:"time"`
+	Actor     string    `json:"actor,omitempty"`
+	Action    string    `json:"action"`
+	Entity    string    `json:"entity"`
+	ProjectID int       `json:"project_id,omitempty"`
+	TaskID    int       `json:"task_id,omitempty"`
+	SubtaskID int       `json:"subtask_id,omitempty"`
+	Key       string    `json:"key,omitempty"` // Handle of a person or name of a view
+	Field     string    `json:"field,omitempty"`
+	Old       string    `json:"old,omitempty"`
+	New       string    `json:"new,omitempty"`
+}
+
+// String describes a change, e.g. `task 4 Title: "Draft" -> "Final"`.
+func (c Change) String() string {
+	var target string
+	switch c.Entity {
+	case EntityProject:
+		target = fmt.Sprintf("project %d", c.ProjectID)
+	case EntityTask:
+		target = fmt.Sprintf("task %d", c.TaskID)
+	case EntitySubtask:
+		target = fmt.Sprintf("subtask %d of task %d", c.SubtaskID, c.TaskID)
+	default:
+		target = fmt.Sprintf("%s %q", c.Entity, c.Key)
+	}
+	switch c.Action {
+	case ChangeAdd:
+		return fmt.Sprintf("added %s %q", target, c.New)
+	case ChangeDelete:
+		return fmt.Sprintf("deleted %s %q", target, c.Old)
+	}
+	return fmt.Sprintf("%s %s: %q -> %q", target, c.Field, c.Old, c.New)
+}
+
+// ChangeListener is called with the changes made by each mutation.
+type ChangeListener func(changes []Change)
+
+// OnChange registers a listener called after every mutation that changed
+// something.
+func (tm *TaskManager) OnChange(listener ChangeListener) {
+	tm.listeners = append(tm.listeners, listener)
+}
+
+// snapshot is a deep copy of the part of the state a mutation may change:
+// the projects in its scope, the people and the views.
+type snapshot struct {
+	Projects []Project
+	People   []Person
+	Views    []View
+	all      []Project // Every project, the ones out of scope shared with the state
+}
+
+// scope lists the IDs of the projects a mutation may change. The projects
+// out of scope are not copied before the mutation, nor compared after it.
+type scope []int
+
+var (
+	allProjects scope     // Every project, e.g. for a change of assignees
+	noProjects  = scope{} // Only people and views
+)
+
+// includes reports whether a project is in the scope.
+func (sc scope) includes(projectID int) bool {
+	return sc == nil || slices.Contains(sc, projectID)
+}
+
+// takeSnapshot deep-copies the part of the state in a scope.
+func (tm *TaskManager) takeSnapshot(sc scope) snapshot {
+	s := snapshot{all: slices.Clone(tm.projects)}
+	for _, p := range tm.projects {
+		if sc.includes(p.ID) {
+			s.Projects = append(s.Projects, deepCopy(p))
+		}
+	}
+	s.People, s.Views = deepCopy(tm.people), deepCopy(tm.views)
+	return s
+}
+
+// deepCopy copies a value and everything it points to.
+func deepCopy[T any](v T) T {
+	return copyValue(reflect.ValueOf(v)).Interface().(T)
+}
+
+// copyValue copies the slices, maps and pointers of a value recursively.
+// Unexported fields, such as the location of a time, are shared.
+func copyValue(v reflect.Value) reflect.Value {
+	copied := reflect.New(v.Type()).Elem()
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			copied.Set(reflect.New(v.Type().Elem()))
+			copied.Elem().Set(copyValue(v.Elem()))
+		}
+	case reflect.Slice:
+		if !v.IsNil() {
+			copied.Set(reflect.MakeSlice(v.Type(), v.Len(), v.Len()))
+			for i := 0; i < v.Len(); i++ {
+				copied.Index(i).Set(copyValue(v.Index(i)))
+			}
+		}
+	case reflect.Map:
+		if !v.IsNil() {
+			copied.Set(reflect.MakeMapWithSize(v.Type(), v.Len()))
+			for iter := v.MapRange(); iter.Next(); {
+				copied.SetMapIndex(iter.Key(), copyValue(iter.Value()))
+			}
+		}
+	case reflect.Struct:
+		copied.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if copied.Field(i).CanSet() {
+				copied.Field(i).Set(copyValue(v.Field(i)))
+			}
+		}
+	default:
+		copied.Set(v)
+	}
+	return copied
+}
+
+// track snapshots the projects in a scope, the people and the views before a
+// mutation, and returns a function that, deferred, records what the mutation
+// changed. Nested mutations are recorded by the outermost one, whose scope
+// must include theirs. If the mutation is undone, because a hook vetoed it
+// or its changes cannot be recorded, the error is stored in *err, unless err
+// is nil, and passed to the veto listeners.
+func (tm *TaskManager) track(err *error, sc scope) func() {
+	_, _, end := tm.begin(err, sc)
+	return end
+}
+
+// begin is track, also returning the state before the mutation and the next
+// IDs that went with it, or nil if the mutation is nested in another one.
+func (tm *TaskManager) begin(undone *error, sc scope) (*snapshot, [3]int, func()) {
+	tm.tracking++
+	if tm.tracking > 1 {
+		return nil, [3]int{}, func() { tm.tracking-- }
+	}
+	before := tm.takeSnapshot(sc)
+	nextIDs := tm.nextIDs()
+	return &before, nextIDs, func() {
+		tm.tracking--
+		after := tm.takeSnapshot(sc)
+		now := time.Now()
+		ops, err := diffOps(before, after, now)
+		if err == nil && len(ops) > 0 && tm.hook != nil && !tm.replacing {
+			if err = tm.runHook(before, after); err == nil {
+				after = tm.takeSnapshot(sc)
+				ops, err = diffOps(before, after, now)
+			}
+		}
+		if err != nil {
+			tm.restore(before, nextIDs)
+			if undone != nil {
+				*undone = err
+			}
+			for _, listener := range tm.vetoListeners {
+				listener(err)
+			}
+			return
+		}
+		if len(ops) == 0 {
+			return
+		}
+		tm.revision++
+		for _, listener := range tm.opListeners {
+			listener(ops)
+		}
+		tm.publish(before, after, now)
+		if len(tm.listeners) == 0 {
+			return
+		}
+		changes := diffSnapshots(before, after, Change{Time: now, Actor: tm.currentUser})
+		for _, listener := range tm.listeners {
+			listener(changes)
+		}
+	}
+}
+
+// formatValue formats a field value for the history: strings as they are,
+// times as RFC 3339, everything else as JSON.
+func formatValue(v reflect.Value) string {
+	switch value := v.Interface().(type) {
+	case string:
+		return value
+	case time.Time:
+		if value.IsZero() {
+			return ""
+		}
+		return value.Format(time.RFC3339)
+	case *time.Time:
+		if value == nil {
+			return ""
+		}
+		return value.Format(time.RFC3339)
+	}
+	if (v.Kind() == reflect.Slice || v.Kind() == reflect.Map) && v.Len() == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(v.Interface())
+	return string(data)
+}
+
+// diffFields records the fields of two versions of an entity that differ,
+// skipping the slices of nested entities. Lists of records such as comments
+// and time entries are compared item by item, e.g. as field "Comments[2]".
+func diffFields(ref Change, before, after any) []Change {
+	var changes []Change
+	record := func(field, old, new string) {
+		if old != new {
+			change := ref
+			change.Action = ChangeModify
+			change.Field = field
+			change.Old, change.New = old, new
+			changes = append(changes, change)
+		}
+	}
+	b, a := reflect.ValueOf(before), reflect.ValueOf(after)
+	for i := 0; i < b.NumField(); i++ {
+		field := b.Type().Field(i).Name
+		if field == "Tasks" || field == "Subtasks" {
+			continue
+		}
+		if b.Field(i).Kind() != reflect.Slice || b.Field(i).Type().Elem().Kind() != reflect.Struct {
+			record(field, formatValue(b.Field(i)), formatValue(a.Field(i)))
+			continue
+		}
+		for j := 0; j < b.Field(i).Len() || j < a.Field(i).Len(); j++ {
+			var old, new string
+			if j < b.Field(i).Len() {
+				old = formatValue(b.Field(i).Index(j))
+			}
+			if j < a.Field(i).Len() {
+				new = formatValue(a.Field(i).Index(j))
+			}
+			record(fmt.Sprintf("%s[%d]", field, j), old, new)
+		}
+	}
+	return changes
+}
+
+// entityName returns the title or name an entity is listed by.
+func entityName(entity any) string {
+	switch e := entity.(type) {
+	case Project:
+		return e.Name
+	case Task:
+		return e.Title
+	case Subtask:
+		return e.Title
+	case Person:
+		return e.Handle
+	case View:
+		return e.Name
+	}
+	return ""
+}
+
+// walkSnapshots calls visit for every project, task, subtask, person and view
+// of two states, with its version before and after. The version is nil when
+// the entity did not exist, and the entities of a deleted project or task
+// are not visited. Entities are visited in the order of the state after the
+// change followed by the deleted ones, parents before their children.
+func walkSnapshots(before, after snapshot, base Change, visit func(ref Change, old, new any)) {
+	oldProjects := map[int]Project{}
+	for _, p := range before.Projects {
+		oldProjects[p.ID] = p
+	}
+	for _, p := range after.Projects {
+		ref := base
+		ref.Entity, ref.ProjectID = EntityProject, p.ID
+		old, existed := oldProjects[p.ID]
+		delete(oldProjects, p.ID)
+		if existed {
+			visit(ref, old, p)
+		} else {
+			visit(ref, nil, p)
+		}
+
+		oldTasks := map[int]Task{}
+		for _, t := range old.Tasks {
+			oldTasks[t.ID] = t
+		}
+		for _, t := range p.Tasks {
+			ref := base
+			ref.Entity, ref.ProjectID, ref.TaskID = EntityTask, p.ID, t.ID
+			oldTask, existed := oldTasks[t.ID]
+			delete(oldTasks, t.ID)
+			if existed {
+				visit(ref, oldTask, t)
+			} else {
+				visit(ref, nil, t)
+			}
+
+			oldSubtasks := map[int]Subtask{}
+			for _, s := range oldTask.Subtasks {
+				oldSubtasks[s.ID] = s
+			}
+			for _, s := range t.Subtasks {
+				ref := base
+				ref.Entity, ref.ProjectID, ref.TaskID, ref.SubtaskID = EntitySubtask, p.ID, t.ID, s.ID
+				oldSubtask, existed := oldSubtasks[s.ID]
+				delete(oldSubtasks, s.ID)
+				if existed {
+					visit(ref, oldSubtask, s)
+				} else {
+					visit(ref, nil, s)
+				}
+			}
+			for _, s := range oldTask.Subtasks {
+				if _, gone := oldSubtasks[s.ID]; gone {
+					ref := base
+					ref.Entity, ref.ProjectID, ref.TaskID, ref.SubtaskID = EntitySubtask, p.ID, t.ID, s.ID
+					visit(ref, s, nil)
+				}
+			}
+		}
+		for _, t := range old.Tasks {
+			if _, gone := oldTasks[t.ID]; gone {
+				ref := base
+				ref.Entity, ref.ProjectID, ref.TaskID = EntityTask, p.ID, t.ID
+				visit(ref, t, nil)
+			}
+		}
+	}
+	for _, p := range before.Projects {
+		if _, gone := oldProjects[p.ID]; gone {
+			ref := base
+			ref.Entity, ref.ProjectID = EntityProject, p.ID
+			visit(ref, p, nil)
+		}
+	}
+
+	oldPeople := map[string]Person{}
+	for _, person := range before.People {
+		oldPeople[strings.ToLower(person.Handle)] = person
+	}
+	for _, person := range after.People {
+		ref := base
+		ref.Entity, ref.Key = EntityPerson, person.Handle
+		old, existed := oldPeople[strings.ToLower(person.Handle)]
+		delete(oldPeople, strings.ToLower(person.Handle))
+		if existed {
+			visit(ref, old, person)
+		} else {
+			visit(ref, nil, person)
+		}
+	}
+	for _, person := range before.People {
+		if _, gone := oldPeople[strings.ToLower(person.Handle)]; gone {
+			ref := base
+			ref.Entity, ref.Key = EntityPerson, person.Handle
+			visit(ref, person, nil)
+		}
+	}
+
+	oldViews := map[string]View{}
+	for _, view := range before.Views {
+		oldViews[view.Name] = view
+	}
+	for _, view := range after.Views {
+		ref := base
+		ref.Entity, ref.Key = EntityView, view.Name
+		old, existed := oldViews[view.Name]
+		delete(oldViews, view.Name)
+		if existed {
+			visit(ref, old, view)
+		} else {
+			visit(ref, nil, view)
+		}
+	}
+	for _, view := range before.Views {
+		if _, gone := oldViews[view.Name]; gone {
+			ref := base
+			ref.Entity, ref.Key = EntityView, view.Name
+			visit(ref, view, nil)
+		}
+	}
+}
+
+// diffSnapshots records the differences between two states, in the order of
+// the entities after the change followed by the deleted ones.
+func diffSnapshots(before, after snapshot, base Change) []Change {
+	var changes []Change
+	walkSnapshots(before, after, base, func(ref Change, old, new any) {
+		switch {
+		case old == nil:
+			ref.Action, ref.New = ChangeAdd, entityName(new)
+			changes = append(changes, ref)
+		case new == nil:
+			ref.Action, ref.Old = ChangeDelete, entityName(old)
+			changes = append(changes, ref)
+		default:
+			changes = append(changes, diffFields(ref, old, new)...)
+		}
+	})
+	return changes
+}
+
+// HistoryFilter selects changes from the history.
+type HistoryFilter struct {
+	ProjectID int // 0 matches every project
+	TaskID    int
+	SubtaskID int
+	Actor     string
+	Field     string
+	Action    string
+	Since     time.Time
+	Until     time.Time
+}
+
+// Match reports whether a change satisfies the filter. Filtering on a task
+// also matches the changes of its subtasks.
+func (f HistoryFilter) Match(c Change) bool {
+	switch {
+	case f.ProjectID != 0 && c.ProjectID != f.ProjectID,
+		f.TaskID != 0 && c.TaskID != f.TaskID,
+		f.SubtaskID != 0 && c.SubtaskID != f.SubtaskID,
+		f.Actor != "" && !strings.EqualFold(c.Actor, strings.TrimPrefix(f.Actor, "@")),
+		f.Field != "" && !strings.EqualFold(c.Field, f.Field),
+		f.Action != "" && !strings.EqualFold(c.Action, f.Action),
+		!f.Since.IsZero() && c.Time.Before(f.Since),
+		!f.Until.IsZero() && !c.Time.Before(f.Until):
+		return false
+	}
+	return true
+}
@@ -0,0 +1,119 @@
+package task
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestManager returns a TaskManager with two projects of one task each,
+// and the ops of its mutations from now on.
+func newTestManager() (*TaskManager, *[]Op) {
+	tm := NewTaskManager()
+	tm.AddProject(Project{Name: "Website"})
+	tm.AddProject(Project{Name: "Blog"})
+	tm.AddTask(1, Task{Title: "Design"})
+	tm.AddTask(2, Task{Title: "Write"})
+	var ops []Op
+	tm.OnOps(func(changed []Op) { ops = append(ops, changed...) })
+	return tm, &ops
+}
+
+func TestMutationRecordsOnlyWhatChanged(t *testing.T) {
+	tm, ops := newTestManager()
+	if err := tm.SetTaskText(2, 2, "Write the post", ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(*ops) != 1 || (*ops)[0].Entity != EntityTask || (*ops)[0].ProjectID != 2 || (*ops)[0].TaskID != 2 {
+		t.Fatalf("ops = %+v, want one modifying task 2/2", *ops)
+	}
+	tm.AddProject(Project{Name: "Shop"})
+	tm.RemoveProject(1)
+	if len(*ops) != 3 || (*ops)[1].Action != ChangeAdd || (*ops)[1].ProjectID != 3 || (*ops)[2].Action != ChangeDelete || (*ops)[2].ProjectID != 1 {
+		t.Fatalf("ops = %+v, want project 3 added and project 1 deleted", *ops)
+	}
+}
+
+func TestUnencodableChangeIsUndone(t *testing.T) {
+	tm, ops := newTestManager()
+	var undone []error
+	tm.OnVeto(func(err error) { undone = append(undone, err) })
+
+	// Times past year 9999 cannot be encoded as JSON
+	due := time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)
+	err := tm.SetTaskDue(1, 1, &due)
+	if err == nil {
+		t.Fatal("SetTaskDue succeeded")
+	}
+	if len(undone) != 1 || undone[0] != err {
+		t.Errorf("veto listeners got %v, want %v", undone, err)
+	}
+	if len(*ops) != 0 || tm.ListTasks(1)[0].Due != nil {
+		t.Errorf("change recorded or kept: ops %+v, due %v", *ops, tm.ListTasks(1)[0].Due)
+	}
+}
+
+func TestVetoRestoresTheState(t *testing.T) {
+	tm, ops := newTestManager()
+	tm.SetHook(func(op Op, old json.RawMessage) (json.RawMessage, error) {
+		if op.Entity == EntityTask && op.ProjectID == 1 {
+			return nil, errors.New("project 1 is frozen")
+		}
+		return op.Value, nil
+	})
+	before := tm.nextIDs()
+
+	err := tm.SetTaskText(1, 1, "Design the pages", "")
+	var veto *VetoError
+	if !errors.As(err, &veto) {
+		t.Fatalf("SetTaskText returned %v, want a *VetoError", err)
+	}
+	if title := tm.ListTasks(1)[0].Title; title != "Design" {
+		t.Errorf("title = %q after the veto", title)
+	}
+	tm.AddTask(1, Task{Title: "Build"})
+	if len(tm.ListTasks(1)) != 1 || tm.nextIDs() != before {
+		t.Errorf("tasks %+v and next IDs %v, want the added task undone", tm.ListTasks(1), tm.nextIDs())
+	}
+	// Projects out of the scope of the vetoed mutations are left alone
+	if err := tm.SetTaskText(2, 2, "Write the post", ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(*ops) != 1 || tm.ListTasks(2)[0].Title != "Write the post" || len(tm.ListProjects()) != 2 {
+		t.Errorf("ops = %+v, projects %+v, want only the change of project 2", *ops, tm.ListProjects())
+	}
+}
+
+func TestUpdateRestoresOnError(t *testing.T) {
+	tm, ops := newTestManager()
+	err := tm.Update(func() error {
+		tm.RemoveProject(2)
+		tm.AddTask(1, Task{Title: "Build"})
+		return errors.New("stop")
+	})
+	if err == nil || len(*ops) != 0 {
+		t.Fatalf("Update returned %v with ops %+v", err, *ops)
+	}
+	if projects := tm.ListProjects(); len(projects) != 2 || len(projects[0].Tasks) != 1 {
+		t.Errorf("projects = %+v, want the state before the update", projects)
+	}
+}
+
+func TestDeepCopy(t *testing.T) {
+	done := time.Now()
+	completedAt := done
+	p := Project{Tasks: []Task{{Title: "Design", CompletedAt: &completedAt, DependsOn: []int{2}, Subtasks: []Subtask{{Title: "Mockups"}}}}}
+	copied := deepCopy(p)
+	p.Tasks[0].Title = "Build"
+	*p.Tasks[0].CompletedAt = done.Add(time.Hour)
+	p.Tasks[0].DependsOn[0] = 3
+	p.Tasks[0].Subtasks[0].Title = "Pages"
+	task := copied.Tasks[0]
+	if task.Title != "Design" || !task.CompletedAt.Equal(done) || task.DependsOn[0] != 2 || task.Subtasks[0].Title != "Mockups" {
+		t.Errorf("copy changed with the original: %+v", task)
+	}
+	if copied := deepCopy(Project{}); copied.Tasks != nil {
+		t.Errorf("nil slice copied to %#v", copied.Tasks)
+	}
+}
@@ -0,0 +1,90 @@
+package task
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Hook is called with each project, task or subtask a mutation is about to
+// add, modify or delete, before the change is recorded. op holds the entity
+// as the mutation left it, and old the entity as it was, or nil if it is
+// added. The hook returns the value to record in place of op.Value, or an
+// error vetoing the mutation, whose changes are then all undone.
+type Hook func(op Op, old json.RawMessage) (json.RawMessage, error)
+
+// VetoError is the error of a mutation a hook vetoed.
+type VetoError struct {
+	Err error
+}
+
+func (e *VetoError) Error() string { return e.Err.Error() }
+func (e *VetoError) Unwrap() error { return e.Err }
+
+// VetoListener is called with the error of each mutation a hook vetoed.
+type VetoListener func(err error)
+
+// SetHook sets the hook checking every mutation, or removes it if hook is
+// nil. Changes replicated from other copies, which were checked there, and
+// changes reloaded from the files are not checked.
+func (tm *TaskManager) SetHook(hook Hook) {
+	tm.hook = hook
+}
+
+// OnVeto registers a listener called when a hook vetoes a mutation.
+func (tm *TaskManager) OnVeto(listener VetoListener) {
+	tm.vetoListeners = append(tm.vetoListeners, listener)
+}
+
+// runHook passes the projects, tasks and subtasks that differ between two
+// states to the hook, and applies the values it rewrote them to. It returns
+// a *VetoError if the hook vetoed a change or rewrote it to something else.
+func (tm *TaskManager) runHook(before, after snapshot) error {
+	var err error
+	now := time.Now()
+	walkSnapshots(before, after, Change{}, func(ref Change, old, new any) {
+		if err != nil || ref.Entity == EntityPerson || ref.Entity == EntityView {
+			return
+		}
+		op, changed, opErr := newOp(ref, old, new, now)
+		if opErr != nil || !changed {
+			err = opErr
+			return
+		}
+		var previous, value json.RawMessage
+		if old != nil {
+			if previous, err = opValue(old); err != nil {
+				return
+			}
+		}
+		value, err = tm.hook(op, previous)
+		if err == nil && op.Action != ChangeDelete && value != nil && !bytes.Equal(value, op.Value) {
+			if err = checkIdentity(op, value); err == nil {
+				op.Value = value
+				err = tm.applyOp(op)
+			}
+		}
+		if err != nil {
+			err = &VetoError{err}
+		}
+	})
+	return err
+}
+
+// checkIdentity returns an error if a value a hook rewrote an entity to is
+// not valid, or is another entity.
+func checkIdentity(op Op, value json.RawMessage) error {
+	var before, after struct {
+		ID  int
+		UID string
+	}
+	json.Unmarshal(op.Value, &before)
+	if err := json.Unmarshal(value, &after); err != nil {
+		return fmt.Errorf("invalid %s %d returned by a hook: %v", op.Entity, before.ID, err)
+	}
+	if after != before {
+		return fmt.Errorf("a hook cannot change the ID or UID of %s %d", op.Entity, before.ID)
+	}
+	return nil
+}
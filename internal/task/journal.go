@@ -0,0 +1,349 @@
+package task
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Op is a replayable record of one entity changed by a mutation: its whole
+// new value when it was added or modified, or its deletion. The value of a
+// project leaves out its tasks, and the value of a task its subtasks, which
+// have ops of their own.
+type Op struct {
+	Time      time.Time       `json:"time"`
+	Action    string          `json:"action"` // ChangeAdd, ChangeModify or ChangeDelete
+	Entity    string          `json:"entity"`
+	ProjectID int             `json:"project_id,omitempty"`
+	TaskID    int             `json:"task_id,omitempty"`
+	SubtaskID int             `json:"subtask_id,omitempty"`
+	Key       string          `json:"key,omitempty"` // Handle of a person or name of a view
+	Value     json.RawMessage `json:"value,omitempty"`
+}
+
+// OpListener is called with the ops of each mutation.
+type OpListener func(ops []Op)
+
+// OnOps registers a listener called after every mutation that changed
+// something, with the ops replaying it.
+func (tm *TaskManager) OnOps(listener OpListener) {
+	tm.opListeners = append(tm.opListeners, listener)
+}
+
+// opValue encodes an entity without its nested entities.
+func opValue(entity any) (json.RawMessage, error) {
+	switch e := entity.(type) {
+	case Project:
+		e.Tasks = nil
+		entity = e
+	case Task:
+		e.Subtasks = nil
+		entity = e
+	}
+	return json.Marshal(entity)
+}
+
+// diffOps returns the ops turning one state into another.
+func diffOps(before, after snapshot, now time.Time) ([]Op, error) {
+	var ops []Op
+	var err error
+	walkSnapshots(before, after, Change{}, func(ref Change, old, new any) {
+		if err != nil {
+			return
+		}
+		var op Op
+		var changed bool
+		if op, changed, err = newOp(ref, old, new, now); changed {
+			ops = append(ops, op)
+		}
+	})
+	return ops, err
+}
+
+// newOp returns the op turning the old version of an entity into the new
+// one, either being nil if the entity is added or deleted, and whether they
+// differ.
+func newOp(ref Change, old, new any, now time.Time) (Op, bool, error) {
+	op := Op{Time: now, Entity: ref.Entity, ProjectID: ref.ProjectID, TaskID: ref.TaskID, SubtaskID: ref.SubtaskID, Key: ref.Key}
+	var err error
+	switch {
+	case old == nil:
+		op.Action = ChangeAdd
+		op.Value, err = opValue(new)
+	case new == nil:
+		op.Action = ChangeDelete
+	case len(diffFields(ref, old, new)) > 0:
+		op.Action = ChangeModify
+		op.Value, err = opValue(new)
+	default:
+		return op, false, nil
+	}
+	if err != nil {
+		return op, false, fmt.Errorf("cannot record %s %q: %v", ref.Entity, entityName(new), err)
+	}
+	return op, true, nil
+}
+
+// ApplyOps replays ops on the current state, e.g. the ops journaled since the
+// projects were last saved. Replaying is idempotent: an op adding or
+// modifying an entity sets its whole value, and ops modifying or deleting
+// entities that no longer exist, or on entities whose parents no longer
+// exist, are skipped. Listeners are not called.
+func (tm *TaskManager) ApplyOps(ops []Op) error {
+	for _, op := range ops {
+		if err := tm.applyOp(op); err != nil {
+			return fmt.Errorf("cannot replay %s of %s: %v", op.Action, op.Entity, err)
+		}
+	}
+	// Bring the next IDs past the replayed entities
+	tm.SetProjects(tm.projects)
+	if len(ops) > 0 {
+		tm.revision++
+	}
+	return nil
+}
+
+// applyOp replays one op.
+func (tm *TaskManager) applyOp(op Op) error {
+	deleting := op.Action == ChangeDelete
+	switch op.Entity {
+	case EntityProject:
+		for i := range tm.projects {
+			if tm.projects[i].ID == op.ProjectID {
+				if deleting {
+					tm.projects = append(tm.projects[:i], tm.projects[i+1:]...)
+					return nil
+				}
+				var project Project
+				if err := json.Unmarshal(op.Value, &project); err != nil {
+					return err
+				}
+				project.Tasks = tm.projects[i].Tasks
+				tm.projects[i] = project
+				return nil
+			}
+		}
+		if op.Action == ChangeAdd {
+			var project Project
+			if err := json.Unmarshal(op.Value, &project); err != nil {
+				return err
+			}
+			tm.projects = append(tm.projects, project)
+		}
+
+	case EntityTask, EntitySubtask:
+		for i := range tm.projects {
+			if tm.projects[i].ID != op.ProjectID {
+				continue
+			}
+			tasks := &tm.projects[i].Tasks
+			for j := range *tasks {
+				if (*tasks)[j].ID != op.TaskID {
+					continue
+				}
+				if op.Entity == EntitySubtask {
+					return applySubtaskOp(&(*tasks)[j].Subtasks, op)
+				}
+				if deleting {
+					*tasks = append((*tasks)[:j], (*tasks)[j+1:]...)
+					return nil
+				}
+				var t Task
+				if err := json.Unmarshal(op.Value, &t); err != nil {
+					return err
+				}
+				t.Subtasks = (*tasks)[j].Subtasks
+				(*tasks)[j] = t
+				return nil
+			}
+			if op.Entity == EntityTask && op.Action == ChangeAdd {
+				var t Task
+				if err := json.Unmarshal(op.Value, &t); err != nil {
+					return err
+				}
+				*tasks = append(*tasks, t)
+			}
+		}
+
+	case EntityPerson:
+		i := tm.personIndex(op.Key)
+		switch {
+		case deleting && i != -1:
+			tm.people = append(tm.people[:i], tm.people[i+1:]...)
+		case !deleting:
+			var person Person
+			if err := json.Unmarshal(op.Value, &person); err != nil {
+				return err
+			}
+			if i == -1 {
+				tm.people = append(tm.people, person)
+			} else {
+				tm.people[i] = person
+			}
+		}
+
+	case EntityView:
+		i := -1
+		for j, view := range tm.views {
+			if view.Name == op.Key {
+				i = j
+			}
+		}
+		switch {
+		case deleting && i != -1:
+			tm.views = append(tm.views[:i], tm.views[i+1:]...)
+		case !deleting:
+			var view View
+			if err := json.Unmarshal(op.Value, &view); err != nil {
+				return err
+			}
+			if i == -1 {
+				tm.views = append(tm.views, view)
+			} else {
+				tm.views[i] = view
+			}
+		}
+
+	default:
+		return fmt.Errorf("unknown entity %q", op.Entity)
+	}
+	return nil
+}
+
+// applySubtaskOp replays an op on the subtasks of a task.
+func applySubtaskOp(subtasks *[]Subtask, op Op) error {
+	for k := range *subtasks {
+		if (*subtasks)[k].ID == op.SubtaskID {
+			if op.Action == ChangeDelete {
+				*subtasks = append((*subtasks)[:k], (*subtasks)[k+1:]...)
+				return nil
+			}
+			var subtask Subtask
+			if err := json.Unmarshal(op.Value, &subtask); err != nil {
+				return err
+			}
+			(*subtasks)[k] = subtask
+			return nil
+		}
+	}
+	if op.Action != ChangeAdd {
+		return nil
+	}
+	var subtask Subtask
+	if err := json.Unmarshal(op.Value, &subtask); err != nil {
+		return err
+	}
+	*subtasks = append(*subtasks, subtask)
+	return nil
+}
+
+// idTaken reports whether the project, task or subtask added by op has an ID
+// already used by another entity of the same kind.
+func (tm *TaskManager) idTaken(op Op) bool {
+	for _, p := range tm.projects {
+		if op.Entity == EntityProject && p.ID == op.ProjectID {
+			return true
+		}
+		for _, t := range p.Tasks {
+			if op.Entity == EntityTask && t.ID == op.TaskID {
+				return true
+			}
+			for _, s := range t.Subtasks {
+				if op.Entity == EntitySubtask && s.ID == op.SubtaskID {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// renumberValue sets the ID in the value of an op to the ID of its entity,
+// and renumbers the tasks a task depends on.
+func renumberValue(op Op, taskIDs map[int]int) (json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(op.Value, &fields); err != nil {
+		return nil, err
+	}
+	id := map[string]int{EntityProject: op.ProjectID, EntityTask: op.TaskID, EntitySubtask: op.SubtaskID}[op.Entity]
+	fields["ID"], _ = json.Marshal(id)
+	if op.Entity == EntityTask && fields["DependsOn"] != nil {
+		var dependsOn []int
+		if err := json.Unmarshal(fields["DependsOn"], &dependsOn); err != nil {
+			return nil, err
+		}
+		for i, dependency := range dependsOn {
+			if renumbered, ok := taskIDs[dependency]; ok {
+				dependsOn[i] = renumbered
+			}
+		}
+		fields["DependsOn"], _ = json.Marshal(dependsOn)
+	}
+	return json.Marshal(fields)
+}
+
+// RebaseOps applies ops made on an older version of the state, like
+// ApplyOps, giving the projects, tasks and subtasks they add new IDs when
+// theirs were taken in the meantime, e.g. by another process adding entities
+// at the same time. It returns the ops as applied.
+func (tm *TaskManager) RebaseOps(ops []Op) ([]Op, error) {
+	projectIDs, taskIDs, subtaskIDs := map[int]int{}, map[int]int{}, map[int]int{}
+	// New IDs are allocated past the IDs the ops add as well
+	for _, op := range ops {
+		if op.Action != ChangeAdd {
+			continue
+		}
+		switch op.Entity {
+		case EntityProject:
+			tm.nextProjectID = max(tm.nextProjectID, op.ProjectID+1)
+		case EntityTask:
+			tm.nextTaskID = max(tm.nextTaskID, op.TaskID+1)
+		case EntitySubtask:
+			tm.nextSubID = max(tm.nextSubID, op.SubtaskID+1)
+		}
+	}
+
+	rebased := make([]Op, 0, len(ops))
+	for _, op := range ops {
+		renumbered := false
+		if id, ok := projectIDs[op.ProjectID]; ok {
+			op.ProjectID, renumbered = id, true
+		}
+		if id, ok := taskIDs[op.TaskID]; ok {
+			op.TaskID, renumbered = id, true
+		}
+		if id, ok := subtaskIDs[op.SubtaskID]; ok {
+			op.SubtaskID, renumbered = id, true
+		}
+		if op.Action == ChangeAdd && tm.idTaken(op) {
+			switch op.Entity {
+			case EntityProject:
+				projectIDs[op.ProjectID] = tm.getNextProjectID()
+				op.ProjectID = projectIDs[op.ProjectID]
+			case EntityTask:
+				taskIDs[op.TaskID] = tm.getNextTaskID()
+				op.TaskID = taskIDs[op.TaskID]
+			case EntitySubtask:
+				subtaskIDs[op.SubtaskID] = tm.getNextSubtaskID()
+				op.SubtaskID = subtaskIDs[op.SubtaskID]
+			}
+			renumbered = true
+		}
+		if op.Value != nil && (renumbered || op.Entity == EntityTask && len(taskIDs) > 0) {
+			value, err := renumberValue(op, taskIDs)
+			if err != nil {
+				return nil, fmt.Errorf("cannot renumber %s: %v", op.Entity, err)
+			}
+			op.Value = value
+		}
+		if err := tm.applyOp(op); err != nil {
+			return nil, fmt.Errorf("cannot replay %s of %s: %v", op.Action, op.Entity, err)
+		}
+		rebased = append(rebased, op)
+	}
+	tm.SetProjects(tm.projects)
+	if len(rebased) > 0 {
+		tm.revision++
+	}
+	return rebased, nil
+}
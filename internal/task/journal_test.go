@@ -0,0 +1,44 @@
+package task
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestApplyOpsSkipsMissingEntities(t *testing.T) {
+	tm, _ := newTestManager()
+	value := func(v any) json.RawMessage {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	ops := []Op{
+		{Action: ChangeModify, Entity: EntityProject, ProjectID: 9, Value: value(Project{ID: 9, Name: "Gone"})},
+		{Action: ChangeModify, Entity: EntityTask, ProjectID: 1, TaskID: 9, Value: value(Task{ID: 9, Title: "Gone"})},
+		{Action: ChangeModify, Entity: EntitySubtask, ProjectID: 1, TaskID: 1, SubtaskID: 9, Value: value(Subtask{ID: 9, Title: "Gone"})},
+		{Action: ChangeAdd, Entity: EntitySubtask, ProjectID: 9, TaskID: 1, SubtaskID: 8, Value: value(Subtask{ID: 8, Title: "Orphan"})},
+		{Action: ChangeDelete, Entity: EntityTask, ProjectID: 1, TaskID: 9},
+		{Action: ChangeAdd, Entity: EntityTask, ProjectID: 1, TaskID: 3, Value: value(Task{ID: 3, Title: "Build"})},
+		{Action: ChangeModify, Entity: EntityTask, ProjectID: 1, TaskID: 1, Value: value(Task{ID: 1, Title: "Design the pages"})},
+	}
+	if err := tm.ApplyOps(ops); err != nil {
+		t.Fatal(err)
+	}
+	projects := tm.ListProjects()
+	if len(projects) != 2 {
+		t.Fatalf("projects = %+v, want the missing project left out", projects)
+	}
+	tasks := projects[0].Tasks
+	if len(tasks) != 2 || tasks[0].Title != "Design the pages" || tasks[1].Title != "Build" || len(tasks[0].Subtasks) != 0 {
+		t.Errorf("tasks = %+v, want the added task and the modified one only", tasks)
+	}
+	// Replaying again changes nothing
+	if err := tm.ApplyOps(ops); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(tm.ListTasks(1)); got != 2 {
+		t.Errorf("%d tasks after replaying twice, want 2", got)
+	}
+}
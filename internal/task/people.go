@@ -0,0 +1,241 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PersonColors are the colours a person can be shown in.
+var PersonColors = []string{"red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+
+// Person is a registered teammate tasks can be assigned to.
+type Person struct {
+	Handle string // Short unique name stored in AssignedTo, e.g. "sara"
+	Name   string // Display name
+	Email  string
+	Color  string // One of PersonColors, or empty
+}
+
+// SplitAssignees splits a comma-separated list of assignees, dropping empty
+// entries, leading @ signs and case-insensitive duplicates.
+func SplitAssignees(s string) []string {
+	var assignees []string
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ",") {
+		name := strings.TrimPrefix(strings.TrimSpace(part), "@")
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		assignees = append(assignees, name)
+	}
+	return assignees
+}
+
+// JoinAssignees formats assignees the way they are stored in AssignedTo.
+func JoinAssignees(assignees []string) string {
+	return strings.Join(SplitAssignees(strings.Join(assignees, ",")), ", ")
+}
+
+// Assignees returns the people a task is assigned to.
+func (t Task) Assignees() []string {
+	return SplitAssignees(t.AssignedTo)
+}
+
+// Assignees returns the people a subtask is assigned to.
+func (s Subtask) Assignees() []string {
+	return SplitAssignees(s.AssignedTo)
+}
+
+// hasAssignee reports whether a list of assignees includes name.
+func hasAssignee(assignedTo, name string) bool {
+	name = strings.TrimPrefix(name, "@")
+	for _, assignee := range SplitAssignees(assignedTo) {
+		if strings.EqualFold(assignee, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// validatePerson checks the handle and colour of a person.
+func validatePerson(person Person) error {
+	if person.Handle == "" {
+		return fmt.Errorf("a person needs a handle")
+	}
+	if strings.ContainsAny(person.Handle, ", \t@") {
+		return fmt.Errorf("handle %q must not contain spaces, commas or @", person.Handle)
+	}
+	if person.Color != "" {
+		for _, color := range PersonColors {
+			if person.Color == color {
+				return nil
+			}
+		}
+		return fmt.Errorf("unknown colour %q, expected one of %s", person.Color, strings.Join(PersonColors, ", "))
+	}
+	return nil
+}
+
+// ListPeople returns the registered people ordered by handle.
+func (tm *TaskManager) ListPeople() []Person {
+	people := append([]Person(nil), tm.people...)
+	sort.Slice(people, func(i, j int) bool {
+		return strings.ToLower(people[i].Handle) < strings.ToLower(people[j].Handle)
+	})
+	return people
+}
+
+// SetPeople replaces the registered people.
+func (tm *TaskManager) SetPeople(people []Person) {
+	tm.people = people
+}
+
+// personIndex returns the position of the person with the given handle, or -1.
+func (tm *TaskManager) personIndex(handle string) int {
+	handle = strings.TrimPrefix(handle, "@")
+	for i, person := range tm.people {
+		if strings.EqualFold(person.Handle, handle) {
+			return i
+		}
+	}
+	return -1
+}
+
+// AddPerson registers a person, or updates the person with the same handle.
+func (tm *TaskManager) AddPerson(person Person) (err error) {
+	defer tm.track(&err, noProjects)()
+	person.Handle = strings.TrimPrefix(strings.TrimSpace(person.Handle), "@")
+	person.Color = strings.ToLower(strings.TrimSpace(person.Color))
+	if err := validatePerson(person); err != nil {
+		return err
+	}
+	if i := tm.personIndex(person.Handle); i != -1 {
+		tm.people[i] = person
+		return nil
+	}
+	tm.people = append(tm.people, person)
+	return nil
+}
+
+// RemovePerson removes a person from the registry. Their tasks keep them as
+// a free-text assignee.
+func (tm *TaskManager) RemovePerson(handle string) (err error) {
+	defer tm.track(&err, noProjects)()
+	i := tm.personIndex(handle)
+	if i == -1 {
+		return fmt.Errorf("person %q not found", handle)
+	}
+	tm.people = append(tm.people[:i], tm.people[i+1:]...)
+	return nil
+}
+
+// FindPerson returns the person whose handle, display name or email is name.
+func (tm *TaskManager) FindPerson(name string) (Person, bool) {
+	name = strings.TrimPrefix(strings.TrimSpace(name), "@")
+	for _, person := range tm.people {
+		if strings.EqualFold(person.Handle, name) || strings.EqualFold(person.Name, name) || strings.EqualFold(person.Email, name) {
+			return person, true
+		}
+	}
+	return Person{}, false
+}
+
+// ResolveAssignees turns a comma-separated list of names into the stored
+// form, replacing registered people's names and emails by their handles.
+func (tm *TaskManager) ResolveAssignees(names string) string {
+	assignees := SplitAssignees(names)
+	for i, name := range assignees {
+		if person, ok := tm.FindPerson(name); ok {
+			assignees[i] = person.Handle
+		}
+	}
+	return JoinAssignees(assignees)
+}
+
+// CompleteAssignee returns the handles of the people whose handle, display
+// name or email starts with prefix.
+func (tm *TaskManager) CompleteAssignee(prefix string) []string {
+	prefix = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(prefix), "@"))
+	var handles []string
+	for _, person := range tm.ListPeople() {
+		for _, candidate := range []string{person.Handle, person.Name, person.Email} {
+			if candidate != "" && strings.HasPrefix(strings.ToLower(candidate), prefix) {
+				handles = append(handles, person.Handle)
+				break
+			}
+		}
+	}
+	return handles
+}
+
+// CurrentUser returns the handle of the person using termile, if set.
+func (tm *TaskManager) CurrentUser() string {
+	return tm.currentUser
+}
+
+// SetCurrentUser sets the person using termile. Filters match them as @me.
+func (tm *TaskManager) SetCurrentUser(handle string) {
+	tm.currentUser = strings.TrimPrefix(strings.TrimSpace(handle), "@")
+}
+
+// renameIn replaces an assignee in a list of assignees and reports whether
+// the list changed.
+func renameIn(assignedTo *string, from, to string) bool {
+	if !hasAssignee(*assignedTo, from) {
+		return false
+	}
+	assignees := SplitAssignees(*assignedTo)
+	for i, assignee := range assignees {
+		if strings.EqualFold(assignee, from) {
+			assignees[i] = to
+		}
+	}
+	*assignedTo = JoinAssignees(assignees)
+	return true
+}
+
+// RenameAssignee rewrites every assignment of from to to and returns the
+// number of tasks and subtasks changed. In the registry, from is renamed to
+// to, or merged into it when both are registered.
+func (tm *TaskManager) RenameAssignee(from, to string) (renamed int, err error) {
+	defer tm.track(&err, allProjects)()
+	from = strings.TrimPrefix(strings.TrimSpace(from), "@")
+	to = strings.TrimPrefix(strings.TrimSpace(to), "@")
+	if from == "" || to == "" {
+		return 0, fmt.Errorf("rename needs both an old and a new name")
+	}
+	if strings.Contains(to, ",") {
+		return 0, fmt.Errorf("new name %q must not contain commas", to)
+	}
+
+	if i := tm.personIndex(from); i != -1 {
+		if j := tm.personIndex(to); j != -1 && j != i {
+			tm.people = append(tm.people[:i], tm.people[i+1:]...)
+		} else {
+			renamed := tm.people[i]
+			renamed.Handle = to
+			if err := validatePerson(renamed); err != nil {
+				return 0, err
+			}
+			tm.people[i] = renamed
+		}
+	}
+
+	changed := 0
+	for i := range tm.projects {
+		for j := range tm.projects[i].Tasks {
+			t := &tm.projects[i].Tasks[j]
+			if renameIn(&t.AssignedTo, from, to) {
+				changed++
+			}
+			for k := range t.Subtasks {
+				if renameIn(&t.Subtasks[k].AssignedTo, from, to) {
+					changed++
+				}
+			}
+		}
+	}
+	return changed, nil
+}
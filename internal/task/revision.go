@@ -0,0 +1,59 @@
+package task
+
+// Revision returns a number that changes with every mutation that changed
+// something, e.g. to tell whether the state was modified since it was drawn.
+func (tm *TaskManager) Revision() int {
+	return tm.revision
+}
+
+// Dirty reports whether the state changed since it was last marked saved.
+func (tm *TaskManager) Dirty() bool {
+	return tm.revision != tm.savedRevision
+}
+
+// MarkSaved records that the current state is saved.
+func (tm *TaskManager) MarkSaved() {
+	tm.savedRevision = tm.revision
+}
+
+// Update runs several mutations as one change, e.g. to edit many fields of a
+// task at once: listeners are called once with everything fn changed. If fn
+// fails or a hook vetoes the change, the state is restored, nothing is
+// recorded and the error is returned.
+func (tm *TaskManager) Update(fn func() error) (err error) {
+	before, nextIDs, end := tm.begin(&err, allProjects)
+	defer end()
+	if before == nil {
+		// Nested in another mutation, which records it
+		state := tm.takeSnapshot(allProjects)
+		before, nextIDs = &state, tm.nextIDs()
+	}
+	if err := fn(); err != nil {
+		tm.restore(*before, nextIDs)
+		return err
+	}
+	return nil
+}
+
+// nextIDs returns the next project, task and subtask IDs.
+func (tm *TaskManager) nextIDs() [3]int {
+	return [3]int{tm.nextProjectID, tm.nextTaskID, tm.nextSubID}
+}
+
+// restore brings back a state and the next IDs that went with it. The
+// projects out of the scope of the snapshot were not changed since.
+func (tm *TaskManager) restore(s snapshot, nextIDs [3]int) {
+	copies := map[int]Project{}
+	for _, p := range s.Projects {
+		copies[p.ID] = p
+	}
+	projects := make([]Project, len(s.all))
+	for i, p := range s.all {
+		if copied, ok := copies[p.ID]; ok {
+			p = copied
+		}
+		projects[i] = p
+	}
+	tm.projects, tm.people, tm.views = projects, s.People, s.Views
+	tm.nextProjectID, tm.nextTaskID, tm.nextSubID = nextIDs[0], nextIDs[1], nextIDs[2]
+}
@@ -0,0 +1,305 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sprint is a milestone or time-boxed iteration of a project. Tasks join a
+// sprint through their SprintID; a SprintID of 0 leaves a task in the backlog.
+type Sprint struct {
+	ID          int
+	Name        string
+	Goal        string
+	Start       time.Time
+	End         time.Time // Last day of the sprint
+	Closed      bool
+	ClosedAt    *time.Time
+	CarriedOver []int // IDs of the tasks moved to the next sprint on close
+}
+
+// SprintProgress sums up the scope and completion of a sprint.
+type SprintProgress struct {
+	Total        int
+	Done         int
+	Estimate     float64
+	DoneEstimate float64
+}
+
+// Percent returns the share of the sprint's tasks that are done.
+func (p SprintProgress) Percent() int {
+	if p.Total == 0 {
+		return 0
+	}
+	return p.Done * 100 / p.Total
+}
+
+// SprintSummary reports on a closed sprint.
+type SprintSummary struct {
+	Sprint    Sprint
+	Unit      string
+	Completed []Task
+	Carried   []Task
+	Next      Sprint // The sprint the unfinished tasks were carried to
+	Progress  SprintProgress
+}
+
+// String formats the summary as a short plain-text report.
+func (s SprintSummary) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s (%s to %s) closed\n", s.Sprint.Name, s.Sprint.Start.Format(DateLayout), s.Sprint.End.Format(DateLayout))
+	if s.Sprint.Goal != "" {
+		fmt.Fprintf(&sb, "Goal: %s\n", s.Sprint.Goal)
+	}
+	fmt.Fprintf(&sb, "Completed %d of %d tasks (%d%%)", s.Progress.Done, s.Progress.Total, s.Progress.Percent())
+	if s.Progress.Estimate > 0 {
+		fmt.Fprintf(&sb, ", %s of %s", FormatEstimate(s.Progress.DoneEstimate, s.Unit), FormatEstimate(s.Progress.Estimate, s.Unit))
+	}
+	sb.WriteString("\n")
+	for _, t := range s.Completed {
+		fmt.Fprintf(&sb, "  [x] %d. %s\n", t.ID, t.Title)
+	}
+	if len(s.Carried) > 0 {
+		fmt.Fprintf(&sb, "Carried over to %s:\n", s.Next.Name)
+		for _, t := range s.Carried {
+			fmt.Fprintf(&sb, "  [ ] %d. %s\n", t.ID, t.Title)
+		}
+	}
+	return sb.String()
+}
+
+// sprintProgress sums up the tasks of a project in a sprint.
+func sprintProgress(project Project, sprintID int) SprintProgress {
+	var progress SprintProgress
+	for _, t := range project.Tasks {
+		if t.SprintID != sprintID {
+			continue
+		}
+		progress.Total++
+		progress.Estimate += t.TotalEstimate()
+		if t.Complete {
+			progress.Done++
+			progress.DoneEstimate += t.TotalEstimate()
+		}
+	}
+	return progress
+}
+
+// sortSprints orders sprints by start date, then ID.
+func sortSprints(sprints []Sprint) {
+	sort.SliceStable(sprints, func(i, j int) bool {
+		if !sprints[i].Start.Equal(sprints[j].Start) {
+			return sprints[i].Start.Before(sprints[j].Start)
+		}
+		return sprints[i].ID < sprints[j].ID
+	})
+}
+
+// nextSprintName numbers the sprint after one called name, e.g. "Sprint 3"
+// becomes "Sprint 4"; other names get a "(continued)" suffix.
+func nextSprintName(name string) string {
+	if i := strings.LastIndex(name, " "); i != -1 {
+		if n, err := strconv.Atoi(name[i+1:]); err == nil {
+			return name[:i+1] + strconv.Itoa(n+1)
+		}
+	}
+	return name + " (continued)"
+}
+
+// ListSprints returns the sprints of a project ordered by start date.
+func (tm *TaskManager) ListSprints(projectID int) []Sprint {
+	for _, project := range tm.projects {
+		if project.ID == projectID {
+			sprints := append([]Sprint(nil), project.Sprints...)
+			sortSprints(sprints)
+			return sprints
+		}
+	}
+	return nil
+}
+
+// AddSprint adds a sprint to a project and returns its ID.
+func (tm *TaskManager) AddSprint(projectID int, sprint Sprint) (id int, err error) {
+	defer tm.track(&err, scope{projectID})()
+	sprint.Name = strings.TrimSpace(sprint.Name)
+	if sprint.Name == "" {
+		return 0, fmt.Errorf("a sprint needs a name")
+	}
+	if sprint.End.Before(sprint.Start) {
+		return 0, fmt.Errorf("sprint %q ends before it starts", sprint.Name)
+	}
+	for i := range tm.projects {
+		if tm.projects[i].ID == projectID {
+			sprint.ID = 1
+			for _, existing := range tm.projects[i].Sprints {
+				if existing.ID >= sprint.ID {
+					sprint.ID = existing.ID + 1
+				}
+			}
+			sprint.Closed = false
+			sprint.ClosedAt = nil
+			tm.projects[i].Sprints = append(tm.projects[i].Sprints, sprint)
+			return sprint.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("project %d not found", projectID)
+}
+
+// ActiveSprint returns the open sprint of a project running at now, or else
+// the earliest open sprint.
+func (tm *TaskManager) ActiveSprint(projectID int, now time.Time) (Sprint, bool) {
+	var first *Sprint
+	sprints := tm.ListSprints(projectID)
+	for i, sprint := range sprints {
+		if sprint.Closed {
+			continue
+		}
+		if !now.Before(sprint.Start) && now.Before(sprint.End.AddDate(0, 0, 1)) {
+			return sprint, true
+		}
+		if first == nil {
+			first = &sprints[i]
+		}
+	}
+	if first == nil {
+		return Sprint{}, false
+	}
+	return *first, true
+}
+
+// SprintTasks returns the tasks of a project in a sprint, or in the backlog
+// when sprintID is 0.
+func (tm *TaskManager) SprintTasks(projectID, sprintID int) []Task {
+	tasks := []Task{}
+	for _, t := range tm.ListTasks(projectID) {
+		if t.SprintID == sprintID {
+			tasks = append(tasks, t)
+		}
+	}
+	return tasks
+}
+
+// SprintProgress sums up the scope and completion of a sprint.
+func (tm *TaskManager) SprintProgress(projectID, sprintID int) SprintProgress {
+	for _, project := range tm.projects {
+		if project.ID == projectID {
+			return sprintProgress(project, sprintID)
+		}
+	}
+	return SprintProgress{}
+}
+
+// AssignSprint moves a task into an open sprint of its project, or back to
+// the backlog when sprintID is 0. Tasks of closed sprints stay where they are.
+func (tm *TaskManager) AssignSprint(projectID, taskID, sprintID int) (err error) {
+	defer tm.track(&err, scope{projectID})()
+	for i := range tm.projects {
+		if tm.projects[i].ID != projectID {
+			continue
+		}
+		closed := map[int]string{}
+		found := sprintID == 0
+		for _, sprint := range tm.projects[i].Sprints {
+			if sprint.Closed {
+				closed[sprint.ID] = sprint.Name
+			}
+			found = found || sprint.ID == sprintID
+		}
+		if !found {
+			return fmt.Errorf("sprint %d not found", sprintID)
+		}
+		if name, ok := closed[sprintID]; ok {
+			return fmt.Errorf("sprint %q is closed", name)
+		}
+		for j := range tm.projects[i].Tasks {
+			if tm.projects[i].Tasks[j].ID == taskID {
+				if name, ok := closed[tm.projects[i].Tasks[j].SprintID]; ok {
+					return fmt.Errorf("task %d belongs to closed sprint %q", taskID, name)
+				}
+				tm.projects[i].Tasks[j].SprintID = sprintID
+				return nil
+			}
+		}
+		return fmt.Errorf("task %d not found", taskID)
+	}
+	return fmt.Errorf("project %d not found", projectID)
+}
+
+// CloseSprint closes a sprint and carries its unfinished tasks to the next
+// open sprint of the project. When there is none, a sprint of the same length
+// starting the day after this one ends is created for them.
+func (tm *TaskManager) CloseSprint(projectID, sprintID int, now time.Time) (summary SprintSummary, err error) {
+	defer tm.track(&err, scope{projectID})()
+	for i := range tm.projects {
+		project := &tm.projects[i]
+		if project.ID != projectID {
+			continue
+		}
+		index := -1
+		for j, sprint := range project.Sprints {
+			if sprint.ID == sprintID {
+				index = j
+			}
+		}
+		if index == -1 {
+			return SprintSummary{}, fmt.Errorf("sprint %d not found", sprintID)
+		}
+		if project.Sprints[index].Closed {
+			return SprintSummary{}, fmt.Errorf("sprint %q is already closed", project.Sprints[index].Name)
+		}
+
+		summary := SprintSummary{Unit: project.Unit(), Progress: sprintProgress(*project, sprintID)}
+		var unfinished []int
+		for _, t := range project.Tasks {
+			if t.SprintID != sprintID {
+				continue
+			}
+			if t.Complete {
+				summary.Completed = append(summary.Completed, t)
+			} else {
+				summary.Carried = append(summary.Carried, t)
+				unfinished = append(unfinished, t.ID)
+			}
+		}
+
+		closing := project.Sprints[index]
+		if len(unfinished) > 0 {
+			var next *Sprint
+			sprints := tm.ListSprints(projectID)
+			for j, sprint := range sprints {
+				if sprint.ID != sprintID && !sprint.Closed && !sprint.Start.Before(closing.Start) {
+					next = &sprints[j]
+					break
+				}
+			}
+			if next == nil {
+				length := closing.End.Sub(closing.Start)
+				start := closing.End.AddDate(0, 0, 1)
+				created := Sprint{Name: nextSprintName(closing.Name), Start: start, End: start.Add(length)}
+				id, err := tm.AddSprint(projectID, created)
+				if err != nil {
+					return SprintSummary{}, err
+				}
+				created.ID = id
+				next = &created
+			}
+			summary.Next = *next
+			for j := range project.Tasks {
+				if project.Tasks[j].SprintID == sprintID && !project.Tasks[j].Complete {
+					project.Tasks[j].SprintID = next.ID
+				}
+			}
+		}
+
+		closedAt := now
+		project.Sprints[index].Closed = true
+		project.Sprints[index].ClosedAt = &closedAt
+		project.Sprints[index].CarriedOver = unfinished
+		summary.Sprint = project.Sprints[index]
+		return summary, nil
+	}
+	return SprintSummary{}, fmt.Errorf("project %d not found", projectID)
+}
@@ -7,44 +7,76 @@ import (
 
 // Project represents a project with tasks.
 type Project struct {
-	ID          int
-	Name        string
-	Description string
-	Tasks       []Task
-	CreatedAt   time.Time
+	ID           int
+	UID          string // Globally unique, see NewUID; ID is the short number shown
+	Name         string
+	Description  string
+	Tasks        []Task
+	Workflow     []WorkflowStatus
+	EstimateUnit string // UnitHours or UnitPoints
+	Sprints      []Sprint
+	CreatedAt    time.Time
 }
 
 // Task represents a task with subtasks.
 type Task struct {
 	ID          int
+	UID         string
 	Title       string
 	Description string
-	AssignedTo  string // New field
-	Complete    bool
+	AssignedTo  string // Comma-separated handles, see Assignees
+	Status      string
+	Complete    bool // Derived from Status for compatibility
 	Subtasks    []Subtask
 	CreatedAt   time.Time
 	CompletedAt *time.Time
+	StartedAt   *time.Time // When the task first entered an in-progress status
+	Start       *time.Time
+	Due         *time.Time
+	DependsOn   []int // IDs of tasks in the same project
+	TimeEntries []TimeEntry
+	Estimate    float64 // In the project's estimate unit
+	SprintID    int     // 0 while the task is in the backlog
+	Comments    []Comment
 }
 
 // Subtask represents a subtask.
 type Subtask struct {
 	ID          int
+	UID         string
 	Title       string
 	Description string
-	AssignedTo  string // New field
+	AssignedTo  string // Comma-separated handles, see Assignees
 	Complete    bool
 	CreatedAt   time.Time
 	CompletedAt *time.Time
+	TimeEntries []TimeEntry
+	Estimate    float64
+	Comments    []Comment
 }
 
 // TaskManager manages a list of projects, tasks, and subtasks.
 type TaskManager struct {
-	projects      []Project
-	tasks         []Task
-	subtasks      []Subtask
-	nextProjectID int
-	nextTaskID    int
-	nextSubID     int
+	projects       []Project
+	tasks          []Task
+	subtasks       []Subtask
+	nextProjectID  int
+	nextTaskID     int
+	nextSubID      int
+	views          []View
+	people         []Person
+	currentUser    string
+	listeners      []ChangeListener
+	opListeners    []OpListener
+	subscribers    map[int]Subscriber
+	nextSubscriber int
+	bus            *Bus
+	hook           Hook
+	vetoListeners  []VetoListener
+	replacing      bool // Set while ReplaceState runs, see SetHook
+	tracking       int  // Depth of the mutations in progress, see track
+	revision       int  // Number of changes made, see Revision
+	savedRevision  int
 }
 
 // NewTaskManager creates a new TaskManager.
@@ -56,20 +88,30 @@ func NewTaskManager() *TaskManager {
 		nextProjectID: 1,
 		nextTaskID:    1,
 		nextSubID:     1,
+		bus:           &Bus{},
 	}
 }
 
 // AddProject adds a new project to the TaskManager.
 func (tm *TaskManager) AddProject(project Project) {
+	defer tm.track(nil, scope{tm.nextProjectID})()
 	project.ID = tm.getNextProjectID()
+	if project.UID == "" {
+		project.UID = NewUID(time.Now())
+	}
 	tm.projects = append(tm.projects, project)
 }
 
 // AddTask adds a new task to a specific project.
 func (tm *TaskManager) AddTask(projectID int, task Task) {
+	defer tm.track(nil, scope{projectID})()
 	for i, project := range tm.projects {
 		if project.ID == projectID {
 			task.ID = tm.getNextTaskID()
+			if task.UID == "" {
+				task.UID = NewUID(time.Now())
+			}
+			normalizeStatus(projectWorkflow(project), &task)
 			tm.projects[i].Tasks = append(tm.projects[i].Tasks, task)
 			break
 		}
@@ -78,11 +120,15 @@ func (tm *TaskManager) AddTask(projectID int, task Task) {
 
 // AddSubtask adds a new subtask to a specific task within a project.
 func (tm *TaskManager) AddSubtask(projectID int, taskID int, subtask Subtask) {
+	defer tm.track(nil, scope{projectID})()
 	for i, project := range tm.projects {
 		if project.ID == projectID {
 			for j, taskItem := range project.Tasks {
 				if taskItem.ID == taskID {
 					subtask.ID = tm.getNextSubtaskID()
+					if subtask.UID == "" {
+						subtask.UID = NewUID(time.Now())
+					}
 					tm.projects[i].Tasks[j].Subtasks = append(tm.projects[i].Tasks[j].Subtasks, subtask)
 					break
 				}
@@ -92,8 +138,11 @@ func (tm *TaskManager) AddSubtask(projectID int, taskID int, subtask Subtask) {
 	}
 }
 
-// AssignTaskTo assigns a task to someone.
+// AssignTaskTo assigns a task to someone, or to several people separated by
+// commas. Registered people may be given by display name or email.
 func (tm *TaskManager) AssignTaskTo(projectID int, taskID int, assignedTo string) {
+	defer tm.track(nil, scope{projectID})()
+	assignedTo = tm.ResolveAssignees(assignedTo)
 	for i := range tm.projects {
 		if tm.projects[i].ID == projectID {
 			for j := range tm.projects[i].Tasks {
@@ -106,8 +155,10 @@ func (tm *TaskManager) AssignTaskTo(projectID int, taskID int, assignedTo string
 	}
 }
 
-// AssignSubtaskTo assigns a subtask to someone.
+// AssignSubtaskTo assigns a subtask to someone, or to several people.
 func (tm *TaskManager) AssignSubtaskTo(projectID int, taskID int, subtaskID int, assignedTo string) {
+	defer tm.track(nil, scope{projectID})()
+	assignedTo = tm.ResolveAssignees(assignedTo)
 	for i := range tm.projects {
 		if tm.projects[i].ID == projectID {
 			for j := range tm.projects[i].Tasks {
@@ -150,16 +201,16 @@ func (tm *TaskManager) ListSubtasks(projectID, taskID int) []Subtask {
 
 // ToggleComplete toggles the completion status of a task by ID.
 func (tm *TaskManager) ToggleComplete(projectID int, taskID int) {
+	defer tm.track(nil, scope{projectID})()
 	for i := range tm.projects {
 		if tm.projects[i].ID == projectID {
 			for j := range tm.projects[i].Tasks {
 				if tm.projects[i].Tasks[j].ID == taskID {
-					tm.projects[i].Tasks[j].Complete = !tm.projects[i].Tasks[j].Complete
+					statuses := projectWorkflow(tm.projects[i])
 					if tm.projects[i].Tasks[j].Complete {
-						now := time.Now()
-						tm.projects[i].Tasks[j].CompletedAt = &now
+						setStatus(statuses, &tm.projects[i].Tasks[j], 0)
 					} else {
-						tm.projects[i].Tasks[j].CompletedAt = nil
+						setStatus(statuses, &tm.projects[i].Tasks[j], len(statuses)-1)
 					}
 					break
 				}
@@ -171,6 +222,7 @@ func (tm *TaskManager) ToggleComplete(projectID int, taskID int) {
 
 // ToggleSubtaskComplete toggles the completion status of a subtask by ID.
 func (tm *TaskManager) ToggleSubtaskComplete(projectID int, taskID int, subtaskID int) {
+	defer tm.track(nil, scope{projectID})()
 	for i := range tm.projects {
 		if tm.projects[i].ID == projectID {
 			for j := range tm.projects[i].Tasks {
@@ -197,6 +249,7 @@ func (tm *TaskManager) ToggleSubtaskComplete(projectID int, taskID int, subtaskI
 
 // RemoveTask removes a task by ID from a specific project.
 func (tm *TaskManager) RemoveTask(projectID int, taskID int) {
+	defer tm.track(nil, scope{projectID})()
 	for i := range tm.projects {
 		if tm.projects[i].ID == projectID {
 			tasks := &tm.projects[i].Tasks
@@ -212,6 +265,7 @@ func (tm *TaskManager) RemoveTask(projectID int, taskID int) {
 
 // RemoveSubtask removes a subtask by ID from a specific task in a project.
 func (tm *TaskManager) RemoveSubtask(projectID int, taskID int, subtaskID int) {
+	defer tm.track(nil, scope{projectID})()
 	for i := range tm.projects {
 		if tm.projects[i].ID == projectID {
 			for j := range tm.projects[i].Tasks {
@@ -237,14 +291,20 @@ func (tm *TaskManager) ListProjects() []Project {
 // SetProjects sets the projects and updates the next IDs accordingly.
 func (tm *TaskManager) SetProjects(projects []Project) {
 	tm.projects = projects
+	// Give entities saved before UIDs existed the UIDs every copy gives them
+	AssignUIDs(projects)
 	// Update nextProjectID, nextTaskID, and nextSubID based on existing IDs
 	maxProjectID := 0
 	maxTaskID := 0
 	maxSubID := 0
-	for _, project := range projects {
+	for i, project := range projects {
 		if project.ID > maxProjectID {
 			maxProjectID = project.ID
 		}
+		// Give tasks saved before workflows existed a status
+		for j := range project.Tasks {
+			normalizeStatus(projectWorkflow(project), &projects[i].Tasks[j])
+		}
 		for _, task := range project.Tasks {
 			if task.ID > maxTaskID {
 				maxTaskID = task.ID
@@ -261,8 +321,20 @@ func (tm *TaskManager) SetProjects(projects []Project) {
 	tm.nextSubID = maxSubID + 1
 }
 
+// ReplaceState replaces the projects, views and people with another version
+// of them, e.g. one received from another copy of the data, as one change.
+func (tm *TaskManager) ReplaceState(projects []Project, views []View, people []Person) {
+	tm.replacing = true
+	defer func() { tm.replacing = false }()
+	defer tm.track(nil, allProjects)()
+	tm.SetProjects(projects)
+	tm.SetViews(views)
+	tm.SetPeople(people)
+}
+
 // EditTask updates the title and description of a task
 func (tm *TaskManager) EditTask(projectID, taskID int, newTitle, newDescription string) {
+	defer tm.track(nil, scope{tm.projects[projectID].ID})()
 	// Validate project existence
 	project := tm.projects[projectID]
 	// Locate the subtask by ID
@@ -275,8 +347,73 @@ func (tm *TaskManager) EditTask(projectID, taskID int, newTitle, newDescription
 	}
 }
 
+// SetTaskText sets the title and description of a task by ID.
+func (tm *TaskManager) SetTaskText(projectID, taskID int, title, description string) (err error) {
+	defer tm.track(&err, scope{projectID})()
+	t, err := tm.findTask(projectID, taskID)
+	if err != nil {
+		return err
+	}
+	t.Title, t.Description = title, description
+	return nil
+}
+
+// SetSubtaskText sets the title and description of a subtask by ID.
+func (tm *TaskManager) SetSubtaskText(projectID, taskID, subtaskID int, title, description string) (err error) {
+	defer tm.track(&err, scope{projectID})()
+	s, err := tm.findSubtask(projectID, taskID, subtaskID)
+	if err != nil {
+		return err
+	}
+	s.Title, s.Description = title, description
+	return nil
+}
+
+// SetSubtaskComplete marks a subtask complete or open.
+func (tm *TaskManager) SetSubtaskComplete(projectID, taskID, subtaskID int, complete bool) (err error) {
+	defer tm.track(&err, scope{projectID})()
+	s, err := tm.findSubtask(projectID, taskID, subtaskID)
+	if err != nil {
+		return err
+	}
+	if s.Complete != complete {
+		tm.ToggleSubtaskComplete(projectID, taskID, subtaskID)
+	}
+	return nil
+}
+
+// findTask returns the task of a project by ID.
+func (tm *TaskManager) findTask(projectID, taskID int) (*Task, error) {
+	for i := range tm.projects {
+		if tm.projects[i].ID == projectID {
+			for j := range tm.projects[i].Tasks {
+				if tm.projects[i].Tasks[j].ID == taskID {
+					return &tm.projects[i].Tasks[j], nil
+				}
+			}
+			return nil, fmt.Errorf("task %d not found", taskID)
+		}
+	}
+	return nil, fmt.Errorf("project %d not found", projectID)
+}
+
+// findSubtask returns the subtask of a task by ID.
+func (tm *TaskManager) findSubtask(projectID, taskID, subtaskID int) (*Subtask, error) {
+	t, err := tm.findTask(projectID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range t.Subtasks {
+		if t.Subtasks[i].ID == subtaskID {
+			return &t.Subtasks[i], nil
+		}
+	}
+	return nil, fmt.Errorf("subtask %d not found", subtaskID)
+}
+
 // EditSubtask updates the title and description of a subtask
 func (tm *TaskManager) EditSubtask(projectID int, taskID int, subtaskID int, newTitle string, newDescription string) {
+	defer tm.track(nil, scope{tm.projects[projectID].ID})()
 	// Validate project existence
 	project := tm.projects[projectID]
 
@@ -295,6 +432,7 @@ func (tm *TaskManager) EditSubtask(projectID int, taskID int, subtaskID int, new
 
 // EditProject edits the name and description of a project by ID.
 func (tm *TaskManager) EditProject(projectID int, newName string, newDescription string) {
+	defer tm.track(nil, scope{projectID})()
 	for i := range tm.projects {
 		if tm.projects[i].ID == projectID {
 			tm.projects[i].Name = newName
@@ -306,6 +444,7 @@ func (tm *TaskManager) EditProject(projectID int, newName string, newDescription
 
 // RemoveProject removes a project by ID.
 func (tm *TaskManager) RemoveProject(projectID int) {
+	defer tm.track(nil, scope{projectID})()
 	for i, project := range tm.projects {
 		if project.ID == projectID {
 			tm.projects = append(tm.projects[:i], tm.projects[i+1:]...)
@@ -316,17 +455,23 @@ func (tm *TaskManager) RemoveProject(projectID int) {
 
 // getNextProjectID generates the next project ID.
 func (tm *TaskManager) getNextProjectID() int {
-	return tm.nextProjectID
+	id := tm.nextProjectID
+	tm.nextProjectID++
+	return id
 }
 
 // getNextTaskID generates the next task ID.
 func (tm *TaskManager) getNextTaskID() int {
-	return tm.nextTaskID
+	id := tm.nextTaskID
+	tm.nextTaskID++
+	return id
 }
 
 // getNextSubtaskID generates the next subtask ID.
 func (tm *TaskManager) getNextSubtaskID() int {
-	return tm.nextSubID
+	id := tm.nextSubID
+	tm.nextSubID++
+	return id
 }
 func (tm *TaskManager) GetProjectByIndex(index int) (*Project, error) {
 	projects := tm.ListProjects()
@@ -0,0 +1,48 @@
+package task
+
+import "testing"
+
+func TestNewEntitiesGetDistinctIDs(t *testing.T) {
+	tm := NewTaskManager()
+	tm.AddProject(Project{Name: "Website"})
+	tm.AddProject(Project{Name: "Blog"})
+	tm.AddTask(1, Task{Title: "Design"})
+	tm.AddTask(1, Task{Title: "Build"})
+	tm.AddTask(2, Task{Title: "Write"})
+	tm.AddSubtask(1, 1, Subtask{Title: "Mockups"})
+	tm.AddSubtask(1, 2, Subtask{Title: "Pages"})
+
+	projects := tm.ListProjects()
+	if len(projects) != 2 || projects[0].ID != 1 || projects[1].ID != 2 {
+		t.Fatalf("projects = %+v, want IDs 1 and 2", projects)
+	}
+	// Task and subtask IDs are numbered across projects and tasks
+	var taskIDs, subtaskIDs []int
+	for _, p := range projects {
+		for _, task := range p.Tasks {
+			taskIDs = append(taskIDs, task.ID)
+			for _, s := range task.Subtasks {
+				subtaskIDs = append(subtaskIDs, s.ID)
+			}
+		}
+	}
+	if len(taskIDs) != 3 || taskIDs[0] != 1 || taskIDs[1] != 2 || taskIDs[2] != 3 {
+		t.Errorf("task IDs = %v, want [1 2 3]", taskIDs)
+	}
+	if len(subtaskIDs) != 2 || subtaskIDs[0] != 1 || subtaskIDs[1] != 2 {
+		t.Errorf("subtask IDs = %v, want [1 2]", subtaskIDs)
+	}
+}
+
+func TestIDsContinueAfterLoadedProjects(t *testing.T) {
+	tm := NewTaskManager()
+	tm.SetProjects([]Project{{ID: 4, Name: "Website", Tasks: []Task{{ID: 7, Subtasks: []Subtask{{ID: 9}}}}}})
+	tm.AddProject(Project{Name: "Blog"})
+	tm.AddTask(5, Task{Title: "Write"})
+	tm.AddSubtask(5, 8, Subtask{Title: "Outline"})
+
+	p := tm.ListProjects()[1]
+	if p.ID != 5 || len(p.Tasks) != 1 || p.Tasks[0].ID != 8 || len(p.Tasks[0].Subtasks) != 1 || p.Tasks[0].Subtasks[0].ID != 10 {
+		t.Errorf("new project = %+v, want IDs 5, 8 and 10", p)
+	}
+}
@@ -0,0 +1,191 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrTimerRunning is returned when starting a timer while another one runs.
+var ErrTimerRunning = errors.New("a timer is already running")
+
+// ErrNoTimer is returned when stopping a timer while none runs.
+var ErrNoTimer = errors.New("no timer is running")
+
+// TimeEntry is a period of work logged on a task or subtask.
+type TimeEntry struct {
+	Start time.Time
+	End   *time.Time // nil while the timer is running
+	Note  string
+}
+
+// Duration returns the length of the entry, counting a running entry up to now.
+func (e TimeEntry) Duration(now time.Time) time.Duration {
+	if e.End == nil {
+		return now.Sub(e.Start)
+	}
+	return e.End.Sub(e.Start)
+}
+
+// RunningTimer describes the time entry that is currently open.
+type RunningTimer struct {
+	ProjectID int
+	TaskID    int
+	SubtaskID int // 0 when the timer runs on the task itself
+	Title     string
+	Entry     TimeEntry
+}
+
+// entriesTime sums the durations of time entries.
+func entriesTime(entries []TimeEntry, now time.Time) time.Duration {
+	var total time.Duration
+	for _, entry := range entries {
+		total += entry.Duration(now)
+	}
+	return total
+}
+
+// TrackedTime returns the time logged on a subtask.
+func (s Subtask) TrackedTime(now time.Time) time.Duration {
+	return entriesTime(s.TimeEntries, now)
+}
+
+// TrackedTime returns the time logged on a task and its subtasks.
+func (t Task) TrackedTime(now time.Time) time.Duration {
+	total := entriesTime(t.TimeEntries, now)
+	for _, subtask := range t.Subtasks {
+		total += subtask.TrackedTime(now)
+	}
+	return total
+}
+
+// FormatDuration formats a duration as hours and minutes, e.g. "1h05m".
+func FormatDuration(d time.Duration) string {
+	minutes := int(d.Round(time.Minute).Minutes())
+	return fmt.Sprintf("%dh%02dm", minutes/60, minutes%60)
+}
+
+// RunningTimer returns the open time entry, if any.
+func (tm *TaskManager) RunningTimer() (RunningTimer, bool) {
+	for _, project := range tm.projects {
+		for _, t := range project.Tasks {
+			for _, entry := range t.TimeEntries {
+				if entry.End == nil {
+					return RunningTimer{ProjectID: project.ID, TaskID: t.ID, Title: t.Title, Entry: entry}, true
+				}
+			}
+			for _, subtask := range t.Subtasks {
+				for _, entry := range subtask.TimeEntries {
+					if entry.End == nil {
+						return RunningTimer{ProjectID: project.ID, TaskID: t.ID, SubtaskID: subtask.ID, Title: subtask.Title, Entry: entry}, true
+					}
+				}
+			}
+		}
+	}
+	return RunningTimer{}, false
+}
+
+// StartTimer opens a time entry on a task, or on one of its subtasks when
+// subtaskID is not 0. Only one timer may run at a time.
+func (tm *TaskManager) StartTimer(projectID, taskID, subtaskID int, note string) (err error) {
+	defer tm.track(&err, scope{projectID})()
+	if _, running := tm.RunningTimer(); running {
+		return ErrTimerRunning
+	}
+	entries, err := tm.timeEntries(projectID, taskID, subtaskID)
+	if err != nil {
+		return err
+	}
+	*entries = append(*entries, TimeEntry{Start: time.Now(), Note: note})
+	return nil
+}
+
+// StopTimer closes the running time entry and returns it.
+func (tm *TaskManager) StopTimer() (stopped RunningTimer, err error) {
+	timer, running := tm.RunningTimer()
+	defer tm.track(&err, scope{timer.ProjectID})()
+	if !running {
+		return RunningTimer{}, ErrNoTimer
+	}
+	entries, err := tm.timeEntries(timer.ProjectID, timer.TaskID, timer.SubtaskID)
+	if err != nil {
+		return RunningTimer{}, err
+	}
+	now := time.Now()
+	for i := range *entries {
+		if (*entries)[i].End == nil {
+			(*entries)[i].End = &now
+			timer.Entry = (*entries)[i]
+		}
+	}
+	return timer, nil
+}
+
+// timeEntries returns the time entries of a task, or of a subtask when subtaskID is not 0.
+func (tm *TaskManager) timeEntries(projectID, taskID, subtaskID int) (*[]TimeEntry, error) {
+	for i := range tm.projects {
+		if tm.projects[i].ID != projectID {
+			continue
+		}
+		for j := range tm.projects[i].Tasks {
+			t := &tm.projects[i].Tasks[j]
+			if t.ID != taskID {
+				continue
+			}
+			if subtaskID == 0 {
+				return &t.TimeEntries, nil
+			}
+			for k := range t.Subtasks {
+				if t.Subtasks[k].ID == subtaskID {
+					return &t.Subtasks[k].TimeEntries, nil
+				}
+			}
+			return nil, fmt.Errorf("subtask %d not found", subtaskID)
+		}
+		return nil, fmt.Errorf("task %d not found", taskID)
+	}
+	return nil, fmt.Errorf("project %d not found", projectID)
+}
+
+// ProjectTime returns the time logged on all tasks of a project.
+func (tm *TaskManager) ProjectTime(projectID int, now time.Time) time.Duration {
+	var total time.Duration
+	for _, t := range tm.ListTasks(projectID) {
+		total += t.TrackedTime(now)
+	}
+	return total
+}
+
+// AssigneeTime returns the time logged per assignee. Time on a subtask counts
+// for the subtask's assignees, falling back to the assignees of its task, and
+// is shared evenly when there are several.
+func (tm *TaskManager) AssigneeTime(now time.Time) map[string]time.Duration {
+	totals := map[string]time.Duration{}
+	add := func(assignedTo string, d time.Duration) {
+		assignees := SplitAssignees(assignedTo)
+		if len(assignees) == 0 {
+			totals[""] += d
+		}
+		for _, assignee := range assignees {
+			totals[assignee] += d / time.Duration(len(assignees))
+		}
+	}
+	for _, project := range tm.projects {
+		for _, t := range project.Tasks {
+			if d := entriesTime(t.TimeEntries, now); d > 0 {
+				add(t.AssignedTo, d)
+			}
+			for _, subtask := range t.Subtasks {
+				assignedTo := subtask.AssignedTo
+				if assignedTo == "" {
+					assignedTo = t.AssignedTo
+				}
+				if d := subtask.TrackedTime(now); d > 0 {
+					add(assignedTo, d)
+				}
+			}
+		}
+	}
+	return totals
+}
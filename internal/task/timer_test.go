@@ -0,0 +1,32 @@
+package task
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestVetoedTimerIsNotStarted(t *testing.T) {
+	tm, _ := newTestManager()
+	veto := errors.New("no timers on Fridays")
+	tm.SetHook(func(op Op, old json.RawMessage) (json.RawMessage, error) { return nil, veto })
+
+	if err := tm.StartTimer(1, 1, 0, ""); !errors.Is(err, veto) {
+		t.Fatalf("StartTimer returned %v, want the veto", err)
+	}
+	if timer, running := tm.RunningTimer(); running {
+		t.Fatalf("timer running on %q after the veto", timer.Title)
+	}
+
+	tm.SetHook(nil)
+	if err := tm.StartTimer(1, 1, 0, ""); err != nil {
+		t.Fatal(err)
+	}
+	tm.SetHook(func(op Op, old json.RawMessage) (json.RawMessage, error) { return nil, veto })
+	if _, err := tm.StopTimer(); !errors.Is(err, veto) {
+		t.Fatalf("StopTimer returned %v, want the veto", err)
+	}
+	if _, running := tm.RunningTimer(); !running {
+		t.Error("timer stopped despite the veto")
+	}
+}
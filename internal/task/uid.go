@@ -0,0 +1,86 @@
+package task
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// crockford is the base32 alphabet of ULIDs, without I, L, O and U.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewUID returns a new globally unique ID for a project, task or subtask:
+// a ULID, 26 characters sorting by creation time, e.g.
+// "01J9ZK6X3W8V4T2R0M5N7P9Q1S". Unlike the short IDs numbering entities in
+// one copy of the data, UIDs do not collide between copies edited apart, and
+// they never change.
+func NewUID(now time.Time) string {
+	var entropy [10]byte
+	if _, err := rand.Read(entropy[:]); err != nil {
+		panic(fmt.Sprintf("task: cannot generate a UID: %v", err))
+	}
+	return encodeULID(now, entropy)
+}
+
+// legacyUID returns the UID of an entity saved before entities had UIDs,
+// derived from its kind, short IDs and creation time so that every copy of
+// the same data migrates it to the same UID.
+func legacyUID(createdAt time.Time, entity string, ids ...int) string {
+	key := make([]string, len(ids))
+	for i, id := range ids {
+		key[i] = strconv.Itoa(id)
+	}
+	sum := sha256.Sum256([]byte("termile:" + entity + ":" + strings.Join(key, "/")))
+	var entropy [10]byte
+	copy(entropy[:], sum[:])
+	if createdAt.Before(time.UnixMilli(0)) {
+		createdAt = time.UnixMilli(0)
+	}
+	return encodeULID(createdAt, entropy)
+}
+
+// encodeULID encodes a millisecond timestamp and 80 bits of entropy.
+func encodeULID(t time.Time, entropy [10]byte) string {
+	var data [16]byte
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(t.UnixMilli()))
+	copy(data[:6], ms[2:])
+	copy(data[6:], entropy[:])
+
+	// 128 bits in 26 characters of 5 bits, the first one holding 3 bits
+	var uid [26]byte
+	hi, lo := binary.BigEndian.Uint64(data[:8]), binary.BigEndian.Uint64(data[8:])
+	for i := 25; i >= 0; i-- {
+		uid[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(uid[:])
+}
+
+// AssignUIDs gives a UID to the projects, tasks and subtasks saved without
+// one. Copies of the same data assign the same UIDs.
+func AssignUIDs(projects []Project) {
+	for i := range projects {
+		p := &projects[i]
+		if p.UID == "" {
+			p.UID = legacyUID(p.CreatedAt, EntityProject, p.ID)
+		}
+		for j := range p.Tasks {
+			t := &p.Tasks[j]
+			if t.UID == "" {
+				t.UID = legacyUID(t.CreatedAt, EntityTask, p.ID, t.ID)
+			}
+			for k := range t.Subtasks {
+				s := &t.Subtasks[k]
+				if s.UID == "" {
+					s.UID = legacyUID(s.CreatedAt, EntitySubtask, p.ID, t.ID, s.ID)
+				}
+			}
+		}
+	}
+}
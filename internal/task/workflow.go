@@ -0,0 +1,201 @@
+package task
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WorkflowStatus is one column of a project's workflow. The last status of a
+// workflow is the done status: tasks in it are complete.
+type WorkflowStatus struct {
+	Name     string
+	WIPLimit int // 0 means unlimited
+}
+
+// DefaultWorkflow returns the workflow used by projects that do not define one.
+func DefaultWorkflow() []WorkflowStatus {
+	return []WorkflowStatus{
+		{Name: "Todo"},
+		{Name: "Doing"},
+		{Name: "Review"},
+		{Name: "Done"},
+	}
+}
+
+// ParseWorkflow parses a workflow such as "Todo, Doing:3, Review:2, Done",
+// where the optional number after a colon is the WIP limit of that status.
+func ParseWorkflow(spec string) ([]WorkflowStatus, error) {
+	var statuses []WorkflowStatus
+	seen := map[string]bool{}
+	for _, part := range strings.Split(spec, ",") {
+		name, limit, hasLimit := strings.Cut(strings.TrimSpace(part), ":")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, fmt.Errorf("empty status name")
+		}
+		if seen[strings.ToLower(name)] {
+			return nil, fmt.Errorf("duplicate status %q", name)
+		}
+		seen[strings.ToLower(name)] = true
+		status := WorkflowStatus{Name: name}
+		if hasLimit {
+			n, err := strconv.Atoi(strings.TrimSpace(limit))
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid WIP limit %q for status %q", limit, name)
+			}
+			status.WIPLimit = n
+		}
+		statuses = append(statuses, status)
+	}
+	if len(statuses) < 2 {
+		return nil, fmt.Errorf("a workflow needs at least two statuses")
+	}
+	return statuses, nil
+}
+
+// FormatWorkflow formats a workflow in the form accepted by ParseWorkflow.
+func FormatWorkflow(statuses []WorkflowStatus) string {
+	parts := make([]string, len(statuses))
+	for i, status := range statuses {
+		parts[i] = status.Name
+		if status.WIPLimit > 0 {
+			parts[i] += ":" + strconv.Itoa(status.WIPLimit)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// statusIndex returns the position of a status in a workflow, or -1.
+func statusIndex(statuses []WorkflowStatus, name string) int {
+	for i, status := range statuses {
+		if strings.EqualFold(status.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// projectWorkflow returns the workflow of a project, falling back to the default.
+func projectWorkflow(project Project) []WorkflowStatus {
+	if len(project.Workflow) >= 2 {
+		return project.Workflow
+	}
+	return DefaultWorkflow()
+}
+
+// normalizeStatus makes a task's status agree with its workflow and completion flag.
+func normalizeStatus(statuses []WorkflowStatus, t *Task) {
+	last := len(statuses) - 1
+	index := statusIndex(statuses, t.Status)
+	switch {
+	case t.Complete:
+		t.Status = statuses[last].Name
+	case index == -1 || index == last:
+		t.Status = statuses[0].Name
+	default:
+		t.Status = statuses[index].Name
+	}
+}
+
+// setStatus moves a task to the status at index and derives its completion.
+// Entering an in-progress status for the first time starts the task's cycle.
+func setStatus(statuses []WorkflowStatus, t *Task, index int) {
+	t.Status = statuses[index].Name
+	done := index == len(statuses)-1
+	if index > 0 && !done && t.StartedAt == nil {
+		now := time.Now()
+		t.StartedAt = &now
+	}
+	if done && !t.Complete {
+		now := time.Now()
+		t.CompletedAt = &now
+	} else if !done {
+		t.CompletedAt = nil
+	}
+	t.Complete = done
+}
+
+// Workflow returns the workflow of a project.
+func (tm *TaskManager) Workflow(projectID int) []WorkflowStatus {
+	for _, project := range tm.projects {
+		if project.ID == projectID {
+			return projectWorkflow(project)
+		}
+	}
+	return DefaultWorkflow()
+}
+
+// SetWorkflow replaces the workflow of a project. Tasks whose status no
+// longer exists are moved back to the first status.
+func (tm *TaskManager) SetWorkflow(projectID int, statuses []WorkflowStatus) (err error) {
+	defer tm.track(&err, scope{projectID})()
+	if len(statuses) < 2 {
+		return fmt.Errorf("a workflow needs at least two statuses")
+	}
+	for i := range tm.projects {
+		if tm.projects[i].ID == projectID {
+			tm.projects[i].Workflow = statuses
+			for j := range tm.projects[i].Tasks {
+				normalizeStatus(statuses, &tm.projects[i].Tasks[j])
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("project %d not found", projectID)
+}
+
+// SetTaskStatus moves a task to the named status of its project's workflow.
+func (tm *TaskManager) SetTaskStatus(projectID, taskID int, status string) (err error) {
+	defer tm.track(&err, scope{projectID})()
+	for i := range tm.projects {
+		if tm.projects[i].ID == projectID {
+			statuses := projectWorkflow(tm.projects[i])
+			index := statusIndex(statuses, status)
+			if index == -1 {
+				return fmt.Errorf("unknown status %q", status)
+			}
+			for j := range tm.projects[i].Tasks {
+				if tm.projects[i].Tasks[j].ID == taskID {
+					setStatus(statuses, &tm.projects[i].Tasks[j], index)
+					return nil
+				}
+			}
+			return fmt.Errorf("task %d not found", taskID)
+		}
+	}
+	return fmt.Errorf("project %d not found", projectID)
+}
+
+// MoveTask moves a task delta columns along its project's workflow.
+func (tm *TaskManager) MoveTask(projectID, taskID, delta int) (err error) {
+	defer tm.track(&err, scope{projectID})()
+	for i := range tm.projects {
+		if tm.projects[i].ID == projectID {
+			statuses := projectWorkflow(tm.projects[i])
+			for j := range tm.projects[i].Tasks {
+				t := &tm.projects[i].Tasks[j]
+				if t.ID == taskID {
+					index := statusIndex(statuses, t.Status) + delta
+					if index < 0 || index >= len(statuses) {
+						return fmt.Errorf("task %d cannot move further", taskID)
+					}
+					setStatus(statuses, t, index)
+					return nil
+				}
+			}
+			return fmt.Errorf("task %d not found", taskID)
+		}
+	}
+	return fmt.Errorf("project %d not found", projectID)
+}
+
+// StatusCounts returns the number of tasks in each status of a project.
+func (tm *TaskManager) StatusCounts(projectID int) map[string]int {
+	counts := map[string]int{}
+	for _, t := range tm.ListTasks(projectID) {
+		counts[t.Status]++
+	}
+	return counts
+}
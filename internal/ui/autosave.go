@@ -0,0 +1,60 @@
+package ui
+
+import (
+	"Termile/internal/task"
+	"fmt"
+
+	"github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+)
+
+// updateSaveStatus shows why a hook vetoed the last change, or else whether
+// the changes are saved, waiting to be saved, or could not be saved
+func updateSaveStatus(statusLine *widgets.Paragraph, tm *task.TaskManager, saveErr, vetoErr error) {
+	switch {
+	case vetoErr != nil:
+		statusLine.Text = vetoErr.Error()
+		statusLine.TextStyle = termui.NewStyle(termui.ColorRed)
+	case saveErr != nil:
+		statusLine.Text = "save failed: " + saveErr.Error()
+		statusLine.TextStyle = termui.NewStyle(termui.ColorRed)
+	case tm.Dirty():
+		statusLine.Text = "unsaved"
+		statusLine.TextStyle = termui.NewStyle(termui.ColorYellow)
+	default:
+		statusLine.Text = "saved"
+		statusLine.TextStyle = termui.NewStyle(termui.ColorGreen)
+	}
+}
+
+// confirmQuit asks whether to quit after the last save failed. r retries the
+// save, q quits without saving and any other key keeps termile open. It
+// returns whether to quit and the error of the retried save, if any
+func confirmQuit(uiEvents <-chan termui.Event, save func() error, saveErr error) (bool, error) {
+	prompt := widgets.NewParagraph()
+	prompt.Title = "Unsaved changes"
+	prompt.WrapText = true
+	prompt.BorderStyle = termui.NewStyle(termui.ColorRed)
+
+	for {
+		prompt.Text = fmt.Sprintf("The last save failed: %v\n\nr: retry saving and quit  q: quit anyway  any other key: stay", saveErr)
+		termWidth, termHeight := termui.TerminalDimensions()
+		prompt.SetRect(termWidth/6, termHeight/3, 5*termWidth/6, 2*termHeight/3)
+		termui.Render(prompt)
+
+		e := <-uiEvents
+		if e.Type != termui.KeyboardEvent {
+			continue
+		}
+		switch e.ID {
+		case "q", "<C-q>", "<C-c>":
+			return true, saveErr
+		case "r":
+			if saveErr = save(); saveErr == nil {
+				return true, nil
+			}
+		default:
+			return false, saveErr
+		}
+	}
+}
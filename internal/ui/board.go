@@ -0,0 +1,161 @@
+package ui
+
+import (
+	"Termile/internal/task"
+	"fmt"
+
+	"github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+)
+
+// boardColumns groups the tasks of a project by workflow status
+func boardColumns(tm *task.TaskManager, projectID int, statuses []task.WorkflowStatus) [][]task.Task {
+	columns := make([][]task.Task, len(statuses))
+	for _, t := range tm.ListTasks(projectID) {
+		for i, status := range statuses {
+			if status.Name == t.Status {
+				columns[i] = append(columns[i], t)
+				break
+			}
+		}
+	}
+	return columns
+}
+
+// wipWarning describes the statuses of a workflow whose WIP limit is exceeded
+func wipWarning(statuses []task.WorkflowStatus, columns [][]task.Task) string {
+	warning := ""
+	for i, status := range statuses {
+		if status.WIPLimit > 0 && len(columns[i]) > status.WIPLimit {
+			warning += fmt.Sprintf(" %s (%d/%d)", status.Name, len(columns[i]), status.WIPLimit)
+		}
+	}
+	if warning == "" {
+		return ""
+	}
+	return "⚠ WIP limit exceeded:" + warning
+}
+
+// showBoard displays the kanban board of a project until it is closed with q or Escape
+func showBoard(tm *task.TaskManager, uiEvents <-chan termui.Event, projectID int) {
+	projectName := ""
+	for _, project := range tm.ListProjects() {
+		if project.ID == projectID {
+			projectName = project.Name
+		}
+	}
+	if projectName == "" {
+		return
+	}
+
+	selectedColumn := 0
+	selectedCard := 0
+	message := ""
+
+	for {
+		statuses := tm.Workflow(projectID)
+		columns := boardColumns(tm, projectID, statuses)
+		if selectedColumn >= len(columns) {
+			selectedColumn = len(columns) - 1
+		}
+		if selectedCard >= len(columns[selectedColumn]) {
+			selectedCard = len(columns[selectedColumn]) - 1
+		}
+		if selectedCard < 0 {
+			selectedCard = 0
+		}
+
+		// Render the header and one list per status
+		termui.Clear()
+		termWidth, termHeight := termui.TerminalDimensions()
+		header := widgets.NewParagraph()
+		header.Title = fmt.Sprintf("Board: %s", projectName)
+		header.Text = "h/l: column  j/k: card  H/L: move card  w: edit workflow  q: close"
+		if warning := wipWarning(statuses, columns); warning != "" {
+			header.Text = warning
+			header.TextStyle = termui.NewStyle(termui.ColorRed, termui.ColorClear, termui.ModifierBold)
+		}
+		if message != "" {
+			header.Text = message
+		}
+		header.SetRect(0, 0, termWidth, 3)
+		termui.Render(header)
+
+		columnWidth := termWidth / len(columns)
+		for i, status := range statuses {
+			list := widgets.NewList()
+			list.Title = fmt.Sprintf("%s (%d)", status.Name, len(columns[i]))
+			if status.WIPLimit > 0 {
+				list.Title = fmt.Sprintf("%s (%d/%d)", status.Name, len(columns[i]), status.WIPLimit)
+				if len(columns[i]) > status.WIPLimit {
+					list.TitleStyle = termui.NewStyle(termui.ColorRed, termui.ColorClear, termui.ModifierBold)
+				}
+			}
+			for _, t := range columns[i] {
+				list.Rows = append(list.Rows, fmt.Sprintf("%d. %s", t.ID, t.Title))
+			}
+			// Only the selected column highlights a card
+			list.SelectedRowStyle = list.TextStyle
+			if i == selectedColumn {
+				list.BorderStyle = termui.NewStyle(termui.ColorYellow)
+				list.SelectedRowStyle = termui.NewStyle(termui.ColorBlack, termui.ColorYellow)
+				list.SelectedRow = selectedCard
+			}
+			list.SetRect(i*columnWidth, 3, (i+1)*columnWidth, termHeight)
+			termui.Render(list)
+		}
+
+		e := <-uiEvents
+		message = ""
+		switch e.ID {
+		case "q", "<Escape>", "<C-w>":
+			return
+		case "h", "<Left>":
+			if selectedColumn > 0 {
+				selectedColumn--
+				selectedCard = 0
+			}
+		case "l", "<Right>":
+			if selectedColumn < len(columns)-1 {
+				selectedColumn++
+				selectedCard = 0
+			}
+		case "j", "<Down>":
+			selectedCard++
+		case "k", "<Up>":
+			selectedCard--
+		case "H", "L":
+			if len(columns[selectedColumn]) == 0 {
+				break
+			}
+			delta := -1
+			if e.ID == "L" {
+				delta = 1
+			}
+			card := columns[selectedColumn][selectedCard]
+			if err := tm.MoveTask(projectID, card.ID, delta); err != nil {
+				message = err.Error()
+				break
+			}
+			// Follow the card into its new column
+			selectedColumn += delta
+			for i, t := range boardColumns(tm, projectID, statuses)[selectedColumn] {
+				if t.ID == card.ID {
+					selectedCard = i
+				}
+			}
+		case "w":
+			spec, ok := promptInput(uiEvents, "Workflow (e.g. Todo, Doing:3, Review:2, Done)", task.FormatWorkflow(statuses))
+			if !ok {
+				break
+			}
+			workflow, err := task.ParseWorkflow(spec)
+			if err == nil {
+				err = tm.SetWorkflow(projectID, workflow)
+			}
+			if err != nil {
+				message = fmt.Sprintf("Invalid workflow: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package ui
+
+import (
+	"Termile/internal/task"
+	"fmt"
+	"time"
+
+	"github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+)
+
+// monthGrid returns the first day shown in the month grid of date, which is
+// the Monday on or before the first of the month
+func monthGrid(date time.Time) time.Time {
+	return task.StartOfWeek(time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, date.Location()))
+}
+
+// showCalendar displays a month calendar of due tasks until it is closed with q or Escape
+func showCalendar(tm *task.TaskManager, uiEvents <-chan termui.Event) {
+	now := time.Now()
+	selected := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	for {
+		start := monthGrid(selected)
+
+		// Count the tasks due on each day of the grid
+		table := widgets.NewTable()
+		table.Title = selected.Format("January 2006")
+		table.TextAlignment = termui.AlignCenter
+		table.Rows = [][]string{{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}}
+		table.RowStyles[0] = termui.NewStyle(termui.ColorWhite, termui.ColorClear, termui.ModifierBold)
+		for week := 0; week < 6; week++ {
+			row := make([]string, 7)
+			for weekday := 0; weekday < 7; weekday++ {
+				day := start.AddDate(0, 0, week*7+weekday)
+				due := len(tm.TasksDue(day, day.AddDate(0, 0, 1)))
+				cell := fmt.Sprintf("%2d", day.Day())
+				if due > 0 {
+					cell = fmt.Sprintf("%2d (%d)", day.Day(), due)
+				}
+				switch {
+				case day.Equal(selected):
+					cell = fmt.Sprintf("[%s](fg:black,bg:yellow)", cell)
+				case day.Month() != selected.Month():
+					cell = fmt.Sprintf("[%s](fg:white,mod:faint)", cell)
+				case due > 0:
+					cell = fmt.Sprintf("[%s](fg:cyan)", cell)
+				}
+				row[weekday] = cell
+			}
+			table.Rows = append(table.Rows, row)
+		}
+
+		dayList := widgets.NewList()
+		dayList.Title = fmt.Sprintf("Due %s", selected.Format("Mon Jan 2"))
+		for _, ref := range tm.TasksDue(selected, selected.AddDate(0, 0, 1)) {
+			dayList.Rows = append(dayList.Rows, formatTaskRef(ref))
+		}
+		if len(dayList.Rows) == 0 {
+			dayList.Rows = []string{"Nothing due"}
+		}
+
+		help := widgets.NewParagraph()
+		help.Text = "h/l: day  j/k: week  n/p: month  t: today  q: close"
+
+		termui.Clear()
+		termWidth, termHeight := termui.TerminalDimensions()
+		table.SetRect(0, 0, termWidth, 15)
+		dayList.SetRect(0, 15, termWidth, termHeight-3)
+		help.SetRect(0, termHeight-3, termWidth, termHeight)
+		termui.Render(table, dayList, help)
+
+		e := <-uiEvents
+		switch e.ID {
+		case "q", "<Escape>":
+			return
+		case "h", "<Left>":
+			selected = selected.AddDate(0, 0, -1)
+		case "l", "<Right>":
+			selected = selected.AddDate(0, 0, 1)
+		case "k", "<Up>":
+			selected = selected.AddDate(0, 0, -7)
+		case "j", "<Down>":
+			selected = selected.AddDate(0, 0, 7)
+		case "p", "<PageUp>":
+			selected = selected.AddDate(0, -1, 0)
+		case "n", "<PageDown>":
+			selected = selected.AddDate(0, 1, 0)
+		case "t":
+			selected = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		}
+	}
+}
+
+// showAgenda displays overdue and upcoming tasks of all projects until it is closed
+func showAgenda(tm *task.TaskManager, uiEvents <-chan termui.Event) {
+	agenda := widgets.NewList()
+	agenda.Title = "Agenda"
+	agenda.WrapText = false
+	agenda.SelectedRowStyle = termui.NewStyle(termui.ColorYellow)
+
+	for {
+		agenda.Rows = []string{}
+		for _, section := range tm.Agenda(time.Now()) {
+			agenda.Rows = append(agenda.Rows, fmt.Sprintf("[%s (%d)](fg:yellow,mod:bold)", section.Title, len(section.Tasks)))
+			for _, ref := range section.Tasks {
+				agenda.Rows = append(agenda.Rows, "  "+formatTaskRef(ref))
+			}
+			agenda.Rows = append(agenda.Rows, "")
+		}
+
+		termui.Clear()
+		termWidth, termHeight := termui.TerminalDimensions()
+		agenda.SetRect(0, 0, termWidth, termHeight)
+		termui.Render(agenda)
+
+		e := <-uiEvents
+		switch e.ID {
+		case "q", "<Escape>":
+			return
+		case "j", "<Down>":
+			agenda.ScrollDown()
+		case "k", "<Up>":
+			agenda.ScrollUp()
+		}
+	}
+}
+
+// formatTaskRef formats a task of any project for cross-project lists
+func formatTaskRef(ref task.TaskRef) string {
+	status := "[ ]"
+	if ref.Task.Complete {
+		status = "[x]"
+	}
+	return fmt.Sprintf("%s › %d. %s %s%s (Assigned to: %s)", ref.ProjectName, ref.Task.ID, status, ref.Task.Title, formatDue(ref.Task), ref.Task.AssignedTo)
+}
+
+// formatDue formats the due date of a task for list rows, flagging overdue tasks
+func formatDue(t task.Task) string {
+	switch {
+	case t.IsOverdue(time.Now()):
+		return " ⚠ overdue " + t.Due.Format(task.DateLayout)
+	case t.Due != nil:
+		return " due " + t.Due.Format(task.DateLayout)
+	}
+	return ""
+}
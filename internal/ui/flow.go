@@ -0,0 +1,69 @@
+package ui
+
+import (
+	"Termile/internal/report"
+	"Termile/internal/task"
+	"fmt"
+	"strings"
+
+	"github.com/gizak/termui/v3/widgets"
+)
+
+// updateFlowPanel shows the lead and cycle time percentiles of the selected
+// project, with a histogram of its cycle times
+func updateFlowPanel(panel *widgets.Paragraph, tm *task.TaskManager, projectID int) {
+	var projects []task.Project
+	for _, project := range tm.ListProjects() {
+		if project.ID == projectID {
+			projects = append(projects, project)
+		}
+	}
+	samples := report.FlowSamples(projects)
+	if len(samples) == 0 {
+		panel.Text = "No completed tasks yet"
+		return
+	}
+
+	var text strings.Builder
+	for _, metric := range []string{report.LeadTime, report.CycleTime} {
+		durations := report.Durations(samples, metric)
+		name := "Lead "
+		if metric == report.CycleTime {
+			name = "Cycle"
+		}
+		if len(durations) == 0 {
+			fmt.Fprintf(&text, "[%s](fg:cyan) no tasks started\n", name)
+			continue
+		}
+		fmt.Fprintf(&text, "[%s](fg:cyan) p50 %s  p85 %s  p95 %s  (%d)\n", name,
+			report.FormatDays(report.Percentile(durations, 50)),
+			report.FormatDays(report.Percentile(durations, 85)),
+			report.FormatDays(report.Percentile(durations, 95)),
+			len(durations))
+	}
+
+	// Tasks completed without passing through an in-progress status have no
+	// cycle time, so fall back to lead times
+	durations := report.Durations(samples, report.CycleTime)
+	heading := "Cycle time"
+	if len(durations) == 0 {
+		durations = report.Durations(samples, report.LeadTime)
+		heading = "Lead time"
+	}
+	fmt.Fprintf(&text, "\n%s\n", heading)
+	buckets := report.Histogram(durations)
+	largest := 0
+	for _, bucket := range buckets {
+		if bucket.Count > largest {
+			largest = bucket.Count
+		}
+	}
+	for _, bucket := range buckets {
+		width := 0
+		if largest > 0 {
+			width = bucket.Count * 20 / largest
+		}
+		fmt.Fprintf(&text, "%-5s [%s](fg:green) %d\n", bucket.Label, strings.Repeat("█", width), bucket.Count)
+	}
+	panel.Text = text.String()
+}
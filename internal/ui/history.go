@@ -0,0 +1,62 @@
+package ui
+
+import (
+	"Termile/internal/task"
+	"Termile/pkg/storage"
+	"fmt"
+
+	"github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+)
+
+// showHistory lists the recorded changes of a task, or of one of its
+// subtasks when subtaskID is not 0, newest first until it is closed
+func showHistory(uiEvents <-chan termui.Event, projectID, taskID, subtaskID int) {
+	historyList := widgets.NewList()
+	historyList.Title = fmt.Sprintf("History of task %d", taskID)
+	if subtaskID != 0 {
+		historyList.Title = fmt.Sprintf("History of subtask %d of task %d", subtaskID, taskID)
+	}
+	historyList.SelectedRowStyle = termui.NewStyle(termui.ColorBlack, termui.ColorYellow)
+	historyList.WrapText = true
+	help := widgets.NewParagraph()
+	help.Text = "j/k: scroll  q: close"
+
+	changes, err := storage.LoadHistory(historyFile)
+	filter := task.HistoryFilter{ProjectID: projectID, TaskID: taskID, SubtaskID: subtaskID}
+	historyList.Rows = []string{}
+	for i := len(changes) - 1; i >= 0; i-- {
+		if filter.Match(changes[i]) {
+			change := changes[i]
+			historyList.Rows = append(historyList.Rows, fmt.Sprintf("%s %s  %s", change.Time.Local().Format("2006-01-02 15:04"), change.Actor, change))
+		}
+	}
+	switch {
+	case err != nil:
+		historyList.Rows = []string{"Cannot read the history: " + err.Error()}
+	case len(historyList.Rows) == 0:
+		historyList.Rows = []string{"No changes recorded yet"}
+	}
+
+	for {
+		termui.Clear()
+		termWidth, termHeight := termui.TerminalDimensions()
+		historyList.SetRect(0, 0, termWidth, termHeight-3)
+		help.SetRect(0, termHeight-3, termWidth, termHeight)
+		termui.Render(historyList, help)
+
+		e := <-uiEvents
+		switch e.ID {
+		case "q", "<Escape>":
+			return
+		case "j", "<Down>":
+			historyList.ScrollDown()
+		case "k", "<Up>":
+			historyList.ScrollUp()
+		case "<PageDown>":
+			historyList.ScrollPageDown()
+		case "<PageUp>":
+			historyList.ScrollPageUp()
+		}
+	}
+}
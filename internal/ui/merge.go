@@ -0,0 +1,134 @@
+package ui
+
+import (
+	"Termile/internal/merge"
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+)
+
+// ResolveConflicts lets the user pick a side for each conflict of a merge,
+// showing the base, our and their versions of the selected one. It returns
+// true to write the merged projects, false if the user aborted the merge.
+func ResolveConflicts(result *merge.Result) (bool, error) {
+	if err := termui.Init(); err != nil {
+		return false, fmt.Errorf("failed to initialize termui: %v", err)
+	}
+	defer termui.Close()
+
+	conflictList := widgets.NewList()
+	conflictList.SelectedRowStyle = termui.NewStyle(termui.ColorBlack, termui.ColorYellow)
+	details := widgets.NewParagraph()
+	details.Title = "Versions"
+	details.WrapText = true
+	help := widgets.NewParagraph()
+	help.Text = "j/k: select  o/t: take ours/theirs  O/T: take ours/theirs for all  w: write  q: abort"
+
+	uiEvents := termui.PollEvents()
+	for {
+		conflictList.Title = fmt.Sprintf("Conflicts (%d unresolved)", result.Unresolved())
+		conflictList.Rows = make([]string, len(result.Conflicts))
+		for i, conflict := range result.Conflicts {
+			conflictList.Rows[i] = fmt.Sprintf("%s %s", formatResolution(conflict.Resolution), formatConflict(conflict))
+		}
+		if conflictList.SelectedRow >= len(conflictList.Rows) {
+			conflictList.SelectedRow = len(conflictList.Rows) - 1
+		}
+		if conflictList.SelectedRow < 0 {
+			conflictList.SelectedRow = 0
+		}
+		details.Text = ""
+		if len(result.Conflicts) > 0 {
+			conflict := result.Conflicts[conflictList.SelectedRow]
+			details.Text = fmt.Sprintf("%s\n\n[Base](mod:bold)\n%s\n\n[Ours](fg:green,mod:bold)\n%s\n\n[Theirs](fg:cyan,mod:bold)\n%s",
+				formatConflict(conflict), indentJSON(conflict.Base), indentJSON(conflict.Ours), indentJSON(conflict.Theirs))
+		}
+
+		termui.Clear()
+		termWidth, termHeight := termui.TerminalDimensions()
+		conflictList.SetRect(0, 0, termWidth/2, termHeight-3)
+		details.SetRect(termWidth/2, 0, termWidth, termHeight-3)
+		help.SetRect(0, termHeight-3, termWidth, termHeight)
+		termui.Render(conflictList, details, help)
+
+		e := <-uiEvents
+		switch e.ID {
+		case "q", "<Escape>", "<C-c>":
+			return false, nil
+		case "w", "<Enter>":
+			return true, nil
+		case "j", "<Down>":
+			conflictList.ScrollDown()
+		case "k", "<Up>":
+			conflictList.ScrollUp()
+		case "o", "t":
+			if len(result.Conflicts) == 0 {
+				break
+			}
+			side := merge.Ours
+			if e.ID == "t" {
+				side = merge.Theirs
+			}
+			if err := result.Resolve(conflictList.SelectedRow, side); err != nil {
+				return false, err
+			}
+			conflictList.ScrollDown()
+		case "O", "T":
+			side := merge.Ours
+			if e.ID == "T" {
+				side = merge.Theirs
+			}
+			for i := range result.Conflicts {
+				if err := result.Resolve(i, side); err != nil {
+					return false, err
+				}
+			}
+		}
+	}
+}
+
+// formatResolution shows the side taken for a conflict
+func formatResolution(resolution string) string {
+	switch resolution {
+	case merge.Ours:
+		return "[ours  ](fg:green)"
+	case merge.Theirs:
+		return "[theirs](fg:cyan)"
+	}
+	return "[?     ](fg:red)"
+}
+
+// formatConflict describes what a conflict is about, e.g.
+// `task 1/4 "Write docs": Title` or `subtask 1/4/2 "Review": deleted`
+func formatConflict(conflict merge.Conflict) string {
+	ref := fmt.Sprintf("%s %d", conflict.Entity, conflict.ProjectID)
+	if conflict.Key != "" {
+		ref = conflict.Entity
+	}
+	if conflict.TaskID != 0 {
+		ref += fmt.Sprintf("/%d", conflict.TaskID)
+	}
+	if conflict.SubtaskID != 0 {
+		ref += fmt.Sprintf("/%d", conflict.SubtaskID)
+	}
+	field := conflict.Field
+	if field == "" {
+		field = "deleted on one side, changed on the other"
+	}
+	return fmt.Sprintf("%s %q: %s", ref, conflict.Title, field)
+}
+
+// indentJSON formats one side of a conflict for reading
+func indentJSON(value json.RawMessage) string {
+	if string(value) == "null" {
+		return "(none)"
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, value, "", "  "); err != nil {
+		return string(value)
+	}
+	return indented.String()
+}
@@ -0,0 +1,54 @@
+package ui
+
+import (
+	"Termile/internal/task"
+	"fmt"
+	"strings"
+)
+
+// formatAssignees shows the assignees of a task in their colours
+func formatAssignees(tm *task.TaskManager, assignedTo string) string {
+	assignees := task.SplitAssignees(assignedTo)
+	for i, assignee := range assignees {
+		if person, ok := tm.FindPerson(assignee); ok && person.Color != "" {
+			assignees[i] = fmt.Sprintf("[%s](fg:%s)", assignee, person.Color)
+		}
+	}
+	return strings.Join(assignees, ", ")
+}
+
+// commonPrefix returns the longest case-insensitive common prefix of names
+func commonPrefix(names []string) string {
+	prefix := names[0]
+	for _, name := range names[1:] {
+		n := 0
+		for n < len(prefix) && n < len(name) && strings.EqualFold(prefix[n:n+1], name[n:n+1]) {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+	return prefix
+}
+
+// completeAssignee completes the last name of a comma-separated list of
+// assignees from the people registry. It returns the completed text and the
+// handles that matched.
+func completeAssignee(tm *task.TaskManager, text string) (string, []string) {
+	head := ""
+	last := text
+	if i := strings.LastIndex(text, ","); i != -1 {
+		head, last = strings.TrimSpace(text[:i+1])+" ", text[i+1:]
+	}
+	candidates := tm.CompleteAssignee(last)
+	switch len(candidates) {
+	case 0:
+		return text, nil
+	case 1:
+		return head + candidates[0], candidates
+	}
+	prefix := commonPrefix(candidates)
+	if len(prefix) < len(strings.TrimSpace(last)) {
+		return text, candidates
+	}
+	return head + prefix, candidates
+}
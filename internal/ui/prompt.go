@@ -0,0 +1,49 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+)
+
+// promptInput shows a single-line input box at the bottom of the screen and
+// returns the entered text, or false if the prompt was cancelled with Escape
+func promptInput(uiEvents <-chan termui.Event, title string, initial string) (string, bool) {
+	input := widgets.NewParagraph()
+	input.Title = title
+	input.BorderStyle = termui.NewStyle(termui.ColorYellow)
+
+	termWidth, termHeight := termui.TerminalDimensions()
+	input.SetRect(0, termHeight-3, termWidth, termHeight)
+
+	inputBuffer := strings.Builder{}
+	inputBuffer.WriteString(initial)
+	for {
+		input.Text = inputBuffer.String()
+		termui.Render(input)
+
+		e := <-uiEvents
+		if e.Type != termui.KeyboardEvent {
+			continue
+		}
+		switch e.ID {
+		case "<Escape>", "<C-c>":
+			return "", false
+		case "<Enter>":
+			return strings.TrimSpace(inputBuffer.String()), true
+		case "<Backspace>", "<C-<Backspace>>":
+			currentText := inputBuffer.String()
+			if len(currentText) > 0 {
+				inputBuffer.Reset()
+				inputBuffer.WriteString(currentText[:len(currentText)-1])
+			}
+		case "<Space>":
+			inputBuffer.WriteString(" ")
+		default:
+			if len(e.ID) == 1 {
+				inputBuffer.WriteString(e.ID)
+			}
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package ui
+
+import (
+	"Termile/internal/task"
+	"time"
+)
+
+// staleWidgets records the parts of the screen that changes published on the
+// bus made stale, so that only those are refreshed after a key press.
+type staleWidgets struct {
+	projects bool // Project list, which shows tracked time
+	tasks    bool // Task list or view tasks, charts and flow panel
+	subtasks bool // Subtask list, gauge, description and comments
+	views    bool // View list
+}
+
+// watchChanges returns the widgets made stale by the changes published on a
+// bus from now on, given the selection shown on screen. Changes outside the
+// selection only make the lists showing them stale; if the selection moves,
+// everything is refreshed anyway.
+func watchChanges(bus *task.Bus, shown *selection) *staleWidgets {
+	stale := &staleWidgets{}
+	// Tasks of the selected project, or of every project in view mode
+	shownTasks := func(projectID int) bool {
+		return shown.viewMode || projectID == shown.projectID
+	}
+	// The created, updated and deleted events come with every change
+	bus.OnAny(func(e task.BusEvent) {
+		now := time.Now()
+		switch e := e.(type) {
+		case task.ProjectCreated, task.ProjectDeleted:
+			stale.projects = true
+		case task.ProjectUpdated:
+			stale.projects = true
+			// The workflow and estimate unit show in the task list
+			stale.tasks = stale.tasks || e.After.ID == shown.projectID
+		case task.TaskCreated:
+			stale.tasks = stale.tasks || shownTasks(e.ProjectID)
+			stale.projects = stale.projects || e.Task.TrackedTime(now) > 0
+		case task.TaskDeleted:
+			stale.tasks = stale.tasks || shownTasks(e.ProjectID)
+			stale.projects = stale.projects || e.Task.TrackedTime(now) > 0
+			stale.subtasks = stale.subtasks || e.Task.ID == shown.taskID
+		case task.TaskUpdated:
+			stale.tasks = stale.tasks || shownTasks(e.ProjectID)
+			stale.projects = stale.projects || e.Before.TrackedTime(now) != e.After.TrackedTime(now)
+			// The description and comments of the selected task show with
+			// its subtasks
+			stale.subtasks = stale.subtasks || e.After.ID == shown.taskID
+		case task.SubtaskCreated:
+			stale.subtasks = stale.subtasks || e.TaskID == shown.taskID
+			stale.markRollUp(shownTasks(e.ProjectID), e.Subtask.TrackedTime(now) > 0, e.Subtask.Estimate > 0)
+		case task.SubtaskDeleted:
+			stale.subtasks = stale.subtasks || e.TaskID == shown.taskID
+			stale.markRollUp(shownTasks(e.ProjectID), e.Subtask.TrackedTime(now) > 0, e.Subtask.Estimate > 0)
+		case task.SubtaskUpdated:
+			stale.subtasks = stale.subtasks || e.TaskID == shown.taskID
+			stale.markRollUp(shownTasks(e.ProjectID), e.Before.TrackedTime(now) != e.After.TrackedTime(now), e.Before.Estimate != e.After.Estimate)
+		case task.PersonAdded, task.PersonUpdated, task.PersonRemoved:
+			// Assignees are shown with the names and colours of people
+			stale.tasks, stale.subtasks = true, true
+		case task.ViewAdded, task.ViewUpdated, task.ViewRemoved:
+			stale.views = true
+			stale.tasks = stale.tasks || shown.viewMode
+		}
+	})
+	return stale
+}
+
+// markRollUp marks stale the lists showing the time and estimate of a
+// subtask rolled up into its task and project.
+func (s *staleWidgets) markRollUp(shownTasks, timeChanged, estimateChanged bool) {
+	s.tasks = s.tasks || shownTasks && (timeChanged || estimateChanged)
+	s.projects = s.projects || timeChanged
+}
+
+// markAll marks every widget stale.
+func (s *staleWidgets) markAll() {
+	*s = staleWidgets{projects: true, tasks: true, subtasks: true, views: true}
+}
+
+// selection is what the screen shows besides the data; the widgets depending
+// on it are refreshed when it changes.
+type selection struct {
+	projectIndex, projectID, taskIndex, taskID, subtaskIndex int
+	viewIndex, viewTaskIndex                                 int
+	projectMode, subtaskMode, viewMode, flow                 bool
+}
@@ -0,0 +1,58 @@
+package ui
+
+import (
+	"Termile/pkg/storage"
+	"errors"
+	"fmt"
+
+	"github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+)
+
+// promptMerge asks what to do with the files another process changed while
+// there are unsaved local changes. It returns true to merge them, false to
+// overwrite them
+func promptMerge(uiEvents <-chan termui.Event, pending int) bool {
+	prompt := widgets.NewParagraph()
+	prompt.Title = "Files changed on disk"
+	prompt.WrapText = true
+	prompt.BorderStyle = termui.NewStyle(termui.ColorYellow)
+	prompt.Text = fmt.Sprintf("Another program changed the task files while you have %d unsaved changes.\n\n"+
+		"m: merge - reload the files, which include your changes\n"+
+		"o: overwrite - save your version over theirs", pending)
+
+	termWidth, termHeight := termui.TerminalDimensions()
+	prompt.SetRect(termWidth/6, termHeight/3, 5*termWidth/6, 2*termHeight/3)
+	termui.Render(prompt)
+	for e := range uiEvents {
+		switch e.ID {
+		case "m", "<Enter>":
+			return true
+		case "o":
+			return false
+		}
+	}
+	return true
+}
+
+// mergeExternalChanges brings in the changes another process made to the
+// files. Without unsaved local changes the files are simply reloaded,
+// otherwise the user chooses between merging and overwriting them
+func mergeExternalChanges(uiEvents <-chan termui.Event, store *storage.Store) error {
+	if store.Pending() == 0 || promptMerge(uiEvents, store.Pending()) {
+		return store.Merge()
+	}
+	return store.Overwrite()
+}
+
+// saveStore saves the changes, first bringing in those other processes made
+// to the files since they were read
+func saveStore(uiEvents <-chan termui.Event, store *storage.Store) error {
+	err := store.Save()
+	if errors.Is(err, storage.ErrModified) {
+		if err = mergeExternalChanges(uiEvents, store); err == nil {
+			err = store.Save()
+		}
+	}
+	return err
+}
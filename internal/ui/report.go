@@ -0,0 +1,92 @@
+package ui
+
+import (
+	"Termile/internal/report"
+	"Termile/internal/task"
+	"fmt"
+	"time"
+
+	"github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+)
+
+// weekTimeTable builds a table of the time logged per project on each day of
+// the week starting at weekStart
+func weekTimeTable(tm *task.TaskManager, weekStart time.Time) [][]string {
+	header := []string{"Project"}
+	days := make(map[string][]time.Duration)
+	order := []string{}
+	totals := make([]time.Duration, 8)
+	now := time.Now()
+	for day := 0; day < 7; day++ {
+		date := weekStart.AddDate(0, 0, day)
+		header = append(header, date.Format("Mon 2"))
+		rows, _ := report.TimeReport(tm.ListProjects(), date, date.AddDate(0, 0, 1), report.ByProject, report.Rounding{}, now)
+		for _, row := range rows {
+			if _, ok := days[row.Group]; !ok {
+				days[row.Group] = make([]time.Duration, 8)
+				order = append(order, row.Group)
+			}
+			days[row.Group][day] += row.Duration
+			days[row.Group][7] += row.Duration
+			totals[day] += row.Duration
+			totals[7] += row.Duration
+		}
+	}
+	header = append(header, "Total")
+
+	format := func(d time.Duration) string {
+		if d == 0 {
+			return "-"
+		}
+		return task.FormatDuration(d)
+	}
+	table := [][]string{header}
+	for _, project := range order {
+		row := []string{project}
+		for _, d := range days[project] {
+			row = append(row, format(d))
+		}
+		table = append(table, row)
+	}
+	totalRow := []string{"Total"}
+	for _, d := range totals {
+		totalRow = append(totalRow, format(d))
+	}
+	return append(table, totalRow)
+}
+
+// showTimeReport displays the time logged per project during a week until it is closed
+func showTimeReport(tm *task.TaskManager, uiEvents <-chan termui.Event) {
+	weekStart := task.StartOfWeek(time.Now())
+
+	help := widgets.NewParagraph()
+	help.Text = "h/l: previous/next week  t: this week  q: close"
+
+	for {
+		table := widgets.NewTable()
+		table.Title = fmt.Sprintf("Time logged, week of %s", weekStart.Format("Jan 2, 2006"))
+		table.Rows = weekTimeTable(tm, weekStart)
+		table.TextAlignment = termui.AlignCenter
+		table.RowStyles[0] = termui.NewStyle(termui.ColorWhite, termui.ColorClear, termui.ModifierBold)
+		table.RowStyles[len(table.Rows)-1] = termui.NewStyle(termui.ColorYellow, termui.ColorClear, termui.ModifierBold)
+
+		termui.Clear()
+		termWidth, termHeight := termui.TerminalDimensions()
+		table.SetRect(0, 0, termWidth, termHeight-3)
+		help.SetRect(0, termHeight-3, termWidth, termHeight)
+		termui.Render(table, help)
+
+		e := <-uiEvents
+		switch e.ID {
+		case "q", "<Escape>":
+			return
+		case "h", "<Left>":
+			weekStart = weekStart.AddDate(0, 0, -7)
+		case "l", "<Right>":
+			weekStart = weekStart.AddDate(0, 0, 7)
+		case "t":
+			weekStart = task.StartOfWeek(time.Now())
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package ui
+
+import (
+	"Termile/internal/task"
+	"fmt"
+	"time"
+
+	"github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+)
+
+// Panes of the sprint screen that take the selection keys
+const (
+	paneSprints = iota
+	paneSprintTasks
+	paneBacklog
+)
+
+// formatSprintTask formats a task as a row of the sprint screen
+func formatSprintTask(t task.Task) string {
+	mark := "[ ]"
+	if t.Complete {
+		mark = "[x]"
+	}
+	return fmt.Sprintf("%s %d. %s (%s)", mark, t.ID, t.Title, t.Status)
+}
+
+// promptSprint asks for the name, dates and goal of a new sprint. It returns
+// false if the prompts were cancelled
+func promptSprint(uiEvents <-chan termui.Event, suggestedName string) (task.Sprint, bool, error) {
+	name, ok := promptInput(uiEvents, "Sprint name", suggestedName)
+	if !ok {
+		return task.Sprint{}, false, nil
+	}
+	startText, ok := promptInput(uiEvents, "First day (YYYY-MM-DD, today, +7d)", "today")
+	if !ok {
+		return task.Sprint{}, false, nil
+	}
+	start, err := task.ParseDate(startText, time.Now())
+	if err != nil || start == nil {
+		return task.Sprint{}, true, fmt.Errorf("invalid start date %q", startText)
+	}
+	endText, ok := promptInput(uiEvents, "Last day (YYYY-MM-DD or +Nd from the first day)", "+13d")
+	if !ok {
+		return task.Sprint{}, false, nil
+	}
+	end, err := task.ParseDate(endText, *start)
+	if err != nil || end == nil {
+		return task.Sprint{}, true, fmt.Errorf("invalid end date %q", endText)
+	}
+	goal, ok := promptInput(uiEvents, "Sprint goal (optional)", "")
+	if !ok {
+		return task.Sprint{}, false, nil
+	}
+	return task.Sprint{Name: name, Goal: goal, Start: *start, End: *end}, true, nil
+}
+
+// showSummary displays the report of a closed sprint until a key is pressed
+func showSummary(uiEvents <-chan termui.Event, summary task.SprintSummary) {
+	report := widgets.NewParagraph()
+	report.Title = "Sprint closed"
+	report.Text = summary.String() + "\nPress any key to continue"
+	report.BorderStyle = termui.NewStyle(termui.ColorGreen)
+
+	termWidth, termHeight := termui.TerminalDimensions()
+	report.SetRect(termWidth/6, termHeight/6, 5*termWidth/6, 5*termHeight/6)
+	termui.Render(report)
+	for e := range uiEvents {
+		if e.Type == termui.KeyboardEvent {
+			return
+		}
+	}
+}
+
+// showSprints displays the sprints of a project with their scope, progress
+// and the backlog until it is closed
+func showSprints(tm *task.TaskManager, uiEvents <-chan termui.Event, projectID int) {
+	sprintList := widgets.NewList()
+	sprintList.Title = "Sprints"
+	details := widgets.NewParagraph()
+	details.Title = "Goal"
+	progressGauge := widgets.NewGauge()
+	progressGauge.Title = "Completion"
+	progressGauge.BarColor = termui.ColorGreen
+	sprintTasks := widgets.NewList()
+	backlog := widgets.NewList()
+	backlog.Title = "Backlog"
+	help := widgets.NewParagraph()
+
+	pane := paneSprints
+	panes := []*widgets.List{sprintList, sprintTasks, backlog}
+	status := ""
+
+	for {
+		var project task.Project
+		for _, p := range tm.ListProjects() {
+			if p.ID == projectID {
+				project = p
+			}
+		}
+		sprints := tm.ListSprints(projectID)
+
+		sprintList.Rows = []string{}
+		for _, sprint := range sprints {
+			progress := tm.SprintProgress(projectID, sprint.ID)
+			row := fmt.Sprintf("%s  %s – %s  %d/%d", sprint.Name, sprint.Start.Format("Jan 2"), sprint.End.Format("Jan 2"), progress.Done, progress.Total)
+			if sprint.Closed {
+				row += "  closed"
+			}
+			sprintList.Rows = append(sprintList.Rows, row)
+		}
+		if len(sprints) == 0 {
+			sprintList.Rows = []string{"No sprints yet, press a to add one"}
+		}
+		if sprintList.SelectedRow < 0 || sprintList.SelectedRow >= len(sprintList.Rows) {
+			sprintList.SelectedRow = len(sprintList.Rows) - 1
+		}
+
+		var selected task.Sprint
+		hasSprint := len(sprints) > 0
+		sprintTasks.Rows = []string{}
+		details.Text = ""
+		progressGauge.Percent = 0
+		progressGauge.Label = ""
+		if hasSprint {
+			selected = sprints[sprintList.SelectedRow]
+			sprintTasks.Title = selected.Name
+			details.Text = selected.Goal
+			progress := tm.SprintProgress(projectID, selected.ID)
+			progressGauge.Percent = progress.Percent()
+			progressGauge.Label = fmt.Sprintf("%d of %d tasks", progress.Done, progress.Total)
+			if progress.Estimate > 0 {
+				progressGauge.Label += fmt.Sprintf(", %s of %s", task.FormatEstimate(progress.DoneEstimate, project.Unit()), task.FormatEstimate(progress.Estimate, project.Unit()))
+			}
+			for _, t := range tm.SprintTasks(projectID, selected.ID) {
+				sprintTasks.Rows = append(sprintTasks.Rows, formatSprintTask(t))
+			}
+		}
+		backlogTasks := []task.Task{}
+		backlog.Rows = []string{}
+		for _, t := range tm.SprintTasks(projectID, 0) {
+			if !t.Complete {
+				backlogTasks = append(backlogTasks, t)
+				backlog.Rows = append(backlog.Rows, formatSprintTask(t))
+			}
+		}
+		for i, list := range panes {
+			// Scrolling an empty list leaves its selection at -1
+			if list.SelectedRow < 0 || list.SelectedRow >= len(list.Rows) {
+				list.SelectedRow = 0
+			}
+			list.SelectedRowStyle = termui.NewStyle(termui.ColorWhite)
+			list.BorderStyle = termui.NewStyle(termui.ColorWhite)
+			if i == pane {
+				list.SelectedRowStyle = termui.NewStyle(termui.ColorBlack, termui.ColorYellow)
+				list.BorderStyle = termui.NewStyle(termui.ColorYellow)
+			}
+		}
+		help.Text = "Tab: switch pane  j/k: select  Enter: move task to/from backlog  a: add sprint  c: close sprint  q: close"
+		if status != "" {
+			help.Text = status
+		}
+
+		termui.Clear()
+		termWidth, termHeight := termui.TerminalDimensions()
+		top := (termHeight - 3) / 3
+		sprintList.SetRect(0, 0, termWidth/2, top)
+		details.SetRect(termWidth/2, 0, termWidth, top-3)
+		progressGauge.SetRect(termWidth/2, top-3, termWidth, top)
+		sprintTasks.SetRect(0, top, termWidth/2, termHeight-3)
+		backlog.SetRect(termWidth/2, top, termWidth, termHeight-3)
+		help.SetRect(0, termHeight-3, termWidth, termHeight)
+		termui.Render(sprintList, details, progressGauge, sprintTasks, backlog, help)
+
+		e := <-uiEvents
+		status = ""
+		switch e.ID {
+		case "q", "<Escape>":
+			return
+		case "<Tab>":
+			pane = (pane + 1) % len(panes)
+		case "j", "<Down>":
+			panes[pane].ScrollDown()
+		case "k", "<Up>":
+			panes[pane].ScrollUp()
+		case "<Enter>":
+			if !hasSprint {
+				continue
+			}
+			var err error
+			switch {
+			case pane == paneBacklog && len(backlogTasks) > 0:
+				err = tm.AssignSprint(projectID, backlogTasks[backlog.SelectedRow].ID, selected.ID)
+			case pane == paneSprintTasks && len(sprintTasks.Rows) > 0:
+				err = tm.AssignSprint(projectID, tm.SprintTasks(projectID, selected.ID)[sprintTasks.SelectedRow].ID, 0)
+			}
+			if err != nil {
+				status = err.Error()
+			}
+		case "a":
+			suggested := "Sprint 1"
+			if hasSprint {
+				suggested = fmt.Sprintf("Sprint %d", len(sprints)+1)
+			}
+			sprint, ok, err := promptSprint(uiEvents, suggested)
+			if !ok {
+				continue
+			}
+			if err == nil {
+				_, err = tm.AddSprint(projectID, sprint)
+			}
+			if err != nil {
+				status = err.Error()
+			}
+		case "c":
+			if !hasSprint {
+				continue
+			}
+			summary, err := tm.CloseSprint(projectID, selected.ID, time.Now())
+			if err != nil {
+				status = err.Error()
+				continue
+			}
+			showSummary(uiEvents, summary)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package ui
+
+import (
+	"Termile/internal/report"
+	"Termile/internal/task"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+)
+
+const (
+	burndownDays  = 60 // longest burndown shown on the stats screen
+	velocityWeeks = 8
+)
+
+// projectBurndown returns the burndown of a project over at most the last burndownDays days
+func projectBurndown(project task.Project, now time.Time) []report.BurndownPoint {
+	from := report.ProjectStart(project, now)
+	if earliest := now.AddDate(0, 0, -burndownDays); from.Before(earliest) {
+		from = earliest
+	}
+	return report.Burndown(project, from, now)
+}
+
+// exportStats writes the burndown and velocity of a project as CSV files in
+// the working directory and returns their names
+func exportStats(project task.Project, now time.Time) ([]string, error) {
+	burndownFile := fmt.Sprintf("burndown-%d.csv", project.ID)
+	velocityFile := fmt.Sprintf("velocity-%d.csv", project.ID)
+
+	file, err := os.Create(burndownFile)
+	if err != nil {
+		return nil, err
+	}
+	if err := report.WriteBurndown(file, report.FormatCSV, report.Burndown(project, report.ProjectStart(project, now), now)); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+
+	file, err = os.Create(velocityFile)
+	if err != nil {
+		return nil, err
+	}
+	if err := report.WriteVelocity(file, report.FormatCSV, report.Velocity(project, velocityWeeks, now)); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return []string{burndownFile, velocityFile}, file.Close()
+}
+
+// showStats displays the burndown and weekly velocity of a project until it is closed
+func showStats(tm *task.TaskManager, uiEvents <-chan termui.Event, projectID int) {
+	var project task.Project
+	found := false
+	for _, p := range tm.ListProjects() {
+		if p.ID == projectID {
+			project, found = p, true
+		}
+	}
+	if !found {
+		return
+	}
+
+	help := widgets.NewParagraph()
+	help.Text = "x: export CSV  q: close"
+
+	for {
+		now := time.Now()
+		burndown := projectBurndown(project, now)
+
+		plot := widgets.NewPlot()
+		plot.Title = fmt.Sprintf("Burndown: %s (open tasks since %s)", project.Name, burndown[0].Day.Format("Jan 2"))
+		openTasks := []float64{}
+		openEstimate := []float64{}
+		hasEstimates := false
+		for _, point := range burndown {
+			openTasks = append(openTasks, float64(point.OpenTasks))
+			openEstimate = append(openEstimate, point.OpenPoints)
+			hasEstimates = hasEstimates || point.OpenPoints > 0
+		}
+		// A line needs at least two points
+		if len(openTasks) == 1 {
+			openTasks = append(openTasks, openTasks[0])
+			openEstimate = append(openEstimate, openEstimate[0])
+		}
+		plot.Data = [][]float64{openTasks}
+		plot.LineColors = []termui.Color{termui.ColorRed, termui.ColorCyan}
+		if hasEstimates {
+			plot.Data = append(plot.Data, openEstimate)
+			plot.Title += fmt.Sprintf(", cyan: open %s", project.Unit())
+		}
+		plot.MaxVal, _ = termui.GetMaxFloat64From2dSlice(plot.Data)
+		if plot.MaxVal < 1 {
+			plot.MaxVal = 1
+		}
+
+		velocity := widgets.NewBarChart()
+		velocity.Title = "Tasks completed per week"
+		velocity.BarColors = []termui.Color{termui.ColorGreen}
+		velocity.NumStyles = []termui.Style{termui.NewStyle(termui.ColorBlack)}
+		velocity.LabelStyles = []termui.Style{termui.NewStyle(termui.ColorWhite)}
+		velocity.BarWidth = 7
+		for _, point := range report.Velocity(project, velocityWeeks, now) {
+			velocity.Data = append(velocity.Data, float64(point.Completed))
+			velocity.Labels = append(velocity.Labels, point.WeekStart.Format("Jan 2"))
+		}
+		// Bars are scaled by the largest value, which must not be zero
+		velocity.MaxVal, _ = termui.GetMaxFloat64FromSlice(velocity.Data)
+		if velocity.MaxVal < 1 {
+			velocity.MaxVal = 1
+		}
+
+		termui.Clear()
+		termWidth, termHeight := termui.TerminalDimensions()
+		chartHeight := (termHeight - 3) / 2
+		plot.SetRect(0, 0, termWidth, chartHeight)
+		velocity.SetRect(0, chartHeight, termWidth, termHeight-3)
+		help.SetRect(0, termHeight-3, termWidth, termHeight)
+		termui.Render(plot, velocity, help)
+
+		e := <-uiEvents
+		switch e.ID {
+		case "q", "<Escape>":
+			return
+		case "x":
+			files, err := exportStats(project, now)
+			if err != nil {
+				help.Text = fmt.Sprintf("Export failed: %v", err)
+			} else {
+				help.Text = fmt.Sprintf("Exported %s and %s  x: export CSV  q: close", files[0], files[1])
+			}
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package ui
+
+import (
+	"github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+)
+
+// Screens offered by the view switcher
+const (
+	screenBoard    = "Board"
+	screenCalendar = "Calendar"
+	screenAgenda   = "Agenda"
+	screenTimeline = "Timeline"
+	screenTime     = "Time report"
+	screenStats    = "Stats"
+	screenSprints  = "Sprints"
+	screenWorkload = "Workload"
+)
+
+var screens = []string{screenBoard, screenCalendar, screenAgenda, screenTimeline, screenTime, screenStats, screenSprints, screenWorkload}
+
+// showViewSwitcher lets the user pick a screen and returns its name, or an
+// empty string if the switcher was closed without choosing one
+func showViewSwitcher(uiEvents <-chan termui.Event) string {
+	menu := widgets.NewList()
+	menu.Title = "Switch view"
+	menu.Rows = screens
+	menu.SelectedRowStyle = termui.NewStyle(termui.ColorBlack, termui.ColorYellow)
+
+	termWidth, termHeight := termui.TerminalDimensions()
+	menu.SetRect(termWidth/3, termHeight/4, 2*termWidth/3, termHeight/4+len(screens)+2)
+
+	for {
+		termui.Render(menu)
+		e := <-uiEvents
+		switch e.ID {
+		case "q", "<Escape>", "<C-v>":
+			return ""
+		case "j", "<Down>":
+			menu.ScrollDown()
+		case "k", "<Up>":
+			menu.ScrollUp()
+		case "<Enter>":
+			return screens[menu.SelectedRow]
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package ui
+
+import (
+	"Termile/internal/task"
+	"fmt"
+	"image"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+)
+
+// timelineRow is a project header or a task bar of the timeline
+type timelineRow struct {
+	label     string
+	projectID int
+	task      *task.Task
+	start     time.Time
+	end       time.Time
+}
+
+// timeline draws tasks as horizontal bars against a day scale
+type timeline struct {
+	termui.Block
+	rows       []timelineRow
+	origin     time.Time // day of the first chart column
+	dayWidth   int       // columns per day
+	labelWidth int
+	selected   int
+	offset     int // first visible row
+}
+
+func newTimeline() *timeline {
+	return &timeline{
+		Block:      *termui.NewBlock(),
+		dayWidth:   2,
+		labelWidth: 24,
+	}
+}
+
+// column returns the x coordinate of a day relative to the chart area. Days
+// are counted between calendar dates, so that the 23 and 25 hour days of a
+// DST change count as one
+func (tl *timeline) column(day time.Time) int {
+	from := time.Date(tl.origin.Year(), tl.origin.Month(), tl.origin.Day(), 0, 0, 0, 0, time.UTC)
+	day = day.In(tl.origin.Location())
+	to := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	return int(to.Sub(from)/(24*time.Hour)) * tl.dayWidth
+}
+
+// rowIndex returns the row of a task within a project, or -1
+func (tl *timeline) rowIndex(projectID, taskID int) int {
+	for i, row := range tl.rows {
+		if row.task != nil && row.projectID == projectID && row.task.ID == taskID {
+			return i
+		}
+	}
+	return -1
+}
+
+func (tl *timeline) Draw(buf *termui.Buffer) {
+	tl.Block.Draw(buf)
+
+	chartX := tl.Inner.Min.X + tl.labelWidth + 1
+	chart := image.Rect(chartX, tl.Inner.Min.Y+1, tl.Inner.Max.X, tl.Inner.Max.Y)
+	visibleRows := chart.Dy()
+	if tl.selected < tl.offset {
+		tl.offset = tl.selected
+	} else if tl.selected >= tl.offset+visibleRows {
+		tl.offset = tl.selected - visibleRows + 1
+	}
+	y := func(row int) int { return chart.Min.Y + row - tl.offset }
+	visible := func(x, row int) bool {
+		return image.Pt(x, y(row)).In(chart)
+	}
+
+	// Date scale with a tick every Monday
+	for day := tl.origin; chartX+tl.column(day) < chart.Max.X; day = day.AddDate(0, 0, 1) {
+		if day.Weekday() == time.Monday {
+			buf.SetString(day.Format("Jan 2"), termui.NewStyle(termui.ColorWhite), image.Pt(chartX+tl.column(day), tl.Inner.Min.Y))
+		}
+	}
+
+	// Today marker
+	now := time.Now()
+	todayX := chartX + tl.column(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()))
+	if todayX >= chart.Min.X && todayX < chart.Max.X {
+		buf.Fill(termui.NewCell('│', termui.NewStyle(termui.ColorRed)), image.Rect(todayX, chart.Min.Y, todayX+1, chart.Max.Y))
+	}
+
+	for i, row := range tl.rows {
+		if y(i) < chart.Min.Y || y(i) >= chart.Max.Y {
+			continue
+		}
+		labelStyle := termui.NewStyle(termui.ColorWhite)
+		if row.task == nil {
+			labelStyle = termui.NewStyle(termui.ColorYellow, termui.ColorClear, termui.ModifierBold)
+		}
+		if i == tl.selected {
+			labelStyle = termui.NewStyle(termui.ColorBlack, termui.ColorYellow)
+		}
+		label := []rune(row.label)
+		if len(label) > tl.labelWidth {
+			label = append(label[:tl.labelWidth-1], '…')
+		}
+		buf.SetString(string(label), labelStyle, image.Pt(tl.Inner.Min.X, y(i)))
+		if row.task == nil {
+			continue
+		}
+
+		barStyle := termui.NewStyle(termui.ColorCyan)
+		switch {
+		case row.task.Complete:
+			barStyle = termui.NewStyle(termui.ColorGreen)
+		case row.task.IsOverdue(now):
+			barStyle = termui.NewStyle(termui.ColorRed)
+		}
+		for x := chartX + tl.column(row.start); x < chartX+tl.column(row.end)+tl.dayWidth; x++ {
+			if visible(x, i) {
+				buf.SetCell(termui.NewCell('█', barStyle), image.Pt(x, y(i)))
+			}
+		}
+
+		// Dependency arrows run down from the end of the dependency's bar and
+		// across to the start of the dependent bar
+		arrowStyle := termui.NewStyle(termui.ColorMagenta)
+		startX := chartX + tl.column(row.start)
+		for _, dependencyID := range row.task.DependsOn {
+			from := tl.rowIndex(row.projectID, dependencyID)
+			if from == -1 {
+				continue
+			}
+			endX := chartX + tl.column(tl.rows[from].end) + tl.dayWidth - 1
+			if endX >= startX {
+				// The task starts before its dependency ends
+				if visible(startX, i) {
+					buf.SetCell(termui.NewCell('!', termui.NewStyle(termui.ColorRed, termui.ColorClear, termui.ModifierBold)), image.Pt(startX, y(i)))
+				}
+				continue
+			}
+			step := 1
+			if from > i {
+				step = -1
+			}
+			for r := from + step; r != i; r += step {
+				if visible(endX, r) && buf.GetCell(image.Pt(endX, y(r))).Rune != '█' {
+					buf.SetCell(termui.NewCell('│', arrowStyle), image.Pt(endX, y(r)))
+				}
+			}
+			corner := '└'
+			if step == -1 {
+				corner = '┌'
+			}
+			if visible(endX, i) {
+				buf.SetCell(termui.NewCell(corner, arrowStyle), image.Pt(endX, y(i)))
+			}
+			for x := endX + 1; x < startX-1; x++ {
+				if visible(x, i) {
+					buf.SetCell(termui.NewCell('─', arrowStyle), image.Pt(x, y(i)))
+				}
+			}
+			if visible(startX-1, i) {
+				buf.SetCell(termui.NewCell('▶', arrowStyle), image.Pt(startX-1, y(i)))
+			}
+		}
+	}
+}
+
+// buildTimelineRows lists every project followed by its tasks that have dates
+func buildTimelineRows(tm *task.TaskManager) []timelineRow {
+	rows := []timelineRow{}
+	for _, project := range tm.ListProjects() {
+		rows = append(rows, timelineRow{label: project.Name, projectID: project.ID})
+		for i := range project.Tasks {
+			t := project.Tasks[i]
+			start, end, ok := t.Span()
+			if !ok {
+				continue
+			}
+			rows = append(rows, timelineRow{
+				label:     fmt.Sprintf("  %d. %s", t.ID, t.Title),
+				projectID: project.ID,
+				task:      &t,
+				start:     start,
+				end:       end,
+			})
+		}
+	}
+	return rows
+}
+
+// showTimeline displays a scrollable timeline of all projects until it is closed
+func showTimeline(tm *task.TaskManager, uiEvents <-chan termui.Event) {
+	tl := newTimeline()
+	tl.Title = "Timeline"
+	now := time.Now()
+	tl.origin = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, -14)
+
+	help := widgets.NewParagraph()
+	help.Text = "j/k: task  h/l: scroll  +/-: zoom  t: today  s: start date  d: due date  p: depends on  q: close"
+
+	for {
+		tl.rows = buildTimelineRows(tm)
+		if tl.selected >= len(tl.rows) {
+			tl.selected = len(tl.rows) - 1
+		}
+
+		termui.Clear()
+		termWidth, termHeight := termui.TerminalDimensions()
+		tl.SetRect(0, 0, termWidth, termHeight-3)
+		help.SetRect(0, termHeight-3, termWidth, termHeight)
+		termui.Render(tl, help)
+
+		e := <-uiEvents
+		help.Text = "j/k: task  h/l: scroll  +/-: zoom  t: today  s: start date  d: due date  p: depends on  q: close"
+		var selected *timelineRow
+		if tl.selected >= 0 && tl.selected < len(tl.rows) && tl.rows[tl.selected].task != nil {
+			selected = &tl.rows[tl.selected]
+		}
+		switch e.ID {
+		case "q", "<Escape>":
+			return
+		case "j", "<Down>":
+			if tl.selected < len(tl.rows)-1 {
+				tl.selected++
+			}
+		case "k", "<Up>":
+			if tl.selected > 0 {
+				tl.selected--
+			}
+		case "h", "<Left>":
+			tl.origin = tl.origin.AddDate(0, 0, -7)
+		case "l", "<Right>":
+			tl.origin = tl.origin.AddDate(0, 0, 7)
+		case "+":
+			if tl.dayWidth < 6 {
+				tl.dayWidth++
+			}
+		case "-":
+			if tl.dayWidth > 1 {
+				tl.dayWidth--
+			}
+		case "t":
+			tl.origin = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, -14)
+		case "s", "d":
+			if selected == nil {
+				break
+			}
+			title, current, set := "Start date", selected.task.Start, tm.SetTaskStart
+			if e.ID == "d" {
+				title, current, set = "Due date", selected.task.Due, tm.SetTaskDue
+			}
+			initial := ""
+			if current != nil {
+				initial = current.Format(task.DateLayout)
+			}
+			text, ok := promptInput(uiEvents, title+" (YYYY-MM-DD, today, +3d, empty to clear)", initial)
+			if !ok {
+				break
+			}
+			date, err := task.ParseDate(text, time.Now())
+			if err == nil {
+				err = set(selected.projectID, selected.task.ID, date)
+			}
+			if err != nil {
+				help.Text = err.Error()
+			}
+		case "p":
+			if selected == nil {
+				break
+			}
+			initial := []string{}
+			for _, id := range selected.task.DependsOn {
+				initial = append(initial, strconv.Itoa(id))
+			}
+			text, ok := promptInput(uiEvents, "Depends on task IDs (comma separated)", strings.Join(initial, ", "))
+			if !ok {
+				break
+			}
+			dependsOn := []int{}
+			var err error
+			for _, field := range strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == ' ' }) {
+				id, convErr := strconv.Atoi(field)
+				if convErr != nil {
+					err = fmt.Errorf("invalid task ID %q", field)
+					break
+				}
+				dependsOn = append(dependsOn, id)
+			}
+			if err == nil {
+				err = tm.SetTaskDependencies(selected.projectID, selected.task.ID, dependsOn)
+			}
+			if err != nil {
+				help.Text = err.Error()
+			}
+		}
+	}
+}
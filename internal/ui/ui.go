@@ -9,12 +9,15 @@ import (
 	"log"
 	"math"
 	"strings"
+	"time"
 )
 
-const projectFile = "projects.json"
+const historyFile = "history.jsonl"
 
-// StartUI starts the terminal UI for task and subtask management
-func StartUI(tm *task.TaskManager) {
+// StartUI starts the terminal UI for task and subtask management. The state
+// of tm is saved to store once no change was made for autosaveDelay, unless
+// it is zero, and reloaded when another process changes the files of store
+func StartUI(tm *task.TaskManager, store *storage.Store, autosaveDelay time.Duration) {
 	if err := termui.Init(); err != nil {
 		fmt.Printf("failed to initialize termui: %v", err)
 		return
@@ -79,28 +82,57 @@ func StartUI(tm *task.TaskManager) {
 	projectList.Title = "Projects"
 	projectList.SelectedRowStyle = termui.NewStyle(termui.ColorGreen)
 
+	viewList := widgets.NewList()
+	viewList.Title = "Views"
+	viewList.SelectedRowStyle = termui.NewStyle(termui.ColorMagenta)
+
+	// Comment thread of the selected task or subtask
+	commentList := widgets.NewList()
+	commentList.Title = "Comments"
+	commentList.WrapText = true
+	commentList.SelectedRowStyle = termui.NewStyle(termui.ColorWhite)
+
+	// Lead and cycle times, shown in place of the pie chart on demand
+	flowPanel := widgets.NewParagraph()
+	flowPanel.Title = "⏱ Lead & Cycle Time ⏱"
+	flowPanel.BorderStyle = termui.NewStyle(termui.ColorCyan)
+	flowPanel.TitleStyle = termui.NewStyle(termui.ColorMagenta, termui.ColorClear, termui.ModifierBold)
+	showFlow := false
+
+	// Whether the changes are saved
+	statusLine := widgets.NewParagraph()
+	statusLine.Title = "Status"
+
 	// Create a grid and arrange widgets
 	grid := termui.NewGrid()
 	termWidth, termHeight := termui.TerminalDimensions()
 	grid.SetRect(0, 0, termWidth, termHeight)
 
-	grid.Set(
-		termui.NewRow(1.0,
-			termui.NewCol(0.25,
-				termui.NewRow(0.9, projectList),
-				termui.NewRow(0.1, taskInput), // Adjust proportions as needed
+	// setLayout arranges the widgets, with chart in the bottom right corner
+	setLayout := func(chart termui.Drawable) {
+		grid.Items = nil
+		grid.Set(
+			termui.NewRow(1.0,
+				termui.NewCol(0.25,
+					termui.NewRow(0.5, projectList),
+					termui.NewRow(0.3, viewList),
+					termui.NewRow(0.1, taskInput), // Adjust proportions as needed
+					termui.NewRow(0.1, statusLine),
+				),
+				termui.NewCol(0.35,
+					termui.NewRow(0.5, taskList),
+					termui.NewRow(0.5, subtaskList),
+				),
+				termui.NewCol(0.4,
+					termui.NewRow(0.2, description),
+					termui.NewRow(0.2, commentList),
+					termui.NewRow(0.2, gauge),
+					termui.NewRow(0.4, chart),
+				),
 			),
-			termui.NewCol(0.35,
-				termui.NewRow(0.5, taskList),
-				termui.NewRow(0.5, subtaskList),
-			),
-			termui.NewCol(0.4,
-				termui.NewRow(0.3, description),
-				termui.NewRow(0.3, gauge),
-				termui.NewRow(0.4, pieChart),
-			),
-		),
-	)
+		)
+	}
+	setLayout(pieChart)
 
 	// Event handling and other logic remains the same...
 	// (You can reuse your existing event loop code here)
@@ -117,6 +149,10 @@ func StartUI(tm *task.TaskManager) {
 	selectedProjectID := -1 // ID of the currently selected project
 	selectedTaskID := -1    // ID of the currently selected task
 	inProjectMode := true
+	inViewMode := false
+	selectedViewIndex := 0
+	selectedViewTaskIndex := 0
+	pendingViewName := ""
 
 	projects := tm.ListProjects()
 	if len(projects) > 0 {
@@ -126,23 +162,103 @@ func StartUI(tm *task.TaskManager) {
 	}
 
 	updateProjectList(projectList, tm, 0)
+	updateViewList(viewList, tm, selectedViewIndex)
 	updateTaskList(taskList, tm, selectedProjectID)
 	updateSubtaskList(subtaskList, tm, selectedProjectID, selectedTaskID)
 	updateBarChart(barChart, tm, selectedProjectID)
 	updatePieChart(pieChart, tm, selectedProjectID)
 	updateGauge(gauge, tm, selectedProjectID, selectedTaskID)
 	updateDescription(description, tm, selectedProjectID, selectedTaskIndex, selectedSubtaskIndex, inSubtaskMode)
+	var saveErr error // Error of the last save, if it failed
+	var vetoErr error // Error of the hook that vetoed the last change, if any
+	tm.OnVeto(func(err error) {
+		vetoErr = err
+	})
+	updateSaveStatus(statusLine, tm, saveErr, vetoErr)
 	termui.Render(grid)
 
+	// Refresh the running timer once a second
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	// Refresh only the widgets whose data changed or selection moved
+	var shown selection
+	stale := watchChanges(tm.Bus(), &shown)
+
+	// Save once no change was made for autosaveDelay
+	var autosave <-chan time.Time
+	revision := tm.Revision()
+	save := func() error { return saveStore(uiEvents, store) }
+
 	for {
-		e := <-uiEvents
+		var e termui.Event
+		select {
+		case e = <-uiEvents:
+		case <-ticker.C:
+			if _, running := tm.RunningTimer(); running && !typingMode {
+				updateTimerIndicator(taskInput, tm)
+				termui.Render(taskInput)
+			}
+			// Bring in the changes other processes made to the files
+			if modified, err := store.Modified(); err != nil || !modified {
+				continue
+			}
+			saveErr = mergeExternalChanges(uiEvents, store)
+			// Keep the selection where it still exists
+			projects := tm.ListProjects()
+			found := false
+			for i, p := range projects {
+				if p.ID == selectedProjectID {
+					selectedProjectIndex, found = i, true
+				}
+			}
+			if !found {
+				selectedProjectIndex, selectedProjectID, selectedTaskIndex = 0, -1, 0
+				if len(projects) > 0 {
+					selectedProjectID = projects[0].ID
+				}
+			}
+			tasks := tm.ListTasks(selectedProjectID)
+			if selectedTaskIndex >= len(tasks) {
+				selectedTaskIndex = 0
+				selectedSubtaskIndex = 0
+			}
+			selectedTaskID = -1
+			if len(tasks) > 0 {
+				selectedTaskID = tasks[selectedTaskIndex].ID
+			}
+			if selectedSubtaskIndex >= len(tm.ListSubtasks(selectedProjectID, selectedTaskID)) {
+				selectedSubtaskIndex = 0
+			}
+			updateProjectList(projectList, tm, selectedProjectIndex)
+			// e is left empty: the event handling below only redraws
+		case <-autosave:
+			autosave = nil
+			if tm.Dirty() {
+				saveErr = save()
+			}
+			updateSaveStatus(statusLine, tm, saveErr, vetoErr)
+			termui.Render(statusLine)
+			continue
+		}
 
 		switch e.ID {
 		case "<C-q>", "<C-c>":
+			if saveErr != nil && tm.Dirty() {
+				quit, err := confirmQuit(uiEvents, save, saveErr)
+				saveErr = err
+				if !quit {
+					updateSaveStatus(statusLine, tm, saveErr, vetoErr)
+					termui.Clear()
+					termui.Render(grid)
+					continue
+				}
+			}
 			return // Exit the app
 
 		case "<C-p>": // Switch to project mode
 			inProjectMode = true
+			inViewMode = false
 			inSubtaskMode = false
 			updateProjectList(projectList, tm, 0)
 			termui.Render(projectList)
@@ -158,9 +274,17 @@ func StartUI(tm *task.TaskManager) {
 				termui.Render(taskInput)
 			}
 		case "<C-x>":
-			if err := storage.SaveProjects(projectFile, tm.ListProjects()); err != nil {
-				log.Printf("failed to save projects: %v", err)
+			saveErr = save()
+
+		case "<C-f>": // Browse saved views, pressing again moves to the next view
+			if inViewMode && len(tm.ListViews()) > 0 {
+				selectedViewIndex = (selectedViewIndex + 1) % len(tm.ListViews())
 			}
+			inViewMode = true
+			inProjectMode = false
+			inSubtaskMode = false
+			selectedViewTaskIndex = 0
+			updateViewList(viewList, tm, selectedViewIndex)
 
 		case "<Backspace>": // Handle backspace during getTask input
 			if typingMode {
@@ -175,6 +299,154 @@ func StartUI(tm *task.TaskManager) {
 				}
 			}
 
+		case "<C-y>": // Comment on the selected task or subtask
+			if !inViewMode && !inSubtaskMode && len(tm.ListTasks(selectedProjectID)) > 0 ||
+				!inViewMode && inSubtaskMode && len(tm.ListSubtasks(selectedProjectID, selectedTaskID)) > 0 {
+				typingMode = true
+
+				inputState = "comment"
+				inputBuffer.Reset()
+				taskInput.Title = "Add comment"
+				taskInput.Text = ""
+				termui.Render(taskInput)
+			}
+
+		case "<PageDown>": // Scroll the comment thread
+			commentList.ScrollPageDown()
+
+		case "<PageUp>":
+			commentList.ScrollPageUp()
+
+		case "<M-h>": // Show the history of the selected task or subtask
+			if !inViewMode && !inSubtaskMode && len(tm.ListTasks(selectedProjectID)) > 0 {
+				showHistory(uiEvents, selectedProjectID, tm.ListTasks(selectedProjectID)[selectedTaskIndex].ID, 0)
+				termui.Clear()
+				termui.Render(grid)
+			} else if !inViewMode && inSubtaskMode && len(tm.ListSubtasks(selectedProjectID, selectedTaskID)) > 0 {
+				showHistory(uiEvents, selectedProjectID, selectedTaskID, tm.ListSubtasks(selectedProjectID, selectedTaskID)[selectedSubtaskIndex].ID)
+				termui.Clear()
+				termui.Render(grid)
+			}
+
+		case "<C-z>": // Toggle between the status pie chart and lead/cycle times
+			showFlow = !showFlow
+			if showFlow {
+				setLayout(flowPanel)
+			} else {
+				setLayout(pieChart)
+			}
+
+		case "<C-w>": // Show the kanban board of the selected project
+			if selectedProjectID != -1 {
+				showBoard(tm, uiEvents, selectedProjectID)
+				termui.Clear()
+				termui.Render(grid)
+			}
+
+		case "<C-v>": // Switch to another view
+			switch showViewSwitcher(uiEvents) {
+			case screenBoard:
+				if selectedProjectID != -1 {
+					showBoard(tm, uiEvents, selectedProjectID)
+				}
+			case screenCalendar:
+				showCalendar(tm, uiEvents)
+			case screenAgenda:
+				showAgenda(tm, uiEvents)
+			case screenTimeline:
+				showTimeline(tm, uiEvents)
+			case screenTime:
+				showTimeReport(tm, uiEvents)
+			case screenStats:
+				showStats(tm, uiEvents, selectedProjectID)
+			case screenSprints:
+				if selectedProjectID != -1 {
+					showSprints(tm, uiEvents, selectedProjectID)
+				}
+			case screenWorkload:
+				showWorkload(tm, uiEvents)
+			}
+			termui.Clear()
+			termui.Render(grid)
+
+		case "<C-u>": // Set the due date of the selected task
+			if !inViewMode && !inSubtaskMode && len(tm.ListTasks(selectedProjectID)) > 0 {
+				typingMode = true
+
+				inputState = "due"
+				inputBuffer.Reset()
+				selectedTask := tm.ListTasks(selectedProjectID)[selectedTaskIndex]
+				taskInput.Title = "Due date (YYYY-MM-DD, today, +3d, empty to clear)"
+				taskInput.Text = ""
+				if selectedTask.Due != nil {
+					taskInput.Text = selectedTask.Due.Format(task.DateLayout)
+				}
+				inputBuffer.WriteString(taskInput.Text)
+				termui.Render(taskInput)
+			}
+
+		case "<C-r>": // Start a timer on the selected task or subtask, or stop the running one
+			if _, running := tm.RunningTimer(); running {
+				if _, err := tm.StopTimer(); err != nil {
+					log.Printf("Error stopping timer: %v", err)
+				}
+			} else if !inViewMode && inSubtaskMode && len(tm.ListSubtasks(selectedProjectID, selectedTaskID)) > 0 {
+				subtask := tm.ListSubtasks(selectedProjectID, selectedTaskID)[selectedSubtaskIndex]
+				if err := tm.StartTimer(selectedProjectID, selectedTaskID, subtask.ID, ""); err != nil {
+					log.Printf("Error starting timer: %v", err)
+				}
+			} else if !inViewMode && !inSubtaskMode && len(tm.ListTasks(selectedProjectID)) > 0 {
+				selectedTask := tm.ListTasks(selectedProjectID)[selectedTaskIndex]
+				if err := tm.StartTimer(selectedProjectID, selectedTask.ID, 0, ""); err != nil {
+					log.Printf("Error starting timer: %v", err)
+				}
+			}
+
+		case "<C-n>": // Set the estimate unit of the project, or the estimate of the selected task or subtask
+			if inViewMode {
+				break
+			}
+			unit := task.UnitHours
+			if project, err := tm.GetProject(selectedProjectIndex); err == nil {
+				unit = project.Unit()
+			}
+			if inProjectMode && len(tm.ListProjects()) > 0 {
+				typingMode = true
+
+				inputState = "estimate_unit"
+				inputBuffer.Reset()
+				taskInput.Title = "Estimate unit (hours or points)"
+				taskInput.Text = unit
+				inputBuffer.WriteString(taskInput.Text)
+				termui.Render(taskInput)
+			} else if !inSubtaskMode && len(tm.ListTasks(selectedProjectID)) > 0 {
+				typingMode = true
+
+				inputState = "estimate"
+				inputBuffer.Reset()
+				selectedTask := tm.ListTasks(selectedProjectID)[selectedTaskIndex]
+				taskInput.Title = fmt.Sprintf("Estimate task in %s", unit)
+				taskInput.Text = ""
+				if selectedTask.Estimate > 0 {
+					taskInput.Text = fmt.Sprint(selectedTask.Estimate)
+				}
+				inputBuffer.WriteString(taskInput.Text)
+				termui.Render(taskInput)
+			} else if inSubtaskMode && len(tm.ListSubtasks(selectedProjectID, selectedTaskID)) > 0 {
+				typingMode = true
+
+				inputState = "estimate"
+				inputBuffer.Reset()
+				selectedSubtask := tm.ListSubtasks(selectedProjectID, selectedTaskID)[selectedSubtaskIndex]
+				taskInput.Title = fmt.Sprintf("Estimate subtask in %s", unit)
+				taskInput.Text = ""
+				if selectedSubtask.Estimate > 0 {
+					taskInput.Text = fmt.Sprint(selectedSubtask.Estimate)
+				}
+				inputBuffer.WriteString(taskInput.Text)
+				termui.Render(taskInput)
+			}
+
 		case "<C-b>":
 			showTaskTreeModal(tm)
 			termui.Clear() // Clear the screen after closing the tree modal
@@ -229,7 +501,14 @@ func StartUI(tm *task.TaskManager) {
 			}
 
 		case "<C-d>": // Delete the selected getTask or subtask
-			if inProjectMode && len(tm.ListProjects()) > 0 {
+			if inViewMode && len(tm.ListViews()) > 0 {
+				tm.RemoveView(selectedViewIndex)
+				if selectedViewIndex >= len(tm.ListViews()) && selectedViewIndex > 0 {
+					selectedViewIndex--
+				}
+				selectedViewTaskIndex = 0
+				updateViewList(viewList, tm, selectedViewIndex)
+			} else if inProjectMode && len(tm.ListProjects()) > 0 {
 				if selectedProjectIndex >= 0 && selectedProjectIndex < len(tm.ListProjects()) {
 					selectedProjectID = tm.ListProjects()[selectedProjectIndex].ID
 					projectList.SelectedRow = selectedProjectIndex
@@ -439,6 +718,93 @@ func StartUI(tm *task.TaskManager) {
 						inSubtaskMode = false
 					}
 
+				case "view_name":
+					if err := tm.ValidateViewName(inputText); err != nil {
+						taskInput.Title = err.Error()
+						break
+					}
+					pendingViewName = inputText
+					inputState = "view_query"
+					inputBuffer.Reset()
+					taskInput.Title = "Enter view query"
+					taskInput.Text = ""
+
+				case "view_query":
+					if _, err := task.ParseFilter(inputText, time.Now()); err != nil {
+						taskInput.Title = fmt.Sprintf("Invalid query: %v", err)
+						break
+					}
+					if err := tm.AddView(task.View{Name: pendingViewName, Query: inputText}); err != nil {
+						taskInput.Title = err.Error()
+						break
+					}
+					selectedViewIndex = len(tm.ListViews()) - 1
+					selectedViewTaskIndex = 0
+					updateViewList(viewList, tm, selectedViewIndex)
+
+					// Reset input states
+					typingMode = false
+
+					inputState = ""
+					inputBuffer.Reset()
+					taskInput.Text = ""
+					taskInput.Title = "Input"
+
+				case "due":
+					due, err := task.ParseDate(inputText, time.Now())
+					if err != nil {
+						taskInput.Title = err.Error()
+						break
+					}
+					selectedTask := tm.ListTasks(selectedProjectID)[selectedTaskIndex]
+					if err := tm.SetTaskDue(selectedProjectID, selectedTask.ID, due); err != nil {
+						log.Printf("Error setting due date: %v", err)
+					}
+
+					// Reset input states
+					typingMode = false
+
+					inputState = ""
+					inputBuffer.Reset()
+					taskInput.Text = ""
+					taskInput.Title = "Input"
+
+				case "comment":
+					var err error
+					if inSubtaskMode {
+						subtask := tm.ListSubtasks(selectedProjectID, selectedTaskID)[selectedSubtaskIndex]
+						_, err = tm.AddSubtaskComment(selectedProjectID, selectedTaskID, subtask.ID, "", inputText, time.Now())
+					} else {
+						selectedTask := tm.ListTasks(selectedProjectID)[selectedTaskIndex]
+						_, err = tm.AddComment(selectedProjectID, selectedTask.ID, "", inputText, time.Now())
+					}
+					if err != nil {
+						log.Printf("Error adding comment: %v", err)
+					}
+					commentList.ScrollBottom()
+
+				case "estimate_unit":
+					if err := tm.SetEstimateUnit(selectedProjectID, strings.ToLower(inputText)); err != nil {
+						log.Printf("Error setting estimate unit: %v", err)
+					}
+
+				case "estimate":
+					estimate, err := task.ParseEstimate(inputText)
+					if err != nil {
+						log.Printf("Error setting estimate: %v", err)
+						break
+					}
+					if !inSubtaskMode {
+						selectedTask := tm.ListTasks(selectedProjectID)[selectedTaskIndex]
+						err = tm.SetTaskEstimate(selectedProjectID, selectedTask.ID, estimate)
+					} else {
+						selectedSubtask := tm.ListSubtasks(selectedProjectID, selectedTaskID)[selectedSubtaskIndex]
+						err = tm.SetSubtaskEstimate(selectedProjectID, selectedTaskID, selectedSubtask.ID, estimate)
+					}
+					if err != nil {
+						log.Printf("Error setting estimate: %v", err)
+					}
+
 				case "getTask":
 					if inputText != "" && selectedProjectID != -1 {
 						newTask := task.Task{
@@ -480,7 +846,7 @@ func StartUI(tm *task.TaskManager) {
 				}
 
 				// After handling inputState, perform common resets and updates if not already done
-				if inputState != "edit_project_description" && inputState != "project" && inputState != "getTask" && inputState != "subtask" {
+				if inputState != "edit_project_description" && inputState != "project" && inputState != "getTask" && inputState != "subtask" && inputState != "view_query" && inputState != "due" {
 					typingMode = false
 
 					inputState = ""
@@ -506,7 +872,10 @@ func StartUI(tm *task.TaskManager) {
 			typingMode = true
 
 			inputBuffer.Reset()
-			if inProjectMode {
+			if inViewMode {
+				inputState = "view_name"
+				taskInput.Title = "Enter new view name"
+			} else if inProjectMode {
 				inputState = "project"
 				taskInput.Title = "Enter new project name"
 			} else if !inSubtaskMode {
@@ -520,7 +889,12 @@ func StartUI(tm *task.TaskManager) {
 			termui.Render(taskInput)
 
 		case "<C-j>", "<Down>": // Move selection down
-			if inProjectMode && len(tm.ListProjects()) > 0 && selectedProjectIndex < len(tm.ListProjects())-1 {
+			if inViewMode {
+				refs, _ := viewTasks(tm, selectedViewIndex)
+				if selectedViewTaskIndex < len(refs)-1 {
+					selectedViewTaskIndex++
+				}
+			} else if inProjectMode && len(tm.ListProjects()) > 0 && selectedProjectIndex < len(tm.ListProjects())-1 {
 				selectedProjectIndex++
 				projectList.SelectedRow = selectedProjectIndex
 				selectedProjectID = tm.ListProjects()[selectedProjectIndex].ID
@@ -577,7 +951,11 @@ func StartUI(tm *task.TaskManager) {
 			}
 
 		case "<C-k>", "<Up>": // Move selection up
-			if inProjectMode && len(tm.ListProjects()) > 0 && selectedProjectIndex > 0 {
+			if inViewMode {
+				if selectedViewTaskIndex > 0 {
+					selectedViewTaskIndex--
+				}
+			} else if inProjectMode && len(tm.ListProjects()) > 0 && selectedProjectIndex > 0 {
 				selectedProjectIndex--
 				projectList.SelectedRow = selectedProjectIndex
 				selectedProjectID = tm.ListProjects()[selectedProjectIndex].ID
@@ -616,6 +994,7 @@ func StartUI(tm *task.TaskManager) {
 
 		case "<C-t>": // Switch to getTask mode
 			inProjectMode = false
+			inViewMode = false
 			inSubtaskMode = false
 			// Optionally reset selected indices
 			selectedTaskIndex = 0
@@ -631,7 +1010,13 @@ func StartUI(tm *task.TaskManager) {
 			termui.Render(taskList, subtaskList, taskInput)
 
 		case "<C-g>": // Toggle getTask completion (mark as done/undone)
-			if inSubtaskMode && len(tm.ListSubtasks(selectedProjectID, tm.ListTasks(selectedProjectID)[selectedTaskIndex].ID)) > 0 {
+			if inViewMode {
+				refs, _ := viewTasks(tm, selectedViewIndex)
+				if selectedViewTaskIndex < len(refs) {
+					ref := refs[selectedViewTaskIndex]
+					tm.ToggleComplete(ref.ProjectID, ref.Task.ID)
+				}
+			} else if inSubtaskMode && len(tm.ListSubtasks(selectedProjectID, tm.ListTasks(selectedProjectID)[selectedTaskIndex].ID)) > 0 {
 				// Subtask mode: Toggle the selected subtask's completion status
 				tm.ToggleSubtaskComplete(
 					selectedProjectID,
@@ -650,14 +1035,14 @@ func StartUI(tm *task.TaskManager) {
 				termui.Render(taskList)
 			}
 
-		case "<C-m>": // Assign to someone
+		case "<C-m>", "<M-a>": // Assign to someone; most terminals send Ctrl-m as Enter
 			if !inSubtaskMode && len(tm.ListTasks(selectedProjectID)) > 0 {
 				typingMode = true
 
 				inputState = "assign"
 				inputBuffer.Reset()
 				selectedTask := tm.ListTasks(selectedProjectID)[selectedTaskIndex]
-				taskInput.Title = "Assign getTask to"
+				taskInput.Title = "Assign getTask to (Tab completes, commas separate people)"
 				taskInput.Text = selectedTask.AssignedTo
 				inputBuffer.WriteString(taskInput.Text)
 				termui.Render(taskInput)
@@ -667,7 +1052,7 @@ func StartUI(tm *task.TaskManager) {
 				inputState = "assign"
 				inputBuffer.Reset()
 				selectedSubtask := tm.ListSubtasks(selectedProjectID, selectedTaskID)[selectedSubtaskIndex]
-				taskInput.Title = "Assign subtask to"
+				taskInput.Title = "Assign subtask to (Tab completes, commas separate people)"
 				taskInput.Text = selectedSubtask.AssignedTo
 				inputBuffer.WriteString(taskInput.Text)
 				termui.Render(taskInput)
@@ -680,7 +1065,16 @@ func StartUI(tm *task.TaskManager) {
 					case "<Space>":
 						inputBuffer.WriteString(" ")
 					case "<Tab>":
-						inputBuffer.WriteString("\t")
+						if inputState != "assign" {
+							inputBuffer.WriteString("\t")
+							break
+						}
+						completed, candidates := completeAssignee(tm, inputBuffer.String())
+						inputBuffer.Reset()
+						inputBuffer.WriteString(completed)
+						if len(candidates) > 1 {
+							taskInput.Title = "Assign to: " + strings.Join(candidates, ", ")
+						}
 					case "<Backspace>":
 						// Handled in a separate case
 					case "<Enter>":
@@ -698,14 +1092,55 @@ func StartUI(tm *task.TaskManager) {
 		}
 
 		termui.Render(taskList, subtaskList, taskInput)
-		// After any update
-		updateTaskList(taskList, tm, selectedProjectID)
-		if len(tm.ListTasks(selectedProjectID)) > 0 {
-			updateSubtaskList(subtaskList, tm, selectedProjectID, selectedTaskID)
-			updateGauge(gauge, tm, selectedProjectID, selectedTaskID)
+		// After any update, refresh what the selection or the changes made
+		// stale
+		if current := (selection{selectedProjectIndex, selectedProjectID, selectedTaskIndex, selectedTaskID, selectedSubtaskIndex,
+			selectedViewIndex, selectedViewTaskIndex, inProjectMode, inSubtaskMode, inViewMode, showFlow}); current != shown {
+			shown = current
+			stale.markAll()
 		}
-		updatePieChart(pieChart, tm, selectedProjectID)
-		updateDescription(description, tm, selectedProjectID, selectedTaskIndex, selectedSubtaskIndex, inSubtaskMode)
+		if _, running := tm.RunningTimer(); running {
+			// Tracked times grow with the running timer
+			stale.markAll()
+		}
+		if stale.projects {
+			updateProjectList(projectList, tm, selectedProjectIndex)
+		}
+		if stale.tasks {
+			if inViewMode {
+				selectedViewTaskIndex = updateViewTaskList(taskList, tm, selectedViewIndex, selectedViewTaskIndex)
+				shown.viewTaskIndex = selectedViewTaskIndex
+			} else {
+				updateTaskList(taskList, tm, selectedProjectID)
+			}
+			updatePieChart(pieChart, tm, selectedProjectID)
+			if showFlow {
+				updateFlowPanel(flowPanel, tm, selectedProjectID)
+			}
+		}
+		if stale.views {
+			updateViewList(viewList, tm, selectedViewIndex)
+		}
+		if stale.subtasks {
+			if len(tm.ListTasks(selectedProjectID)) > 0 {
+				updateSubtaskList(subtaskList, tm, selectedProjectID, selectedTaskID)
+				updateGauge(gauge, tm, selectedProjectID, selectedTaskID)
+			}
+			updateDescription(description, tm, selectedProjectID, selectedTaskIndex, selectedSubtaskIndex, inSubtaskMode)
+			updateComments(commentList, tm, selectedProjectID, selectedTaskIndex, selectedSubtaskIndex, inSubtaskMode)
+		}
+		*stale = staleWidgets{}
+		if !typingMode {
+			updateTimerIndicator(taskInput, tm)
+		}
+		if tm.Revision() != revision {
+			revision = tm.Revision()
+			vetoErr = nil
+			if autosaveDelay > 0 {
+				autosave = time.After(autosaveDelay)
+			}
+		}
+		updateSaveStatus(statusLine, tm, saveErr, vetoErr)
 		termWidth, termHeight = termui.TerminalDimensions()
 		grid.SetRect(0, 0, termWidth, termHeight)
 		termui.Clear()
@@ -720,14 +1155,21 @@ func updateTaskList(taskList *widgets.List, tm *task.TaskManager, projectID int)
 		taskList.Rows = []string{"No tasks available"}
 		return
 	}
+	taskList.Title = "Tasks"
+	if comparison := tm.ProjectEstimate(projectID, time.Now()); comparison.Estimate > 0 {
+		taskList.Title = "Tasks · " + comparison.String()
+	}
 	tasks := tm.ListTasks(projectID)
+	statuses := tm.Workflow(projectID)
 	rows := []string{}
 	for _, task := range tasks {
 		status := "[ ]"
 		if task.Complete {
 			status = "[x]"
+		} else if task.Status != statuses[0].Name {
+			status = fmt.Sprintf("[~] (%s)", task.Status)
 		}
-		rows = append(rows, fmt.Sprintf("%d. %s %s (Assigned to: %s)", task.ID, status, task.Title, task.AssignedTo))
+		rows = append(rows, fmt.Sprintf("%d. %s %s%s (Assigned to: %s)%s", task.ID, status, task.Title, formatDue(task), formatAssignees(tm, task.AssignedTo), formatTrackedTime(task.TrackedTime(time.Now()))))
 	}
 	taskList.Rows = rows
 
@@ -736,11 +1178,69 @@ func updateTaskList(taskList *widgets.List, tm *task.TaskManager, projectID int)
 	}
 }
 
+// updateViewList updates the sidebar list of saved views
+func updateViewList(viewList *widgets.List, tm *task.TaskManager, selectedViewIndex int) {
+	rows := []string{}
+	for _, view := range tm.ListViews() {
+		rows = append(rows, view.Name)
+	}
+	if len(rows) == 0 {
+		viewList.Rows = []string{"No views saved"}
+		viewList.SelectedRow = 0
+		return
+	}
+	viewList.Rows = rows
+	if selectedViewIndex >= 0 && selectedViewIndex < len(rows) {
+		viewList.SelectedRow = selectedViewIndex
+	}
+}
+
+// viewTasks evaluates a saved view against the current tasks
+func viewTasks(tm *task.TaskManager, viewIndex int) ([]task.TaskRef, error) {
+	views := tm.ListViews()
+	if viewIndex < 0 || viewIndex >= len(views) {
+		return nil, fmt.Errorf("view index %d out of range", viewIndex)
+	}
+	filter, err := task.ParseFilter(views[viewIndex].Query, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return tm.FindTasks(filter), nil
+}
+
+// updateViewTaskList fills the task list with the tasks matching a saved view
+// and returns the selected row clamped to the number of matches
+func updateViewTaskList(taskList *widgets.List, tm *task.TaskManager, viewIndex int, selectedRow int) int {
+	refs, err := viewTasks(tm, viewIndex)
+	if err != nil {
+		taskList.Title = "View"
+		taskList.Rows = []string{err.Error()}
+		taskList.SelectedRow = 0
+		return 0
+	}
+	taskList.Title = fmt.Sprintf("View: %s (%d)", tm.ListViews()[viewIndex].Name, len(refs))
+	rows := []string{}
+	for _, ref := range refs {
+		rows = append(rows, formatTaskRef(ref))
+	}
+	if len(rows) == 0 {
+		taskList.Rows = []string{"No matching tasks"}
+		taskList.SelectedRow = 0
+		return 0
+	}
+	taskList.Rows = rows
+	if selectedRow >= len(rows) {
+		selectedRow = len(rows) - 1
+	}
+	taskList.SelectedRow = selectedRow
+	return selectedRow
+}
+
 func updateProjectList(projectList *widgets.List, tm *task.TaskManager, selectedProjectIndex int) {
 	projects := tm.ListProjects()
 	rows := []string{}
 	for _, project := range projects {
-		rows = append(rows, fmt.Sprintf("%d. %s", project.ID, project.Name))
+		rows = append(rows, fmt.Sprintf("%d. %s%s", project.ID, project.Name, formatTrackedTime(tm.ProjectTime(project.ID, time.Now()))))
 	}
 	if len(rows) == 0 {
 		projectList.Rows = []string{"No projects available"}
@@ -768,7 +1268,7 @@ func updateSubtaskList(subtaskList *widgets.List, tm *task.TaskManager, projectI
 		if subtask.Complete {
 			status = "[x]"
 		}
-		rows = append(rows, fmt.Sprintf("%d. %s %s (Assigned to: %s)", subtask.ID, status, subtask.Title, subtask.AssignedTo))
+		rows = append(rows, fmt.Sprintf("%d. %s %s (Assigned to: %s)%s", subtask.ID, status, subtask.Title, formatAssignees(tm, subtask.AssignedTo), formatTrackedTime(subtask.TrackedTime(time.Now()))))
 	}
 	subtaskList.Rows = rows
 
@@ -777,6 +1277,26 @@ func updateSubtaskList(subtaskList *widgets.List, tm *task.TaskManager, projectI
 	}
 }
 
+// updateTimerIndicator shows the running timer in the title of the input box
+func updateTimerIndicator(taskInput *widgets.Paragraph, tm *task.TaskManager) {
+	timer, running := tm.RunningTimer()
+	if !running {
+		taskInput.Title = "Input"
+		taskInput.TitleStyle = termui.Theme.Block.Title
+		return
+	}
+	taskInput.Title = fmt.Sprintf("⏱ %s %s", task.FormatDuration(timer.Entry.Duration(time.Now())), timer.Title)
+	taskInput.TitleStyle = termui.NewStyle(termui.ColorRed, termui.ColorClear, termui.ModifierBold)
+}
+
+// formatTrackedTime formats logged time for list rows, omitting zero durations
+func formatTrackedTime(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return " ⏱ " + task.FormatDuration(d)
+}
+
 // updateBarChart updates the bar chart with current task completion statistics
 func updateBarChart(barChart *widgets.BarChart, tm *task.TaskManager, projectID int) {
 	if projectID == -1 {
@@ -896,6 +1416,10 @@ func updateDescription(description *widgets.Paragraph, tm *task.TaskManager, pro
 			if len(task.Subtasks) > subtaskIndex {
 				subtask := task.Subtasks[subtaskIndex]
 				description.Text = subtask.Description
+				comparison, err := tm.CompareSubtaskEstimate(projectID, task.ID, subtask.ID, time.Now())
+				if err == nil && (comparison.Estimate > 0 || comparison.Actual > 0) {
+					description.Text += "\n\n" + comparison.String()
+				}
 			}
 		} else {
 			description.Text = "No Subtask Selected"
@@ -904,12 +1428,43 @@ func updateDescription(description *widgets.Paragraph, tm *task.TaskManager, pro
 		if taskIndex >= 0 && len(tm.ListTasks(projectID)) > taskIndex {
 			task := tm.ListTasks(projectID)[taskIndex]
 			description.Text = task.Description
+			comparison, err := tm.CompareEstimate(projectID, task.ID, time.Now())
+			if err == nil && (comparison.Estimate > 0 || comparison.Actual > 0) {
+				description.Text += "\n\n" + comparison.String()
+			}
 		} else {
 			description.Text = "No Task Selected"
 		}
 	}
 }
 
+// updateComments shows the comment thread of the selected task or subtask
+func updateComments(commentList *widgets.List, tm *task.TaskManager, projectID int, taskIndex int, subtaskIndex int, inSubtaskMode bool) {
+	var comments []task.Comment
+	tasks := tm.ListTasks(projectID)
+	if taskIndex >= 0 && taskIndex < len(tasks) {
+		comments = tasks[taskIndex].Comments
+		if inSubtaskMode {
+			comments = nil
+			if subtaskIndex >= 0 && subtaskIndex < len(tasks[taskIndex].Subtasks) {
+				comments = tasks[taskIndex].Subtasks[subtaskIndex].Comments
+			}
+		}
+	}
+	commentList.Title = fmt.Sprintf("Comments (%d) · PgUp/PgDn", len(comments))
+	commentList.Rows = []string{}
+	for _, comment := range comments {
+		author := comment.Author
+		if author == "" {
+			author = "anonymous"
+		}
+		commentList.Rows = append(commentList.Rows, fmt.Sprintf("[%s %s](fg:cyan) %s", comment.CreatedAt.Format("Jan 2 15:04"), author, comment.Body))
+	}
+	if commentList.SelectedRow < 0 || commentList.SelectedRow >= len(commentList.Rows) {
+		commentList.SelectedRow = 0
+	}
+}
+
 // showHelpModal displays a modal with help information
 func showHelpModal() {
 	helpText := widgets.NewParagraph()
@@ -925,7 +1480,17 @@ func showHelpModal() {
 		"Ctrl+k / Up Arrow: Move selection up\n" +
 		"Ctrl+j / Down Arrow: Move selection down\n" +
 		"Ctrl+t: Toggle task or subtask completion\n" +
-		"Ctrl+b: Show task tree\n"
+		"Ctrl+b: Show task tree\n" +
+		"Ctrl+f: Browse saved views (again for next view)\n" +
+		"Ctrl+w: Show kanban board of the project\n" +
+		"Ctrl+u: Set due date of the selected task\n" +
+		"Ctrl+v: Switch view (board, calendar, agenda, timeline, time report, stats, sprints, workload)\n" +
+		"Ctrl+r: Start/stop a timer on the selected task or subtask\n" +
+		"Ctrl+n: Set estimate (or estimate unit in project mode)\n" +
+		"Ctrl+z: Toggle status chart / lead and cycle times\n" +
+		"Alt+a: Assign task or subtask (Tab completes names)\n" +
+		"Ctrl+y: Comment on the selected task or subtask (PgUp/PgDn scroll)\n" +
+		"Alt+h: History of the selected task or subtask\n"
 	helpText.WrapText = true
 
 	termWidth, termHeight := termui.TerminalDimensions()
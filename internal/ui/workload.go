@@ -0,0 +1,99 @@
+package ui
+
+import (
+	"Termile/internal/report"
+	"Termile/internal/task"
+	"fmt"
+	"time"
+
+	"github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+)
+
+// formatWorkloadRow summarises the open work of a person in one line
+func formatWorkloadRow(row report.WorkloadRow) string {
+	text := fmt.Sprintf("%-14s %3d open", row.Person, row.Open)
+	if row.Hours > 0 {
+		text += "  " + task.FormatEstimate(row.Hours, task.UnitHours)
+	}
+	if row.Points > 0 {
+		text += "  " + task.FormatEstimate(row.Points, task.UnitPoints)
+	}
+	if row.Overdue > 0 {
+		text += fmt.Sprintf("  [%d overdue](fg:red)", row.Overdue)
+	}
+	return text
+}
+
+// showWorkload lists the open work of each person, with the tasks of the
+// selected person across all projects, until it is closed
+func showWorkload(tm *task.TaskManager, uiEvents <-chan termui.Event) {
+	peopleList := widgets.NewList()
+	peopleList.Title = "Workload"
+	peopleList.SelectedRowStyle = termui.NewStyle(termui.ColorBlack, termui.ColorYellow)
+	taskList := widgets.NewList()
+	taskList.SelectedRowStyle = termui.NewStyle(termui.ColorBlack, termui.ColorYellow)
+	help := widgets.NewParagraph()
+	help.Text = "j/k: select person  Tab: browse their tasks  q: close"
+	inTasks := false
+
+	for {
+		now := time.Now()
+		rows := report.Workload(tm.ListProjects(), tm.ListPeople(), now)
+		peopleList.Rows = []string{}
+		for _, row := range rows {
+			peopleList.Rows = append(peopleList.Rows, formatWorkloadRow(row))
+		}
+		taskList.Rows = []string{}
+		taskList.Title = "Open tasks"
+		if len(rows) == 0 {
+			peopleList.Rows = []string{"Nothing is open"}
+		} else {
+			if peopleList.SelectedRow >= len(rows) {
+				peopleList.SelectedRow = len(rows) - 1
+			}
+			selected := rows[peopleList.SelectedRow]
+			taskList.Title = fmt.Sprintf("Open tasks of %s", selected.Person)
+			if selected.Name != "" {
+				taskList.Title = fmt.Sprintf("Open tasks of %s (%s)", selected.Name, selected.Person)
+			}
+			for _, ref := range selected.Tasks {
+				taskList.Rows = append(taskList.Rows, formatTaskRef(ref))
+			}
+		}
+		if taskList.SelectedRow < 0 || taskList.SelectedRow >= len(taskList.Rows) {
+			taskList.SelectedRow = 0
+		}
+		peopleList.BorderStyle = termui.NewStyle(termui.ColorYellow)
+		taskList.BorderStyle = termui.NewStyle(termui.ColorWhite)
+		if inTasks {
+			peopleList.BorderStyle, taskList.BorderStyle = taskList.BorderStyle, peopleList.BorderStyle
+		}
+
+		termui.Clear()
+		termWidth, termHeight := termui.TerminalDimensions()
+		peopleList.SetRect(0, 0, termWidth*2/5, termHeight-3)
+		taskList.SetRect(termWidth*2/5, 0, termWidth, termHeight-3)
+		help.SetRect(0, termHeight-3, termWidth, termHeight)
+		termui.Render(peopleList, taskList, help)
+
+		list := peopleList
+		if inTasks {
+			list = taskList
+		}
+		e := <-uiEvents
+		switch e.ID {
+		case "q", "<Escape>":
+			return
+		case "<Tab>":
+			inTasks = !inTasks
+		case "j", "<Down>":
+			list.ScrollDown()
+		case "k", "<Up>":
+			list.ScrollUp()
+		}
+		if !inTasks && peopleList.SelectedRow < 0 {
+			peopleList.SelectedRow = 0
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package storage
+
+import (
+	"Termile/internal/task"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultRemote is the git remote synced with when none is configured.
+const DefaultRemote = "origin"
+
+// Repo is the git repository holding the files of a Store in sync mode.
+// Every save is committed, and Sync exchanges the commits with a remote.
+type Repo struct {
+	dir string
+}
+
+// OpenRepo returns the repository of dir if sync mode was turned on there by
+// InitRepo, or nil otherwise.
+func OpenRepo(dir string) (*Repo, error) {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	repo := &Repo{dir: dir}
+	enabled, err := repo.config("termile.sync")
+	if err != nil || enabled != "true" {
+		return nil, err
+	}
+	return repo, nil
+}
+
+// InitRepo turns on sync mode in dir, creating its git repository if needed.
+// In an existing repository, only the store files are committed by saves,
+// and Sync refuses to run while other tracked files have changes.
+// The store files are merged entity by entity by mergeDriver, a command
+// taking the base, our and their versions of projects.json like
+// `termile merge -o %A %O %A %B`. The journal, lock and history stay out of
+// the repository. A non-empty remoteURL sets the URL of the remote.
+func InitRepo(dir, mergeDriver, remote, remoteURL string) (*Repo, error) {
+	repo := &Repo{dir: dir}
+	if _, err := os.Stat(filepath.Join(dir, ".git")); errors.Is(err, os.ErrNotExist) {
+		if _, err := repo.git("init", "-q"); err != nil {
+			return nil, err
+		}
+	}
+	settings := [][2]string{
+		{"termile.sync", "true"},
+		{"termile.remote", remote},
+		{"merge.termile.name", "termile entity-level merge"},
+		{"merge.termile.driver", mergeDriver},
+	}
+	for _, setting := range settings {
+		if _, err := repo.git("config", setting[0], setting[1]); err != nil {
+			return nil, err
+		}
+	}
+	if err := appendLines(filepath.Join(dir, ".gitattributes"), ProjectFile+" merge=termile"); err != nil {
+		return nil, err
+	}
+	if err := appendLines(filepath.Join(dir, ".gitignore"), JournalFile, LockFile, "history.jsonl", OpLogFile, ReplicaFile); err != nil {
+		return nil, err
+	}
+	if remoteURL != "" {
+		args := []string{"remote", "add", remote, remoteURL}
+		if _, err := repo.git("remote", "get-url", remote); err == nil {
+			args[1] = "set-url"
+		}
+		if _, err := repo.git(args...); err != nil {
+			return nil, err
+		}
+	}
+	return repo, nil
+}
+
+// appendLines adds the lines missing from a file.
+func appendLines(filename string, lines ...string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	existing := map[string]bool{}
+	for _, line := range strings.Split(string(data), "\n") {
+		existing[strings.TrimSpace(line)] = true
+	}
+	var missing bytes.Buffer
+	if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+		missing.WriteString("\n")
+	}
+	for _, line := range lines {
+		if !existing[line] {
+			missing.WriteString(line + "\n")
+		}
+	}
+	if strings.TrimSpace(missing.String()) == "" {
+		return nil
+	}
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(missing.Bytes()); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// git runs a git command in the repository and returns its output.
+func (r *Repo) git(args ...string) (string, error) {
+	out, err := r.gitInput(nil, args...)
+	return strings.TrimSpace(string(out)), err
+}
+
+// gitInput runs a git command in the repository with the given input and
+// returns its output untrimmed, e.g. to pipe a patch from one command to
+// another.
+func (r *Repo) gitInput(input []byte, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.dir
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+		return nil, fmt.Errorf("git %s: %s", args[0], message)
+	}
+	return stdout.Bytes(), nil
+}
+
+// config returns a setting of the repository, empty if it is not set.
+func (r *Repo) config(name string) (string, error) {
+	value, err := r.git("config", "--get", name)
+	if err != nil {
+		// git config fails when the setting is missing
+		return "", nil
+	}
+	return value, nil
+}
+
+// Remote returns the name of the remote synced with.
+func (r *Repo) Remote() string {
+	if remote, _ := r.config("termile.remote"); remote != "" {
+		return remote
+	}
+	return DefaultRemote
+}
+
+// trackedFiles are the store files kept in the repository.
+var trackedFiles = []string{ProjectFile, ViewFile, PeopleFile, ".gitattributes", ".gitignore"}
+
+// isTracked reports whether a path of the repository is a store file.
+func isTracked(path string) bool {
+	for _, name := range trackedFiles {
+		if path == name {
+			return true
+		}
+	}
+	return false
+}
+
+// commit commits the store files if they changed, with the given message.
+// Only the store files are committed: other changes, staged or not, are left
+// as they are.
+func (r *Repo) commit(message string) error {
+	var files []string
+	for _, name := range trackedFiles {
+		if _, err := os.Stat(filepath.Join(r.dir, name)); err == nil {
+			files = append(files, name)
+		}
+	}
+	if _, err := r.git(append([]string{"add", "--"}, files...)...); err != nil {
+		return err
+	}
+	if _, err := r.git(append([]string{"diff", "--cached", "--quiet", "--"}, files...)...); err == nil {
+		// Nothing changed since the last commit
+		return nil
+	}
+	_, err := r.git(append([]string{"commit", "-q", "-m", message, "--"}, files...)...)
+	return err
+}
+
+// otherChanges lists the files other than the store files whose changes are
+// not committed, leaving out untracked files.
+func (r *Repo) otherChanges() ([]string, error) {
+	status, err := r.gitInput(nil, "status", "--porcelain", "-z", "--untracked-files=no")
+	if err != nil {
+		return nil, err
+	}
+	var changed []string
+	entries := strings.Split(string(status), "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		paths := []string{entry[3:]}
+		if entry[0] == 'R' || entry[0] == 'C' {
+			// The original path of a rename or copy follows
+			i++
+			if i < len(entries) {
+				paths = append(paths, entries[i])
+			}
+		}
+		for _, path := range paths {
+			if !isTracked(path) {
+				changed = append(changed, path)
+			}
+		}
+	}
+	return changed, nil
+}
+
+// commitMessage describes the changes made by ops, e.g. "Add task 1/4
+// \"Write docs\"" for one change, or a line counting the changed entities by
+// action followed by one line per entity.
+func commitMessage(ops []task.Op) string {
+	type change struct{ action, entity, ref, title string }
+	var changes []*change
+	byEntity := map[string]*change{}
+	for _, op := range ops {
+		ref := op.Key
+		switch op.Entity {
+		case task.EntityProject:
+			ref = strconv.Itoa(op.ProjectID)
+		case task.EntityTask:
+			ref = fmt.Sprintf("%d/%d", op.ProjectID, op.TaskID)
+		case task.EntitySubtask:
+			ref = fmt.Sprintf("%d/%d/%d", op.ProjectID, op.TaskID, op.SubtaskID)
+		}
+		c := byEntity[op.Entity+" "+ref]
+		if c == nil {
+			c = &change{action: op.Action, entity: op.Entity, ref: ref}
+			byEntity[op.Entity+" "+ref] = c
+			changes = append(changes, c)
+		}
+		// An entity added by the changes is not also modified
+		if op.Action == task.ChangeDelete || c.action == task.ChangeDelete {
+			c.action = op.Action
+		}
+		if title := opTitle(op); title != "" {
+			c.title = title
+		}
+	}
+
+	describe := func(c *change) string {
+		if c.title == "" {
+			return fmt.Sprintf("%s %s %s", c.action, c.entity, c.ref)
+		}
+		return fmt.Sprintf("%s %s %s %q", c.action, c.entity, c.ref, c.title)
+	}
+	capitalize := func(s string) string { return strings.ToUpper(s[:1]) + s[1:] }
+	switch len(changes) {
+	case 0:
+		return "Save tasks"
+	case 1:
+		return capitalize(describe(changes[0]))
+	}
+
+	counts := map[string]int{}
+	var kinds []string
+	for _, c := range changes {
+		kind := c.action + " " + c.entity
+		if counts[kind] == 0 {
+			kinds = append(kinds, kind)
+		}
+		counts[kind]++
+	}
+	var parts []string
+	for _, kind := range kinds {
+		action, entity, _ := strings.Cut(kind, " ")
+		switch {
+		case counts[kind] > 1 && entity == task.EntityPerson:
+			entity = fmt.Sprintf("%d people", counts[kind])
+		case counts[kind] > 1:
+			entity = fmt.Sprintf("%d %ss", counts[kind], entity)
+		}
+		parts = append(parts, action+" "+entity)
+	}
+	var message strings.Builder
+	message.WriteString(capitalize(strings.Join(parts, ", ")) + "\n\n")
+	for _, c := range changes {
+		message.WriteString("- " + describe(c) + "\n")
+	}
+	return message.String()
+}
+
+// opTitle returns the name of the project or title of the task or subtask
+// of an op, empty for a deletion.
+func opTitle(op task.Op) string {
+	var value struct{ Name, Title string }
+	if op.Value == nil || json.Unmarshal(op.Value, &value) != nil {
+		return ""
+	}
+	if op.Entity == task.EntityProject {
+		return value.Name
+	}
+	return value.Title
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"Termile/internal/task"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// writeFileAtomic replaces a file with data in a way that leaves either the
+// old or the new contents behind if the process dies while writing
+func writeFileAtomic(filename string, data []byte) error {
+	temp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		os.Remove(temp.Name())
+		return err
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		os.Remove(temp.Name())
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		os.Remove(temp.Name())
+		return err
+	}
+	if err := os.Chmod(temp.Name(), 0644); err != nil {
+		os.Remove(temp.Name())
+		return err
+	}
+	return os.Rename(temp.Name(), filename)
+}
+
+// AppendJournal appends ops to a journal file, one JSON object per line, and
+// flushes them to disk before returning
+func AppendJournal(filename string, ops []task.Op) error {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	for _, op := range ops {
+		if err := encoder.Encode(op); err != nil {
+			return err
+		}
+	}
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(buffer.Bytes()); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// LoadJournal loads the ops of a journal file. A last line left incomplete by
+// a crash while appending is ignored. A missing journal holds no ops.
+func LoadJournal(filename string) ([]task.Op, error) {
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	// Every complete op ends with a newline
+	lines := bytes.Split(data, []byte("\n"))
+	lines = lines[:len(lines)-1]
+	var ops []task.Op
+	for i, line := range lines {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var op task.Op
+		if err := json.Unmarshal(line, &op); err != nil {
+			return nil, fmt.Errorf("%s line %d: %v", filename, i+1, err)
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+// ResetJournal empties a journal once its ops are part of the saved snapshot
+func ResetJournal(filename string) error {
+	err := os.Truncate(filename, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,47 @@
+//go:build unix
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// LockSupported reports whether Lock keeps other processes out.
+const LockSupported = true
+
+// lockTimeout is how long Lock waits for another process to release a lock
+const lockTimeout = 5 * time.Second
+
+// Lock takes an exclusive advisory lock on filename, creating it if needed,
+// and returns the function releasing it. Processes that lock the same file
+// wait for each other, for up to five seconds.
+func Lock(filename string) (func() error, error) {
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, syscall.EWOULDBLOCK) && !errors.Is(err, syscall.EINTR) {
+			file.Close()
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			file.Close()
+			return nil, fmt.Errorf("%s is locked by another process", filename)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return func() error {
+		// Closing the file releases the lock
+		return file.Close()
+	}, nil
+}
@@ -0,0 +1,61 @@
+//go:build windows
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// LockSupported reports whether Lock keeps other processes out.
+const LockSupported = true
+
+// lockTimeout is how long Lock waits for another process to release a lock
+const lockTimeout = 5 * time.Second
+
+// Flags of LockFileEx, and the error it fails with while another process
+// holds the lock
+const (
+	lockfileFailImmediately = 0x1
+	lockfileExclusiveLock   = 0x2
+	errorLockViolation      = syscall.Errno(33)
+)
+
+var procLockFileEx = syscall.NewLazyDLL("kernel32.dll").NewProc("LockFileEx")
+
+// Lock takes an exclusive lock on filename, creating it if needed, and
+// returns the function releasing it. Processes that lock the same file wait
+// for each other, for up to five seconds.
+func Lock(filename string) (func() error, error) {
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		// Lock the first byte, which is enough to exclude other processes
+		// locking the file the same way
+		var overlapped syscall.Overlapped
+		r, _, err := procLockFileEx.Call(file.Fd(), lockfileExclusiveLock|lockfileFailImmediately, 0, 1, 0, uintptr(unsafe.Pointer(&overlapped)))
+		if r != 0 {
+			break
+		}
+		if !errors.Is(err, errorLockViolation) {
+			file.Close()
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			file.Close()
+			return nil, fmt.Errorf("%s is locked by another process", filename)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return func() error {
+		// Closing the file releases the lock
+		return file.Close()
+	}, nil
+}
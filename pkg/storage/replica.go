@@ -0,0 +1,275 @@
+package storage
+
+import (
+	"Termile/internal/crdt"
+	"Termile/internal/merge"
+	"Termile/internal/task"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Names of the files a replicated Store keeps besides its snapshot files
+const (
+	ReplicaFile = "replica.json" // Settings of the replica, see ReplicaSettings
+	OpLogFile   = "oplog.jsonl"  // Every op of the replicated state
+)
+
+// ReplicaSettings are the settings of a replica, kept in its ReplicaFile.
+type ReplicaSettings struct {
+	ID  string `json:"id"`  // Names the file the replica publishes its ops to
+	Dir string `json:"dir"` // Directory shared with the other replicas
+}
+
+// Replica keeps the state of a Store as a CRDT replicated with other copies
+// of it, see package crdt. Every change made to the TaskManager is recorded
+// as ops appended to the op log; Exchange trades ops with the other replicas
+// through a shared directory, e.g. a network share or a synced folder, where
+// each replica appends its ops to a file of its own.
+type Replica struct {
+	store    *Store
+	settings ReplicaSettings
+	doc      *crdt.Doc
+	offset   int64 // Bytes of the op log applied to doc
+}
+
+// randomID returns n random bytes in hex.
+func randomID(n int) string {
+	id := make([]byte, n)
+	if _, err := rand.Read(id); err != nil {
+		panic(fmt.Sprintf("storage: cannot generate an ID: %v", err))
+	}
+	return hex.EncodeToString(id)
+}
+
+// OpenReplica returns the replica of a store if replication was turned on by
+// InitReplica, or nil otherwise. From then on, the changes made to the
+// TaskManager of the store are recorded.
+func OpenReplica(store *Store) (*Replica, error) {
+	data, err := os.ReadFile(store.path(ReplicaFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var settings ReplicaSettings
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("cannot read %s: %v", ReplicaFile, err)
+	}
+	return newReplica(store, settings)
+}
+
+// InitReplica turns on replication through dir for a store, recording its
+// current state as the first ops of the replica.
+func InitReplica(store *Store, dir string) (*Replica, error) {
+	settings := ReplicaSettings{ID: randomID(8), Dir: dir}
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(store.path(ReplicaFile), data); err != nil {
+		return nil, err
+	}
+	r, err := newReplica(store, settings)
+	if err != nil {
+		return nil, err
+	}
+	return r, store.withLock(r.record)
+}
+
+// newReplica loads the op log of a replica and records the changes made to
+// the TaskManager of the store from now on. Each process is a site of its
+// own, so that processes sharing the files never make ops with the same
+// stamp.
+func newReplica(store *Store, settings ReplicaSettings) (*Replica, error) {
+	r := &Replica{store: store, settings: settings, doc: crdt.NewDoc(settings.ID + "." + randomID(4))}
+	if err := store.withLock(r.catchUp); err != nil {
+		return nil, err
+	}
+	store.tm.OnOps(func([]task.Op) {
+		if err := store.withLock(r.record); err != nil {
+			log.Printf("failed to record changes for replication: %v", err)
+		}
+	})
+	return r, nil
+}
+
+// Dir returns the directory shared with the other replicas.
+func (r *Replica) Dir() string {
+	return r.settings.Dir
+}
+
+// catchUp applies the ops other processes appended to the op log, which
+// must be locked.
+func (r *Replica) catchUp() error {
+	ops, size, err := readOps(r.store.path(OpLogFile), r.offset)
+	if err != nil {
+		return err
+	}
+	for _, op := range ops {
+		r.doc.Apply(op)
+	}
+	r.offset = size
+	return nil
+}
+
+// record appends the ops of the changes made to the TaskManager since the
+// replicated state was last updated. The op log must be locked.
+func (r *Replica) record() error {
+	if err := r.catchUp(); err != nil {
+		return err
+	}
+	tm := r.store.tm
+	ops := r.doc.Record(merge.State{Projects: tm.ListProjects(), Views: tm.ListViews(), People: tm.ListPeople()})
+	return r.append(ops)
+}
+
+// append appends ops to the op log, which must be locked.
+func (r *Replica) append(ops []crdt.Op) error {
+	if len(ops) == 0 {
+		return nil
+	}
+	if err := appendOps(r.store.path(OpLogFile), ops); err != nil {
+		return err
+	}
+	info, err := os.Stat(r.store.path(OpLogFile))
+	if err != nil {
+		return err
+	}
+	r.offset = info.Size()
+	return nil
+}
+
+// Exchange trades ops with the other replicas: the ops of the shared
+// directory this replica misses are applied, and the ops it has that the
+// directory misses are published there. The TaskManager is then updated to
+// the replicated state as one change, and the number of ops received and
+// sent is returned.
+func (r *Replica) Exchange() (received, sent int, err error) {
+	if err := os.MkdirAll(r.settings.Dir, 0755); err != nil {
+		return 0, 0, err
+	}
+	err = r.store.withLock(func() error {
+		if err := r.record(); err != nil {
+			return err
+		}
+
+		// Ops of the other replicas, in a stable order
+		files, err := filepath.Glob(filepath.Join(r.settings.Dir, "*.jsonl"))
+		if err != nil {
+			return err
+		}
+		sort.Strings(files)
+		published := map[crdt.Stamp]bool{}
+		var incoming []crdt.Op
+		for _, file := range files {
+			ops, _, err := readOps(file, 0)
+			if err != nil {
+				return err
+			}
+			for _, op := range ops {
+				published[op.Stamp] = true
+				if !r.doc.Has(op.Stamp) {
+					incoming = append(incoming, op)
+				}
+			}
+		}
+		for _, op := range incoming {
+			if r.doc.Apply(op) {
+				received++
+			}
+		}
+		if err := r.append(incoming); err != nil {
+			return err
+		}
+
+		all, _, err := readOps(r.store.path(OpLogFile), 0)
+		if err != nil {
+			return err
+		}
+		var outgoing []crdt.Op
+		for _, op := range all {
+			if !published[op.Stamp] {
+				published[op.Stamp] = true
+				outgoing = append(outgoing, op)
+			}
+		}
+		sent = len(outgoing)
+		if sent == 0 {
+			return nil
+		}
+		return appendOps(filepath.Join(r.settings.Dir, r.settings.ID+".jsonl"), outgoing)
+	})
+	if err != nil || received == 0 {
+		return received, sent, err
+	}
+
+	// Recording the change only renumbers the entities the replicated state
+	// renumbered
+	state := r.doc.Materialize()
+	r.store.tm.ReplaceState(state.Projects, state.Views, state.People)
+	return received, sent, nil
+}
+
+// appendOps appends ops to a file, one JSON object per line, and flushes
+// them to disk before returning.
+func appendOps(filename string, ops []crdt.Op) error {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	for _, op := range ops {
+		if err := encoder.Encode(op); err != nil {
+			return err
+		}
+	}
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(buffer.Bytes()); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// readOps reads the ops of a file from an offset, and returns the offset
+// after the last complete line. A missing file holds no ops.
+func readOps(filename string, offset int64) ([]crdt.Op, int64, error) {
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, 0, nil
+	} else if err != nil {
+		return nil, 0, err
+	}
+	if offset > int64(len(data)) {
+		return nil, 0, fmt.Errorf("%s was truncated", filename)
+	}
+
+	// A last line without a newline is still being written
+	data = data[offset:]
+	complete := bytes.LastIndexByte(data, '\n') + 1
+	var ops []crdt.Op
+	for i, line := range strings.Split(string(data[:complete]), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var op crdt.Op
+		if err := json.Unmarshal([]byte(line), &op); err != nil {
+			return nil, 0, fmt.Errorf("%s: op %d: %v", filename, i+1, err)
+		}
+		ops = append(ops, op)
+	}
+	return ops, offset + int64(complete), nil
+}
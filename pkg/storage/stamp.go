@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"errors"
+	"os"
+	"time"
+)
+
+// FileStamp identifies a version of a file by its modification time, size
+// and a hash of its contents. The stamp of a missing file is the zero value.
+type FileStamp struct {
+	Exists  bool
+	ModTime time.Time
+	Size    int64
+	Hash    [sha256.Size]byte
+}
+
+// StampFile returns the stamp of the current version of a file.
+func StampFile(filename string) (FileStamp, error) {
+	info, err := os.Stat(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return FileStamp{}, nil
+	} else if err != nil {
+		return FileStamp{}, err
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return FileStamp{}, err
+	}
+	return FileStamp{Exists: true, ModTime: info.ModTime(), Size: info.Size(), Hash: sha256.Sum256(data)}, nil
+}
+
+// Changed reports whether a file differs from the version stamped, and
+// returns the stamp of its current version. The contents are only hashed
+// when the modification time or size changed, so a file that was merely
+// touched is not reported as changed.
+func (s FileStamp) Changed(filename string) (bool, FileStamp, error) {
+	info, err := os.Stat(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return s.Exists, FileStamp{}, nil
+	} else if err != nil {
+		return false, s, err
+	}
+	if s.Exists && info.ModTime().Equal(s.ModTime) && info.Size() == s.Size {
+		return false, s, nil
+	}
+	current, err := StampFile(filename)
+	if err != nil {
+		return false, s, err
+	}
+	return !s.Exists || current.Hash != s.Hash, current, nil
+}
@@ -3,6 +3,7 @@ package storage
 import (
 	"Termile/internal/task"
 	"encoding/json"
+	"fmt"
 	"io"
 	"os"
 )
@@ -14,7 +15,7 @@ func SaveProjects(filename string, projects []task.Project) error {
 		return err
 	}
 
-	return os.WriteFile(filename, data, 0644)
+	return writeFileAtomic(filename, data)
 }
 
 // LoadProjects loads the list of projects from a specified JSON file
@@ -37,3 +38,119 @@ func LoadProjects(filename string) ([]task.Project, error) {
 
 	return projects, nil
 }
+
+// SaveViews saves the list of saved views to a specified file in JSON format
+func SaveViews(filename string, views []task.View) error {
+	data, err := json.Marshal(views)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filename, data)
+}
+
+// LoadViews loads the list of saved views from a specified JSON file
+func LoadViews(filename string) ([]task.View, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var views []task.View
+	if err := json.Unmarshal(data, &views); err != nil {
+		return nil, err
+	}
+
+	return views, nil
+}
+
+// SavePeople saves the people registry to a specified file in JSON format
+func SavePeople(filename string, people []task.Person) error {
+	data, err := json.Marshal(people)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filename, data)
+}
+
+// LoadPeople loads the people registry from a specified JSON file
+func LoadPeople(filename string) ([]task.Person, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var people []task.Person
+	if err := json.Unmarshal(data, &people); err != nil {
+		return nil, err
+	}
+
+	return people, nil
+}
+
+// AppendHistory appends changes to a history file, one JSON object per line
+func AppendHistory(filename string, changes []task.Change) error {
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	for _, change := range changes {
+		if err := encoder.Encode(change); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	return file.Close()
+}
+
+// LoadHistory loads the changes recorded in a history file
+func LoadHistory(filename string) ([]task.Change, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var changes []task.Change
+	decoder := json.NewDecoder(file)
+	for {
+		var change task.Change
+		if err := decoder.Decode(&change); err == io.EOF {
+			return changes, nil
+		} else if err != nil {
+			return changes, fmt.Errorf("%s: entry %d: %v", filename, len(changes)+1, err)
+		}
+		changes = append(changes, change)
+	}
+}
+
+// PruneHistory rewrites a history file keeping only the changes for which
+// keep returns true, and returns the number of changes removed
+func PruneHistory(filename string, keep func(index int, change task.Change) bool) (int, error) {
+	changes, err := LoadHistory(filename)
+	if err != nil {
+		return 0, err
+	}
+	var kept []task.Change
+	for i, change := range changes {
+		if keep(i, change) {
+			kept = append(kept, change)
+		}
+	}
+	if len(kept) == len(changes) {
+		return 0, nil
+	}
+
+	// Write the pruned history next to the file and swap it in, so the
+	// history is never left half written
+	temp := filename + ".tmp"
+	if err := os.Remove(temp); err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	if err := AppendHistory(temp, kept); err != nil {
+		return 0, err
+	}
+	return len(changes) - len(kept), os.Rename(temp, filename)
+}
@@ -0,0 +1,264 @@
+package storage
+
+import (
+	"Termile/internal/task"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// Names of the files a Store keeps in its directory
+const (
+	ProjectFile = "projects.json"
+	ViewFile    = "views.json"
+	PeopleFile  = "people.json"
+	JournalFile = "journal.jsonl"
+	LockFile    = "termile.lock"
+)
+
+// ErrModified is returned by Save when another process changed the files
+// since they were last read or written.
+var ErrModified = errors.New("the task files were changed by another process")
+
+// Store keeps the projects, views and people of a TaskManager in a
+// directory: snapshot files plus a journal of the changes made since they
+// were written. Every change is journaled as soon as it is made. Processes
+// sharing the directory lock it while writing, and a Store notices when
+// another process changed the files since it last read or wrote them.
+//
+// The journal is kept linear: a change made after another process changed
+// the files is applied on top of their version, so the state is reloaded
+// before it is journaled. Entities it adds are renumbered if their IDs were
+// taken by the other process.
+type Store struct {
+	dir          string
+	compactEvery int
+	tm           *task.TaskManager
+	stamps       map[string]FileStamp // Files as this Store last read or wrote them
+	pending      []task.Op            // Changes made by this Store since the last save
+	journaled    int                  // Ops in the journal since it was last emptied
+	repo         *Repo                // Repository the files are committed to in sync mode
+}
+
+// NewStore creates a Store for the files in dir that journals every change
+// made to tm and saves it after compactEvery journaled changes.
+func NewStore(dir string, tm *task.TaskManager, compactEvery int) *Store {
+	s := &Store{dir: dir, compactEvery: compactEvery, tm: tm, stamps: map[string]FileStamp{}}
+	tm.OnOps(s.journal)
+	return s
+}
+
+// SetRepo turns on sync mode: every save is committed to repo.
+func (s *Store) SetRepo(repo *Repo) {
+	s.repo = repo
+}
+
+// path returns the path of one of the files of the store.
+func (s *Store) path(name string) string {
+	return filepath.Join(s.dir, name)
+}
+
+// withLock runs fn while holding the lock of the directory.
+func (s *Store) withLock(fn func() error) error {
+	unlock, err := Lock(s.path(LockFile))
+	if err != nil {
+		return err
+	}
+	err = fn()
+	if unlockErr := unlock(); err == nil {
+		err = unlockErr
+	}
+	return err
+}
+
+// Load replaces the state of the TaskManager with the saved one: the
+// snapshot files with the journal replayed on top. Missing files count as
+// empty, and views default to task.DefaultViews. Files that cannot be read
+// are reported after loading the others.
+func (s *Store) Load() error {
+	return s.withLock(s.read)
+}
+
+// stampFiles records the current version of the files.
+func (s *Store) stampFiles(names ...string) error {
+	for _, name := range names {
+		stamp, err := StampFile(s.path(name))
+		if err != nil {
+			return err
+		}
+		s.stamps[name] = stamp
+	}
+	return nil
+}
+
+// read loads the files, which must be locked.
+func (s *Store) read() error {
+	if err := s.stampFiles(ProjectFile, ViewFile, PeopleFile, JournalFile); err != nil {
+		return err
+	}
+
+	var errs []error
+	projects, err := LoadProjects(s.path(ProjectFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		errs = append(errs, fmt.Errorf("failed to load projects: %v", err))
+	}
+	views, err := LoadViews(s.path(ViewFile))
+	if errors.Is(err, os.ErrNotExist) {
+		views = task.DefaultViews()
+	} else if err != nil {
+		errs = append(errs, fmt.Errorf("failed to load views: %v", err))
+	}
+	people, err := LoadPeople(s.path(PeopleFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		errs = append(errs, fmt.Errorf("failed to load people: %v", err))
+	}
+	s.tm.SetProjects(projects)
+	s.tm.SetViews(views)
+	s.tm.SetPeople(people)
+
+	ops, err := LoadJournal(s.path(JournalFile))
+	if err == nil {
+		err = s.tm.ApplyOps(ops)
+	}
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to replay journal: %v", err))
+	}
+	s.journaled = len(ops)
+	if len(ops) == 0 {
+		// Everything is in the snapshot files
+		s.pending = nil
+		s.tm.MarkSaved()
+	}
+	return errors.Join(errs...)
+}
+
+// modified reports whether another process changed the files since this
+// Store last read or wrote them.
+func (s *Store) modified() (bool, error) {
+	modified := false
+	for name, stamp := range s.stamps {
+		changed, current, err := stamp.Changed(s.path(name))
+		if err != nil {
+			return false, err
+		}
+		if changed {
+			modified = true
+		} else {
+			s.stamps[name] = current
+		}
+	}
+	return modified, nil
+}
+
+// Modified reports whether another process changed the files since this
+// Store last read or wrote them, e.g. to reload them with Merge.
+func (s *Store) Modified() (bool, error) {
+	return s.modified()
+}
+
+// Pending returns the number of changes made since the last save.
+func (s *Store) Pending() int {
+	return len(s.pending)
+}
+
+// journal appends the ops of a change to the journal, and compacts it when
+// it has grown long enough. If another process changed the files, the
+// change is first rebased on their version.
+func (s *Store) journal(ops []task.Op) {
+	err := s.withLock(func() error {
+		modified, err := s.modified()
+		if err != nil {
+			return err
+		}
+		if modified {
+			err := s.tm.Reload(func() error {
+				if err := s.read(); err != nil {
+					return err
+				}
+				ops, err = s.tm.RebaseOps(ops)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if err := AppendJournal(s.path(JournalFile), ops); err != nil {
+			return err
+		}
+		s.pending = append(s.pending, ops...)
+		s.journaled += len(ops)
+		return s.stampFiles(JournalFile)
+	})
+	if err != nil {
+		log.Printf("failed to journal changes: %v", err)
+		return
+	}
+	if s.compactEvery > 0 && s.journaled >= s.compactEvery {
+		if err := s.Save(); err != nil && !errors.Is(err, ErrModified) {
+			log.Printf("failed to compact journal: %v", err)
+		}
+	}
+}
+
+// Save writes the projects, views and people to the snapshot files and
+// empties the journal, whose changes they now include. In sync mode the
+// files are committed, with a message describing the changes. If another process
+// changed the files since this Store last read or wrote them, nothing is
+// written and ErrModified is returned: Merge their changes first, or
+// Overwrite them.
+func (s *Store) Save() error {
+	return s.withLock(func() error {
+		modified, err := s.modified()
+		if err != nil {
+			return err
+		}
+		if modified {
+			return ErrModified
+		}
+		return s.write()
+	})
+}
+
+// Overwrite saves like Save, replacing the changes of other processes.
+func (s *Store) Overwrite() error {
+	return s.withLock(s.write)
+}
+
+// write saves the files, which must be locked.
+func (s *Store) write() error {
+	if err := SaveProjects(s.path(ProjectFile), s.tm.ListProjects()); err != nil {
+		return fmt.Errorf("failed to save projects: %v", err)
+	}
+	if err := SaveViews(s.path(ViewFile), s.tm.ListViews()); err != nil {
+		return fmt.Errorf("failed to save views: %v", err)
+	}
+	if err := SavePeople(s.path(PeopleFile), s.tm.ListPeople()); err != nil {
+		return fmt.Errorf("failed to save people: %v", err)
+	}
+	if err := ResetJournal(s.path(JournalFile)); err != nil {
+		return fmt.Errorf("failed to compact journal: %v", err)
+	}
+	if err := s.stampFiles(ProjectFile, ViewFile, PeopleFile, JournalFile); err != nil {
+		return err
+	}
+	if s.repo != nil {
+		if err := s.repo.commit(commitMessage(s.pending)); err != nil {
+			return fmt.Errorf("failed to commit: %v", err)
+		}
+	}
+	s.pending = nil
+	s.journaled = 0
+	s.tm.MarkSaved()
+	return nil
+}
+
+// Merge reloads the files changed by another process. The changes made by
+// this Store are part of them, since they were journaled, so an entity
+// changed by both keeps the version journaled last.
+func (s *Store) Merge() error {
+	return s.tm.Reload(func() error {
+		return s.withLock(s.read)
+	})
+}
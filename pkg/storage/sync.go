@@ -0,0 +1,237 @@
+package storage
+
+import (
+	"Termile/internal/merge"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ConflictError is returned by Sync when local and remote changes conflict
+// and the conflicts were not resolved. Nothing was synced.
+type ConflictError struct {
+	Conflicts []merge.Conflict
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%d conflicts between the local and remote changes", len(e.Conflicts))
+}
+
+// SyncResult counts the commits a sync brought in and sent.
+type SyncResult struct {
+	Pulled int
+	Pushed int
+}
+
+// Resolver resolves the conflicts of a merge, returning false to abort it.
+type Resolver func(result *merge.Result) (bool, error)
+
+// Sync saves and commits the changes, then exchanges commits with the remote
+// of the repository: remote commits are pulled, local commits are rebased on
+// top of them and pushed. Rebasing merges each local commit into the remote
+// version entity by entity, see merge.Merge; on their side are the local
+// changes, as with git rebase. Conflicts are passed to resolve, if not nil.
+// If they are left unresolved, nothing is synced and a *ConflictError is
+// returned. Sync refuses to run while tracked files other than the store
+// files have uncommitted changes.
+func (s *Store) Sync(resolve Resolver) (SyncResult, error) {
+	var result SyncResult
+	if s.repo == nil {
+		return result, errors.New("sync mode is off, run termile sync init first")
+	}
+	err := s.withLock(func() error {
+		modified, err := s.modified()
+		if err != nil {
+			return err
+		}
+		if modified {
+			if err := s.read(); err != nil {
+				return err
+			}
+		}
+		if err := s.write(); err != nil {
+			return err
+		}
+		// Pulling could otherwise mix the remote changes into the work in
+		// progress on the other files of the repository
+		changed, err := s.repo.otherChanges()
+		if err != nil {
+			return err
+		}
+		if len(changed) > 0 {
+			return fmt.Errorf("cannot sync while files other than the task files have uncommitted changes: %s", strings.Join(changed, ", "))
+		}
+
+		remote := s.repo.Remote()
+		branch, err := s.repo.git("symbolic-ref", "--short", "HEAD")
+		if err != nil {
+			return err
+		}
+		if _, err := s.repo.git("fetch", "-q", remote); err != nil {
+			return err
+		}
+		upstream := remote + "/" + branch
+		if _, err := s.repo.git("rev-parse", "-q", "--verify", upstream); err != nil {
+			// The remote does not have the branch yet
+			local, err := s.repo.commits("HEAD")
+			if err != nil {
+				return err
+			}
+			result.Pushed = len(local)
+			_, err = s.repo.git("push", "-q", "-u", remote, branch)
+			return err
+		}
+
+		local, err := s.repo.commits(upstream + "..HEAD")
+		if err != nil {
+			return err
+		}
+		incoming, err := s.repo.commits("HEAD.." + upstream)
+		if err != nil {
+			return err
+		}
+		if len(incoming) > 0 {
+			if len(local) == 0 {
+				_, err = s.repo.git("merge", "-q", "--ff-only", upstream)
+			} else {
+				err = s.rebase(branch, upstream, local, resolve)
+			}
+			if err != nil {
+				return err
+			}
+			if err := s.read(); err != nil {
+				return err
+			}
+		}
+		if len(local) > 0 {
+			if _, err := s.repo.git("push", "-q", remote, branch); err != nil {
+				return err
+			}
+		}
+		result.Pulled, result.Pushed = len(incoming), len(local)
+		return nil
+	})
+	return result, err
+}
+
+// commits lists the commits of a revision range, oldest first.
+func (r *Repo) commits(revisions string) ([]string, error) {
+	out, err := r.git("rev-list", "--reverse", revisions)
+	if err != nil || out == "" {
+		return nil, err
+	}
+	return strings.Fields(out), nil
+}
+
+// rebase replays the local commits of branch on top of upstream, merging the changes
+// of each one into the store files entity by entity. The changes the commits
+// made to other files are applied as they are. The replay happens on a
+// detached HEAD, and the branch is only moved once every commit is replayed:
+// if a merge fails or has unresolved conflicts, the branch is checked out
+// again as it was.
+func (s *Store) rebase(branch, upstream string, local []string, resolve Resolver) (err error) {
+	r := s.repo
+	// Unlike a reset, checking out refuses to overwrite untracked files
+	if _, err := r.git("checkout", "-q", "--detach", upstream); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			// The only changes left are those of the replay, as Sync checked
+			// nothing else was changed
+			if _, checkoutErr := r.git("checkout", "-q", "-f", branch); checkoutErr != nil {
+				err = errors.Join(err, checkoutErr)
+			}
+		}
+	}()
+
+	storeFiles := []string{ProjectFile, ViewFile, PeopleFile}
+	for _, commit := range local {
+		var versions [3]merge.State
+		for i, revision := range []string{commit + "^", "HEAD", commit} {
+			if versions[i], err = r.state(revision); err != nil {
+				return err
+			}
+		}
+		result := merge.Merge(versions[0], versions[1], versions[2])
+		if result.Unresolved() > 0 && resolve != nil {
+			ok, err := resolve(result)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return errors.New("sync aborted")
+			}
+		}
+		if result.Unresolved() > 0 {
+			return &ConflictError{Conflicts: result.Conflicts}
+		}
+
+		if err := r.applyOtherChanges(commit, storeFiles); err != nil {
+			return err
+		}
+		if err := SaveProjects(s.path(ProjectFile), result.Projects); err != nil {
+			return err
+		}
+		if err := SaveViews(s.path(ViewFile), result.Views); err != nil {
+			return err
+		}
+		if err := SavePeople(s.path(PeopleFile), result.People); err != nil {
+			return err
+		}
+		if _, err := r.git(append([]string{"add", "--"}, storeFiles...)...); err != nil {
+			return err
+		}
+		if _, err := r.git("diff", "--cached", "--quiet"); err == nil {
+			// The remote already made the same changes
+			continue
+		}
+		// Keep the message, author and date of the local commit
+		if _, err := r.git("commit", "-q", "-C", commit); err != nil {
+			return err
+		}
+	}
+	// Move the branch to the replayed commits
+	_, err = r.git("checkout", "-q", "-B", branch)
+	return err
+}
+
+// applyOtherChanges stages the changes a commit made to files other than the
+// given ones, failing if they do not apply.
+func (r *Repo) applyOtherChanges(commit string, except []string) error {
+	args := []string{"diff", "--binary", commit + "^", commit, "--", "."}
+	for _, name := range except {
+		args = append(args, ":(exclude)"+name)
+	}
+	patch, err := r.gitInput(nil, args...)
+	if err != nil || len(patch) == 0 {
+		return err
+	}
+	if _, err := r.gitInput(patch, "apply", "--index"); err != nil {
+		return fmt.Errorf("cannot replay the changes of commit %.7s to other files: %v", commit, err)
+	}
+	return nil
+}
+
+// state reads the files of the store at a revision. Files missing there,
+// like every file before the first commit, hold nothing.
+func (r *Repo) state(revision string) (merge.State, error) {
+	var state merge.State
+	if _, err := r.git("rev-parse", "-q", "--verify", revision+"^{commit}"); err != nil {
+		return state, nil
+	}
+	for name, v := range map[string]any{ProjectFile: &state.Projects, ViewFile: &state.Views, PeopleFile: &state.People} {
+		if _, err := r.git("cat-file", "-e", revision+":"+name); err != nil {
+			continue
+		}
+		data, err := r.git("show", revision+":"+name)
+		if err != nil {
+			return state, err
+		}
+		if err := json.Unmarshal([]byte(data), v); err != nil {
+			return state, fmt.Errorf("cannot read %s at %s: %v", name, revision, err)
+		}
+	}
+	return state, nil
+}
@@ -0,0 +1,349 @@
+package storage
+
+import (
+	"Termile/internal/merge"
+	"Termile/internal/task"
+	"errors"
+	"os"
+	"os/exec"
+	"testing"
+)
+
+// syncCopy is a copy of the task files syncing with a remote.
+type syncCopy struct {
+	tm    *task.TaskManager
+	store *Store
+}
+
+// newRemote creates a bare repository to sync with, and sets the identity
+// git commits with.
+func newRemote(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	for _, name := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(name, "termile")
+	}
+	for _, name := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(name, "termile@example.com")
+	}
+	// Keep the settings of the user out of the test
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	dir := t.TempDir()
+	if _, err := (&Repo{dir: dir}).git("init", "-q", "--bare", "-b", "main"); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// newCopy turns on sync mode in an empty directory and syncs it with the
+// remote.
+func newCopy(t *testing.T, remoteURL string) *syncCopy {
+	t.Helper()
+	dir := t.TempDir()
+	if _, err := (&Repo{dir: dir}).git("init", "-q", "-b", "main"); err != nil {
+		t.Fatal(err)
+	}
+	repo, err := InitRepo(dir, "true", DefaultRemote, remoteURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &syncCopy{tm: task.NewTaskManager()}
+	r.store = NewStore(dir, r.tm, 0)
+	r.store.SetRepo(repo)
+	if err := r.store.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.store.Sync(nil); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// sync saves the changes of a copy and syncs them.
+func (r *syncCopy) sync(t *testing.T, resolve Resolver) (SyncResult, error) {
+	t.Helper()
+	if err := r.store.Save(); err != nil {
+		t.Fatal(err)
+	}
+	return r.store.Sync(resolve)
+}
+
+// head returns the commit a copy is at.
+func (r *syncCopy) head(t *testing.T) string {
+	t.Helper()
+	head, err := r.store.repo.git("rev-parse", "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return head
+}
+
+// task returns the first task of the first project of a copy.
+func (r *syncCopy) task(t *testing.T) task.Task {
+	t.Helper()
+	projects := r.tm.ListProjects()
+	if len(projects) == 0 || len(projects[0].Tasks) == 0 {
+		t.Fatal("the copy has no task")
+	}
+	return projects[0].Tasks[0]
+}
+
+// savedTitle returns the title of the first task in the projects file of a
+// copy.
+func (r *syncCopy) savedTitle(t *testing.T) string {
+	t.Helper()
+	projects, err := LoadProjects(r.store.path(ProjectFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return projects[0].Tasks[0].Title
+}
+
+// setup creates two copies of a project with one task.
+func setup(t *testing.T) (a, b *syncCopy) {
+	t.Helper()
+	remote := newRemote(t)
+	a = newCopy(t, remote)
+	a.tm.AddProject(task.Project{Name: "Website"})
+	a.tm.AddTask(1, task.Task{Title: "Design"})
+	if _, err := a.sync(t, nil); err != nil {
+		t.Fatal(err)
+	}
+	b = newCopy(t, remote)
+	if got := b.task(t).Title; got != "Design" {
+		t.Fatalf("b pulled task %q, want %q", got, "Design")
+	}
+	return a, b
+}
+
+// retitle changes the title of the first task of a copy.
+func (r *syncCopy) retitle(t *testing.T, title string) {
+	t.Helper()
+	current := r.task(t)
+	r.tm.EditTask(0, current.ID, title, current.Description)
+}
+
+func TestSyncMergesChangesToDifferentFields(t *testing.T) {
+	a, b := setup(t)
+	a.retitle(t, "Design the pages")
+	if _, err := a.sync(t, nil); err != nil {
+		t.Fatal(err)
+	}
+	current := b.task(t)
+	b.tm.EditTask(0, current.ID, current.Title, "Mockups first")
+
+	result, err := b.sync(t, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Pulled != 1 || result.Pushed != 1 {
+		t.Errorf("pulled %d and pushed %d commits, want 1 and 1", result.Pulled, result.Pushed)
+	}
+	if got := b.task(t); got.Title != "Design the pages" || got.Description != "Mockups first" {
+		t.Errorf("b has %q, %q, want both changes", got.Title, got.Description)
+	}
+	if _, err := a.sync(t, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.task(t); got.Description != "Mockups first" {
+		t.Errorf("a has description %q after pulling, want b's", got.Description)
+	}
+}
+
+func TestSyncConflicts(t *testing.T) {
+	tests := []struct {
+		name    string
+		resolve Resolver
+		synced  bool   // Whether the sync succeeds
+		title   string // Title of the task after syncing
+	}{
+		{name: "unresolved", resolve: nil, title: "Design the site"},
+		{
+			name:    "aborted",
+			resolve: func(result *merge.Result) (bool, error) { return false, nil },
+			title:   "Design the site",
+		},
+		{
+			name:    "resolver failing",
+			resolve: func(result *merge.Result) (bool, error) { return false, errors.New("no terminal") },
+			title:   "Design the site",
+		},
+		{
+			name: "resolved with the remote changes",
+			resolve: func(result *merge.Result) (bool, error) {
+				return true, result.Resolve(0, merge.Ours)
+			},
+			synced: true, title: "Design the pages",
+		},
+		{
+			name: "resolved with the local changes",
+			resolve: func(result *merge.Result) (bool, error) {
+				return true, result.Resolve(0, merge.Theirs)
+			},
+			synced: true, title: "Design the site",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a, b := setup(t)
+			a.retitle(t, "Design the pages")
+			if _, err := a.sync(t, nil); err != nil {
+				t.Fatal(err)
+			}
+			b.retitle(t, "Design the site")
+			if err := b.store.Save(); err != nil {
+				t.Fatal(err)
+			}
+			orig := b.head(t)
+
+			_, err := b.store.Sync(test.resolve)
+			if test.synced {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := b.task(t).Title; got != test.title {
+					t.Errorf("b has title %q, want %q", got, test.title)
+				}
+				return
+			}
+
+			var conflictErr *ConflictError
+			if test.resolve == nil && !errors.As(err, &conflictErr) {
+				t.Fatalf("sync returned %v, want a *ConflictError", err)
+			} else if err == nil {
+				t.Fatal("sync succeeded, want an error")
+			}
+			if conflictErr != nil && (len(conflictErr.Conflicts) != 1 || conflictErr.Conflicts[0].Field != "Title") {
+				t.Errorf("conflicts = %+v, want one on the title", conflictErr.Conflicts)
+			}
+			// The branch and files are back to the local commit
+			if head := b.head(t); head != orig {
+				t.Errorf("b is at %s, want %s", head, orig)
+			}
+			if got := b.savedTitle(t); got != test.title {
+				t.Errorf("b saved title %q, want %q", got, test.title)
+			}
+			if got := b.task(t).Title; got != test.title {
+				t.Errorf("b has title %q, want %q", got, test.title)
+			}
+			if status, err := b.store.repo.git("status", "--porcelain"); err != nil || status != "" {
+				t.Errorf("b has uncommitted changes %q, %v", status, err)
+			}
+			// The local changes can still be saved, then synced once resolved
+			b.retitle(t, "Design the site again")
+			if err := b.store.Save(); err != nil {
+				t.Errorf("saving after the failed sync: %v", err)
+			}
+			if _, err := b.store.Sync(func(result *merge.Result) (bool, error) {
+				return true, result.Resolve(0, merge.Theirs)
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if got := b.task(t).Title; got != "Design the site again" {
+				t.Errorf("b has title %q after resolving, want its own", got)
+			}
+		})
+	}
+}
+
+func TestSyncWithoutRepo(t *testing.T) {
+	store := NewStore(t.TempDir(), task.NewTaskManager(), 0)
+	if _, err := store.Sync(nil); err == nil {
+		t.Error("sync without a repository succeeded")
+	}
+}
+
+// writeFile writes a file other than the store files into a copy, and
+// stages it if add is set.
+func (r *syncCopy) writeFile(t *testing.T, name, content string, add bool) {
+	t.Helper()
+	if err := os.WriteFile(r.store.path(name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if add {
+		if _, err := r.store.repo.git("add", name); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSaveCommitsOnlyTheStoreFiles(t *testing.T) {
+	a, _ := setup(t)
+	a.writeFile(t, "notes.txt", "draft", true)
+	a.retitle(t, "Design the pages")
+	if err := a.store.Save(); err != nil {
+		t.Fatal(err)
+	}
+	files, err := a.store.repo.git("show", "--name-only", "--format=", "HEAD")
+	if err != nil || files != ProjectFile {
+		t.Errorf("the save committed %q, %v, want only %s", files, err, ProjectFile)
+	}
+	if status, _ := a.store.repo.git("status", "--porcelain"); status != "A  notes.txt" {
+		t.Errorf("status = %q, want notes.txt still staged", status)
+	}
+}
+
+func TestSyncRefusesOtherChanges(t *testing.T) {
+	a, b := setup(t)
+	b.writeFile(t, "notes.txt", "draft", true)
+	if _, err := b.store.repo.git("commit", "-q", "-m", "Add notes", "--", "notes.txt"); err != nil {
+		t.Fatal(err)
+	}
+	a.retitle(t, "Design the pages")
+	if _, err := a.sync(t, nil); err != nil {
+		t.Fatal(err)
+	}
+	b.writeFile(t, "notes.txt", "draft, edited", false)
+	orig := b.head(t)
+
+	if _, err := b.sync(t, nil); err == nil {
+		t.Fatal("sync succeeded with notes.txt changed")
+	}
+	if head := b.head(t); head != orig {
+		t.Errorf("b is at %s, want %s", head, orig)
+	}
+	if data, _ := os.ReadFile(b.store.path("notes.txt")); string(data) != "draft, edited" {
+		t.Errorf("notes.txt holds %q, want the uncommitted edit", data)
+	}
+}
+
+func TestSyncReplaysOtherFiles(t *testing.T) {
+	a, b := setup(t)
+	a.retitle(t, "Design the pages")
+	if _, err := a.sync(t, nil); err != nil {
+		t.Fatal(err)
+	}
+	b.writeFile(t, "notes.txt", "draft", true)
+	if _, err := b.store.repo.git("commit", "-q", "-m", "Add notes"); err != nil {
+		t.Fatal(err)
+	}
+	current := b.task(t)
+	b.tm.EditTask(0, current.ID, current.Title, "Mockups first")
+	b.writeFile(t, "scratch.txt", "untracked", false)
+
+	if _, err := b.sync(t, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.task(t); got.Title != "Design the pages" || got.Description != "Mockups first" {
+		t.Errorf("b has %q, %q, want both changes", got.Title, got.Description)
+	}
+	if branch, err := b.store.repo.git("symbolic-ref", "--short", "HEAD"); err != nil || branch != "main" {
+		t.Errorf("b is on %q, %v, want main", branch, err)
+	}
+	if notes, err := b.store.repo.git("show", "HEAD:notes.txt"); err != nil || notes != "draft" {
+		t.Errorf("replayed notes.txt = %q, %v", notes, err)
+	}
+	if data, _ := os.ReadFile(b.store.path("scratch.txt")); string(data) != "untracked" {
+		t.Errorf("scratch.txt holds %q after the sync", data)
+	}
+	if _, err := a.sync(t, nil); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(a.store.path("notes.txt")); string(data) != "draft" {
+		t.Errorf("a pulled notes.txt %q", data)
+	}
+}